@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnos "github.com/turbinelabs/nonstdlib/os"
+)
+
+const applyDesc = `Apply a Zone document to the Turbine Labs API. The input is
+in the format produced by export-zone, with object keys replaced by names.
+
+Objects in the document are matched against objects already in the Zone:
+Clusters, Listeners, Proxies and SharedRules by name, Domains by name and
+port, and Routes by domain and path. Missing objects are created and objects
+which differ from the document are modified, in dependency order. Cluster
+Instances are left as is unless the document specifies them, as are Listeners,
+and the Listeners of Proxies, if the document has none. If --prune is set,
+objects in the Zone which are not present in the document are deleted.
+
+The Zone is created if it does not already exist. If a zone name is given, it
+overrides the name of the Zone in the document.
+
+If --values or --set is given, the document is treated as a Go text/template
+and rendered with the given values before it is decoded. Use the render
+sub-command to preview the result.
+
+The changes made are printed as a list of actions.`
+
+func cmdApply(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "apply",
+		Summary:     "apply a Zone document to the Turbine Labs API",
+		Usage:       "[OPTIONS] [zone-name]",
+		Description: applyDesc,
+	}
+
+	r := &applyRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.file,
+		"f",
+		"",
+		"The file containing the Zone document. If not specified, the document is read from STDIN.",
+	)
+
+	cmd.Flags.BoolVar(
+		&r.prune,
+		"prune",
+		false,
+		"If true, delete objects in the Zone that are not present in the document.",
+	)
+
+	r.templates = newZoneTemplateFlags(&cmd.Flags)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type applyRunner struct {
+	cfg       globalConfigT
+	file      string
+	prune     bool
+	templates *zoneTemplateFlags
+}
+
+func (r *applyRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *applyRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) > 1 {
+		return cmd.BadInput("takes at most one argument")
+	}
+
+	var (
+		txt string
+		err error
+	)
+
+	if r.file != "" {
+		bytes, err := ioutil.ReadFile(r.file)
+		if err != nil {
+			return cmd.Errorf("could not read %s: %s", r.file, err)
+		}
+		txt = string(bytes)
+	} else {
+		txt, err = tbnos.ReadIfNonEmpty(os.Stdin)
+		if err != nil {
+			return cmd.Errorf("could not process STDIN: %s", err)
+		}
+	}
+
+	if txt == "" {
+		return cmd.BadInput("no zone document provided")
+	}
+
+	txt, err = r.templates.render(templateName(r.file), txt)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	zo := newZoneObjects()
+	if err := codec.DecodeFromString(r.cfg.codec, txt, zo); err != nil {
+		return cmd.BadInputf("could not decode zone document: %s", err)
+	}
+
+	if len(args) == 1 {
+		zo.Zone.Name = args[0]
+	}
+
+	if zo.Zone.Name == "" {
+		return cmd.BadInput("zone name must be specified in the document or as an argument")
+	}
+
+	changes, err := applyZone(r.cfg.apiClient, zo, r.prune)
+	r.cfg.PrintResult(changes)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
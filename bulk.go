@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/turbinelabs/cli/terminal"
+	tbnos "github.com/turbinelabs/nonstdlib/os"
+	tbnstrings "github.com/turbinelabs/nonstdlib/strings"
+)
+
+const (
+	bulkSelectorFlag = "selector"
+
+	bulkSelectorDesc = `{{bold "Bulk Operations"}}
+
+With --selector, the operation applies to every object matching the given
+field_name=field_value terms, each of which may be repeated. A value
+containing '*' or '?' is a glob, matched against the whole of an object's
+string field. A value beginning with '~' is a regular expression, which
+matches any part of the field unless anchored with '^' and '$'. Other values
+must match exactly, as with list. Values may not be empty. The matching
+objects are shown and confirmation is requested once, before any are changed;
+--yes skips the confirmation. If --zone is set, only objects in that Zone are
+matched.`
+
+	bulkYesDesc = "if true, apply the operation to all objects matched by --selector without asking for confirmation"
+)
+
+// fieldMatcher matches a glob or regular expression against a string field,
+// identified by its JSON name.
+type fieldMatcher struct {
+	field   string
+	pattern *regexp.Regexp
+}
+
+// bulkSelector selects objects using exact filter attributes, passed to
+// FilteredIndex, and fieldMatchers, applied to the results.
+type bulkSelector struct {
+	attrs    map[string]string
+	matchers []fieldMatcher
+}
+
+// globToRegexp converts a glob, in which '*' matches any sequence of
+// characters and '?' matches any single character, to an anchored regular
+// expression.
+func globToRegexp(glob string) *regexp.Regexp {
+	re := ""
+	for _, r := range glob {
+		switch r {
+		case '*':
+			re += ".*"
+		case '?':
+			re += "."
+		default:
+			re += regexp.QuoteMeta(string(r))
+		}
+	}
+	return regexp.MustCompile("^" + re + "$")
+}
+
+// parseBulkSelector parses field_name=field_value terms.
+func parseBulkSelector(terms []string) (*bulkSelector, error) {
+	sel := &bulkSelector{attrs: map[string]string{}}
+
+	for _, term := range terms {
+		k, v := tbnstrings.SplitFirstEqual(term)
+		if k == "" || !strings.Contains(term, "=") {
+			return nil, fmt.Errorf("selector %q must be field_name=field_value", term)
+		}
+		if v == "" || v == "~" {
+			return nil, fmt.Errorf("selector %q: field_value may not be empty", term)
+		}
+
+		switch {
+		case strings.HasPrefix(v, "~"):
+			re, err := regexp.Compile(v[1:])
+			if err != nil {
+				return nil, fmt.Errorf("selector %q: %v", term, err)
+			}
+			sel.matchers = append(sel.matchers, fieldMatcher{k, re})
+
+		case strings.ContainsAny(v, "*?"):
+			sel.matchers = append(sel.matchers, fieldMatcher{k, globToRegexp(v)})
+
+		default:
+			if _, ok := sel.attrs[k]; ok {
+				return nil, fmt.Errorf("selector %q: %s is already selected", term, k)
+			}
+			sel.attrs[k] = v
+		}
+	}
+
+	return sel, nil
+}
+
+// stringField returns the value of the string field of obj with the given
+// JSON name.
+func stringField(obj interface{}, name string) (string, bool) {
+	v := reflect.ValueOf(obj)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if getAssignmentName(t.Field(i)) == name && v.Field(i).Kind() == reflect.String {
+			return v.Field(i).String(), true
+		}
+	}
+	return "", false
+}
+
+func (s *bulkSelector) matches(obj interface{}) bool {
+	for _, m := range s.matchers {
+		val, _ := stringField(obj, m.field)
+		if !m.pattern.MatchString(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectObjects returns the objects matched by the selector, sorted by key.
+// Objects in a Zone other than the one given by --zone cause an error, unless
+// force is true.
+func (gc *globalConfigT) selectObjects(
+	svc typelessIface,
+	sel *bulkSelector,
+	force bool,
+) ([]interface{}, error) {
+	ot := svc.Type()
+
+	filter := svc.IndexZeroFilter()
+	attrs := map[string]string{}
+	for k, v := range sel.attrs {
+		if !filterHasField(filter, k) {
+			return nil, fmt.Errorf("%s cannot be selected by %s", ot.Name, k)
+		}
+		attrs[k] = v
+	}
+
+	zero := svc.Zero()
+	for _, m := range sel.matchers {
+		if _, ok := stringField(zero, m.field); !ok {
+			return nil, fmt.Errorf("%s has no string field %s to match", ot.Name, m.field)
+		}
+	}
+
+	gc.scopeFilterAttrs(filter, attrs)
+
+	objs, err := svc.FilteredIndex(selectorSliceSep, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []interface{}{}
+	for _, obj := range objs {
+		if !sel.matches(obj) {
+			continue
+		}
+		if err := gc.checkZoneScope(ot, svc.Key(obj), obj, force); err != nil {
+			return nil, err
+		}
+		result = append(result, obj)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return svc.Key(result[i]) < svc.Key(result[j])
+	})
+
+	return result, nil
+}
+
+// describeObj returns the key of the object, followed by its name or path, if
+// it has one.
+func describeObj(svc typelessIface, obj interface{}) string {
+	for _, field := range []string{"name", "path", "login_email"} {
+		if val, ok := stringField(obj, field); ok && val != "" {
+			return fmt.Sprintf("%s (%s)", svc.Key(obj), val)
+		}
+	}
+	return svc.Key(obj)
+}
+
+// confirmBulk lists the objects and asks once whether the operation, named by
+// verb, should be applied to all of them. If yes is true, the list is not shown
+// and no confirmation is requested.
+func confirmBulk(svc typelessIface, verb string, objs []interface{}, yes bool) (bool, error) {
+	if yes {
+		return true, nil
+	}
+
+	str := fmt.Sprintf("The following %d %s objects will be %s:\n", len(objs), svc.Type().Name, verb)
+	for _, obj := range objs {
+		str += "  " + describeObj(svc, obj) + "\n"
+	}
+	str += "Proceed?"
+
+	return terminal.Ask(tbnos.New(), str)
+}
+
+// sameObj returns true if the objects have the same JSON representation.
+func sameObj(a, b interface{}) (bool, error) {
+	ga, err := toGeneric(a)
+	if err != nil {
+		return false, err
+	}
+	gb, err := toGeneric(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(ga, gb), nil
+}
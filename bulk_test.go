@@ -0,0 +1,205 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	re := globToRegexp("foo-*.example.com:8?")
+	assert.True(t, re.MatchString("foo-bar.example.com:80"))
+	assert.True(t, re.MatchString("foo-.example.com:81"))
+	assert.False(t, re.MatchString("foo-barXexample.com:80"))
+	assert.False(t, re.MatchString("xfoo-bar.example.com:80"))
+	assert.False(t, re.MatchString("foo-bar.example.com:800"))
+}
+
+func TestParseBulkSelector(t *testing.T) {
+	sel, err := parseBulkSelector([]string{"zone_key=zk", "name=foo-*", "path=~^/api/v[0-9]+"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, sel.attrs, map[string]string{"zone_key": "zk"})
+	assert.Equal(t, len(sel.matchers), 2)
+	assert.Equal(t, sel.matchers[0].field, "name")
+	assert.Equal(t, sel.matchers[1].field, "path")
+
+	sel, err = parseBulkSelector([]string{"path=~^/api/v[0-9]+", "zone_key=z*"})
+	assert.Nil(t, err)
+	assert.True(t, sel.matches(api.Route{ZoneKey: "zk", Path: "/api/v2/users"}))
+	assert.False(t, sel.matches(api.Route{ZoneKey: "zk", Path: "/api/users"}))
+	assert.False(t, sel.matches(api.Route{ZoneKey: "k", Path: "/api/v2/users"}))
+
+	// regular expressions match substrings unless anchored
+	sel, err = parseBulkSelector([]string{"path=~v[0-9]"})
+	assert.Nil(t, err)
+	assert.True(t, sel.matches(api.Route{Path: "/api/v2/users"}))
+
+	_, err = parseBulkSelector([]string{"name"})
+	assert.ErrorContains(t, err, `selector "name" must be field_name=field_value`)
+
+	_, err = parseBulkSelector([]string{"name="})
+	assert.ErrorContains(t, err, `selector "name=": field_value may not be empty`)
+
+	_, err = parseBulkSelector([]string{"name=~"})
+	assert.ErrorContains(t, err, `selector "name=~": field_value may not be empty`)
+
+	_, err = parseBulkSelector([]string{"name=~("})
+	assert.ErrorContains(t, err, `selector "name=~("`)
+
+	_, err = parseBulkSelector([]string{"name=a", "name=b"})
+	assert.ErrorContains(t, err, "name is already selected")
+}
+
+func mkBulkTestObjs(t *testing.T) selectorTestObjs {
+	objs := mkSelectorTestObjs(t)
+	objs.gc.codec = codec.NewJson()
+	for _, name := range []string{"foo-1", "foo-2", "bar"} {
+		_, err := objs.gc.apiClient.Cluster().Create(
+			api.Cluster{ZoneKey: objs.zones[0].ZoneKey, Name: name},
+		)
+		assert.Nil(t, err)
+	}
+	return objs
+}
+
+func selectNames(t *testing.T, objs selectorTestObjs, terms ...string) []string {
+	sel, err := parseBulkSelector(terms)
+	assert.Nil(t, err)
+
+	svc := newTypelessIface(objs.gc.apiClient, objecttype.Cluster)
+	matched, err := objs.gc.selectObjects(svc, sel, false)
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, obj := range matched {
+		names = append(names, obj.(api.Cluster).Name)
+	}
+	return names
+}
+
+func TestSelectObjects(t *testing.T) {
+	objs := mkBulkTestObjs(t)
+
+	assert.HasSameElements(t, selectNames(t, objs, "name=foo-*"), []string{"foo-1", "foo-2"})
+	assert.HasSameElements(t, selectNames(t, objs, "name=~only$"), []string{"prod-only", "staging-only"})
+	assert.HasSameElements(t, selectNames(t, objs, "name=bar"), []string{"bar"})
+	assert.HasSameElements(
+		t,
+		selectNames(t, objs, "zone_key="+string(objs.zones[1].ZoneKey), "name=*"),
+		[]string{"api", "staging-only"},
+	)
+
+	objs.gc.zoneKey = objs.zones[1].ZoneKey
+	assert.HasSameElements(t, selectNames(t, objs, "name=*-only"), []string{"staging-only"})
+}
+
+func TestSelectObjectsErrors(t *testing.T) {
+	objs := mkBulkTestObjs(t)
+	svc := newTypelessIface(objs.gc.apiClient, objecttype.Cluster)
+
+	sel, err := parseBulkSelector([]string{"nme=foo"})
+	assert.Nil(t, err)
+	_, err = objs.gc.selectObjects(svc, sel, false)
+	assert.ErrorContains(t, err, "cluster cannot be selected by nme")
+
+	sel, err = parseBulkSelector([]string{"instances=foo-*"})
+	assert.Nil(t, err)
+	_, err = objs.gc.selectObjects(svc, sel, false)
+	assert.ErrorContains(t, err, "cluster has no string field instances to match")
+
+	// an explicit zone_key outside the --zone requires force
+	zone := "staging"
+	objs.gc.zone = &zone
+	objs.gc.zoneKey = objs.zones[1].ZoneKey
+	sel, err = parseBulkSelector([]string{"zone_key=" + string(objs.zones[0].ZoneKey), "name=bar"})
+	assert.Nil(t, err)
+	_, err = objs.gc.selectObjects(svc, sel, false)
+	assert.ErrorContains(t, err, "belongs to zone "+string(objs.zones[0].ZoneKey)+", not staging")
+
+	matched, err := objs.gc.selectObjects(svc, sel, true)
+	assert.Nil(t, err)
+	assert.Equal(t, len(matched), 1)
+}
+
+func TestDeleteSelector(t *testing.T) {
+	objs := mkBulkTestObjs(t)
+
+	r := &delRunner{&delCfg{
+		globalConfigT: objs.gc,
+		yes:           true,
+		concurrency:   1,
+		selectors:     tbnflag.Strings{Strings: []string{"name=foo-*"}},
+	}}
+	r.run(&command.Cmd{}, []string{"cluster"})
+
+	cs, err := objs.gc.apiClient.Cluster().Index(service.ClusterFilter{ZoneKey: objs.zones[0].ZoneKey})
+	assert.Nil(t, err)
+	names := []string{}
+	for _, c := range cs {
+		names = append(names, c.Name)
+	}
+	assert.HasSameElements(t, names, []string{"api", "prod-only", "bar"})
+}
+
+func TestEditApplyBulk(t *testing.T) {
+	objs := mkBulkTestObjs(t)
+	r := &editRunner{&editCfg{globalConfigT: objs.gc, yes: true}}
+	svc := newTypelessIface(objs.gc.apiClient, objecttype.Cluster)
+
+	sel, err := parseBulkSelector([]string{"name=foo-*"})
+	assert.Nil(t, err)
+	selected, err := objs.gc.selectObjects(svc, sel, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(selected), 2)
+
+	changed := selected[0].(api.Cluster)
+	changed.RequireTLS = true
+	txt, err := codec.EncodeToString(objs.gc.codec, []interface{}{changed, selected[1]})
+	assert.Nil(t, err)
+
+	next, err := r.applyBulk(svc, txt, selected)
+	assert.Nil(t, err)
+	assert.Equal(t, next, "")
+
+	got, err := objs.gc.apiClient.Cluster().Get(changed.ClusterKey)
+	assert.Nil(t, err)
+	assert.True(t, got.RequireTLS)
+	assert.NotEqual(t, got.Checksum, changed.Checksum)
+
+	unchanged, err := objs.gc.apiClient.Cluster().Get(selected[1].(api.Cluster).ClusterKey)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, unchanged, selected[1])
+
+	// a stale checksum fails, and the object is returned for editing again
+	next, err = r.applyBulk(svc, txt, selected)
+	assert.ErrorContains(t, err, "1 of 1 modifications failed")
+	assert.NotEqual(t, next, "")
+
+	// objects which weren't selected are rejected
+	txt, err = codec.EncodeToString(objs.gc.codec, []interface{}{objs.cluster})
+	assert.Nil(t, err)
+	_, err = r.applyBulk(svc, txt, selected)
+	assert.ErrorContains(t, err, "is not one of the selected objects")
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/turbinelabs/cli/command"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+)
+
+const (
+	cloneZoneDesc = `Clone a Zone in the Turbine Labs API. The source Zone is
+exported as with export-zone, rewrite rules are applied, and the result is
+imported as with import-zone under the destination name. The destination Zone
+is assumed not to exist, and clone-zone will fail if it is already present.
+Cluster Instances are not copied. If the clone fails partway through, objects
+already created are deleted, unless --no-rollback is given.`
+
+	rewriteDomainDesc = `A rule of the form <pattern>=<replacement> used to
+rename Domains and Domain aliases, e.g. '*.example.com=*.staging.example.com'.
+Each '*' in the pattern matches any sequence of characters, and each '*' in the
+replacement is filled in with the corresponding match. Rules are tried in order,
+and the first matching rule is applied. References to renamed Domains from
+Listeners, Proxies and Routes are updated to match. May be repeated.`
+
+	rewriteClusterSuffixDesc = `A suffix appended to the name of every Cluster.
+Cluster references in SharedRules and Route constraints are updated to match.`
+
+	rewritePortDesc = `A rule of the form <port>=<port> used to change the port
+of Domains and Listeners, e.g. '80=8080'. May be repeated.`
+)
+
+func cmdCloneZone(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "clone-zone",
+		Summary:     "clone a Zone in the Turbine Labs API",
+		Usage:       "[OPTIONS] <src-zone-name>|<src-zone-key> <dst-zone-name>",
+		Description: cloneZoneDesc,
+	}
+
+	r := &cloneZoneRunner{
+		cfg:         globalConfig,
+		domainRules: tbnflag.NewStrings(),
+		portRules:   tbnflag.NewStrings(),
+	}
+	cmd.Flags.Var(&r.domainRules, "rewrite-domain", rewriteDomainDesc)
+	cmd.Flags.StringVar(&r.clusterSuffix, "rewrite-cluster-suffix", "", rewriteClusterSuffixDesc)
+	cmd.Flags.Var(&r.portRules, "rewrite-port", rewritePortDesc)
+	cmd.Flags.BoolVar(&r.noRollback, "no-rollback", false, noRollbackDesc)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type cloneZoneRunner struct {
+	cfg           globalConfigT
+	domainRules   tbnflag.Strings
+	portRules     tbnflag.Strings
+	clusterSuffix string
+	noRollback    bool
+}
+
+func (r *cloneZoneRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *cloneZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) != 2 {
+		return cmd.BadInput("requires exactly two arguments")
+	}
+
+	zr, err := newZoneRewriter(r.domainRules.Strings, r.portRules.Strings, r.clusterSuffix)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	zo, err := exportZone(r.cfg.apiClient, args[0])
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	if err := zr.rewrite(zo); err != nil {
+		return cmd.Error(err)
+	}
+
+	// key mappings recorded during export refer to the source Zone; start the
+	// clone with empty ones
+	clone := newZoneObjects()
+	clone.Zone = zo.Zone
+	clone.Clusters = zo.Clusters
+	clone.Domains = zo.Domains
+	clone.Listeners = zo.Listeners
+	clone.Proxies = zo.Proxies
+	clone.Routes = zo.Routes
+	clone.SharedRules = zo.SharedRules
+
+	err = withRollback(r.cfg.apiClient, r.noRollback, func(svc *unifiedSvc) error {
+		var err error
+		zo, err = storeZone(svc, args[1], clone)
+		return err
+	})
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	r.cfg.PrintResult(zo)
+
+	return command.NoError()
+}
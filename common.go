@@ -30,12 +30,14 @@ import (
 	"github.com/turbinelabs/nonstdlib/editor"
 	"github.com/turbinelabs/nonstdlib/log/console"
 	tbnos "github.com/turbinelabs/nonstdlib/os"
+	tbnstrings "github.com/turbinelabs/nonstdlib/strings"
 )
 
 var objTypeList = []objecttype.ObjectType{
 	objecttype.User,
 	objecttype.Zone,
 	objecttype.Proxy,
+	objecttype.Listener,
 	objecttype.Domain,
 	objecttype.Route,
 	objecttype.SharedRules,
@@ -79,21 +81,59 @@ func objKeyFromStrings(args *[]string) (string, error) {
 	return key, nil
 }
 
+// otFromStrings removes the object type from the front of args and returns
+// it. Object selectors are rejected, since they stand in for an object key,
+// which the caller does not take.
 func otFromStrings(args *[]string) (objecttype.ObjectType, error) {
-	if len(*args) < 1 {
-		return objecttype.ObjectType{}, errors.New("expected object type as first argument, got nothing")
+	ot, sel, err := splitObjType(args)
+	if err != nil {
+		return objecttype.ObjectType{}, err
 	}
 
-	ot, err := objecttype.FromName((*args)[0])
-	if err != nil {
-		return objecttype.ObjectType{}, fmt.Errorf("%s was not a valid object type", (*args)[0])
+	if sel != "" {
+		return objecttype.ObjectType{}, fmt.Errorf(
+			"%s is an object selector, which can only be used in place of an object key",
+			(*args)[0],
+		)
 	}
 
 	*args = (*args)[1:]
+	return ot, nil
+}
+
+// keyedOtFromStrings is like otFromStrings, for callers that go on to take an
+// object key from args. If the first argument is an object selector, the type
+// is taken from it and the selector is left in place of the object key.
+func keyedOtFromStrings(args *[]string) (objecttype.ObjectType, error) {
+	ot, sel, err := splitObjType(args)
+	if err != nil {
+		return objecttype.ObjectType{}, err
+	}
+
+	if sel == "" {
+		*args = (*args)[1:]
+	}
 
 	return ot, nil
 }
 
+// splitObjType parses the first of args as an object type, optionally
+// followed by a '/' and an object selector, and returns both.
+func splitObjType(args *[]string) (objecttype.ObjectType, string, error) {
+	if len(*args) < 1 {
+		return objecttype.ObjectType{}, "", errors.New("expected object type as first argument, got nothing")
+	}
+
+	name, sel := tbnstrings.Split2((*args)[0], "/")
+
+	ot, err := objecttype.FromName(name)
+	if err != nil {
+		return objecttype.ObjectType{}, "", fmt.Errorf("%s was not a valid object type", (*args)[0])
+	}
+
+	return ot, sel, nil
+}
+
 func objTypeNames() string {
 	desc := []string{}
 	for _, ot := range objTypeList {
@@ -120,6 +160,9 @@ in an editor. The command used to launch the editor is taken from the %s
 environment variable and must block execution until the changes are saved and
 the editor is closed. The current editor command is '%s'.
 
+If the %s object cannot be decoded, or is rejected by the API, the editor is
+re-opened with the error prepended as a comment. Save an empty file to abort.
+
 {{ul "Example EDITOR values"}}:
 
 		vim
@@ -138,6 +181,7 @@ available on STDIN through standard use of pipes.
 		editor.EditorVar,
 		edtCmd,
 		act,
+		act,
 	)
 }
 
@@ -151,6 +195,21 @@ func (gc *globalConfigT) UntypedSvc(args *[]string) (typelessIface, error) {
 		return nil, err
 	}
 
+	return gc.untypedSvcFor(ot)
+}
+
+// UntypedKeyedSvc is like UntypedSvc, for commands that take an object key,
+// which may be given as part of an object selector in place of the type.
+func (gc *globalConfigT) UntypedKeyedSvc(args *[]string) (typelessIface, error) {
+	ot, err := keyedOtFromStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return gc.untypedSvcFor(ot)
+}
+
+func (gc *globalConfigT) untypedSvcFor(ot objecttype.ObjectType) (typelessIface, error) {
 	svc := newTypelessIface(gc.apiClient, ot)
 	if svc == nil {
 		return nil, fmt.Errorf("Unsupported object type: %v\n", ot.Name)
@@ -169,39 +228,100 @@ func (gc *globalConfigT) PrintResult(obj interface{}) {
 	fmt.Println()
 }
 
+// editApplyFunc applies text produced by editOrStdin. If it fails, it may
+// return replacement text to present in the editor, or "" to present the text
+// it was given.
+type editApplyFunc func(txt string) (string, error)
+
+var (
+	errEditAborted  = errors.New("edit aborted: empty file saved")
+	errEditCanceled = errors.New("edit canceled")
+)
+
+// yamlCodecType is the --format value of the only codec that accepts comments.
+const yamlCodecType = "yaml"
+
+const editErrorFmt = `The changes could not be applied:
+
+%s
+
+Correct the error and save to try again, or save an empty file to abort.`
+
 // editOrStdin is a helper function for commands that need to allow the user
 // to modify some text starting from an encoded object or use stdin. If stdin
-// has content then no object will be rendered and presented for modification.
-// Otherwise fallback will be called and the returned object will be rendered
-// using the codec available through gc and opened in an editor.
+// has content then no object will be rendered and presented for modification,
+// and its content is passed to apply. Otherwise fallback will be called and
+// the returned object will be rendered using the codec available through gc
+// and opened in an editor. The edited text is passed to apply. If apply fails,
+// the editor is re-opened until apply succeeds or an empty file is saved. For
+// YAML the error is prepended as a comment block, which is removed before the
+// text is applied again; JSON has no comments, so the error goes to stderr.
 func editOrStdin(
 	fallback func() (interface{}, error),
 	gc *globalConfigT,
-) (string, error) {
+	apply editApplyFunc,
+) error {
 	txt, err := tbnos.ReadIfNonEmpty(os.Stdin)
 	if err != nil {
-		return "", fmt.Errorf("could not process STDIN: %s", err.Error())
+		return fmt.Errorf("could not process STDIN: %s", err.Error())
 	}
 	if txt != "" {
-		return txt, nil
+		_, err := apply(txt)
+		return err
 	}
 
 	obj, err := fallback()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	objstr, err := codec.EncodeToString(gc.codec, obj)
+	txt, err = codec.EncodeToString(gc.codec, obj)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	txt, err = editor.EditTextType(objstr, gc.codecFlags.Type())
-	if err != nil {
-		return "", err
+	comments := gc.codecFlags.Type() == yamlCodecType
+	for {
+		edited, err := editor.EditTextType(txt, gc.codecFlags.Type())
+		if err != nil {
+			return err
+		}
+
+		if comments {
+			edited = stripCommentBlock(edited)
+		}
+		if strings.TrimSpace(edited) == "" {
+			return errEditAborted
+		}
+
+		next, err := apply(edited)
+		if err == nil || err == errEditCanceled {
+			return err
+		}
+		if next == "" {
+			next = edited
+		}
+
+		msg := fmt.Sprintf(editErrorFmt, gc.prettyErr(err))
+		if comments {
+			txt = commentBlock(msg) + next
+		} else {
+			console.Error().Println(msg)
+			txt = next
+		}
 	}
+}
 
-	return txt, nil
+// prettyErr returns API client errors marshaled with the configured codec,
+// and the result of Error() for other errors.
+func (gc *globalConfigT) prettyErr(err error) string {
+	if e, ok := err.(*apierror.Error); ok {
+		buf := &bytes.Buffer{}
+		if gc.codec.Encode(e, buf) == nil {
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	return err.Error()
 }
 
 // PrettyCmdErr will marshal API client errors, and return the result of Error()
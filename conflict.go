@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/nonstdlib/log/console"
+)
+
+const maxModifyAttempts = 5
+
+// checksumConflict is called after a failed Modify made with the given
+// checksum. It returns the current version of the object and whether its
+// checksum differs, in which case the failure was likely caused by a
+// concurrent modification.
+func checksumConflict(a typelessIface, key string, cs api.Checksum) (interface{}, bool) {
+	current, err := a.Get(key)
+	if err != nil {
+		return nil, false
+	}
+
+	return current, a.Checksum(current) != cs
+}
+
+// modifyWithRetry performs a read-modify-write of the object with the given
+// key, calling f to modify obj, which the caller has already retrieved. If
+// Modify fails because the object was concurrently modified, the process is
+// retried with the new version, up to maxModifyAttempts times.
+func modifyWithRetry(
+	a typelessIface,
+	key string,
+	obj interface{},
+	f func(interface{}) (interface{}, error),
+) (interface{}, error) {
+	for attempt := 1; ; attempt++ {
+		cs := a.Checksum(obj)
+
+		mod, err := f(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := a.Modify(mod)
+		if err == nil {
+			return res, nil
+		}
+
+		current, conflict := checksumConflict(a, key, cs)
+		if !conflict || attempt >= maxModifyAttempts {
+			return nil, err
+		}
+
+		console.Info().Printf("%s %s was modified concurrently, retrying", a.Type().Name, key)
+		obj = current
+	}
+}
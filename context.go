@@ -0,0 +1,386 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflag "flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+)
+
+const contextDesc = `Manage named contexts. A context holds settings for
+connecting to an instance of the Turbine Labs API, such as its host and port, an
+API key or login username, and defaults such as a Zone or output format.
+Contexts are stored in ~/.tbnctl-config.
+
+The settings of the current context, or of the context named by the global
+--context flag, are used as defaults for the corresponding global flags, which
+take precedence. Each context has its own login token cache, so logging into
+one context does not log out of another.
+
+Actions:
+
+    list
+        List contexts. The current context is marked with a '*'.
+
+    use <name>
+        Make the named context current.
+
+    show [<name>]
+        Show the settings of the named context, or of the current context. API
+        keys are masked.
+
+    set <name> <setting>=<value>...
+        Change settings of the named context, creating it if necessary. An empty
+        value removes the setting. Settings are:
+
+        api.host  the API host
+        api.port  the API port
+        api.ssl   whether to use SSL (true or false)
+        api.key   an API key
+        format    the format used for input and output (json or yaml)
+        username  the username used by login
+        zone      the default Zone name or key`
+
+// contextNameRegex matches valid context names, which are used in token cache
+// file names.
+var contextNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+const (
+	contextFlag     = "context"
+	contextUsername = "username"
+)
+
+// contextSetting describes a setting which may be stored in a context.
+type contextSetting struct {
+	name string
+
+	// flag is true if the setting provides a default for the global flag of
+	// the same name.
+	flag bool
+
+	// sensitive settings are masked when shown.
+	sensitive bool
+
+	validate func(string) error
+}
+
+func validatePort(v string) error {
+	if p, err := strconv.Atoi(v); err != nil || p <= 0 || p > 65535 {
+		return fmt.Errorf("bad port %q", v)
+	}
+	return nil
+}
+
+func validateBool(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("bad boolean %q", v)
+	}
+	return nil
+}
+
+func validateFormat(v string) error {
+	if v != "json" && v != "yaml" {
+		return fmt.Errorf("bad format %q: must be json or yaml", v)
+	}
+	return nil
+}
+
+var contextSettings = []contextSetting{
+	{name: "api.host", flag: true},
+	{name: "api.port", flag: true, validate: validatePort},
+	{name: "api.ssl", flag: true, validate: validateBool},
+	{name: "api.key", flag: true, sensitive: true},
+	{name: "format", flag: true, validate: validateFormat},
+	{name: contextUsername},
+	{name: "zone", flag: true},
+}
+
+func findContextSetting(name string) (contextSetting, bool) {
+	for _, s := range contextSettings {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return contextSetting{}, false
+}
+
+// tbnctlContext holds the settings of a named context.
+type tbnctlContext map[string]string
+
+// set validates and changes a setting of the form <setting>=<value>. An empty
+// value removes the setting.
+func (c tbnctlContext) set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("malformed setting %q: expected <setting>=<value>", s)
+	}
+
+	name, value := parts[0], parts[1]
+	setting, ok := findContextSetting(name)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	if value == "" {
+		delete(c, name)
+		return nil
+	}
+
+	if setting.validate != nil {
+		if err := setting.validate(value); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	c[name] = value
+	return nil
+}
+
+// applyTo uses the context's settings as the values of the corresponding
+// flags in the FlagSet. The flags are not marked as set, so that values given
+// on the command line or by environment variables take precedence.
+func (c tbnctlContext) applyTo(fs *goflag.FlagSet) error {
+	for _, s := range contextSettings {
+		value, ok := c[s.name]
+		if !s.flag || !ok {
+			continue
+		}
+		f := fs.Lookup(s.name)
+		if f == nil {
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("%s: %s", s.name, err)
+		}
+	}
+	return nil
+}
+
+// contextConfig is the contents of the context config file.
+type contextConfig struct {
+	Current  string                   `json:"current_context"`
+	Contexts map[string]tbnctlContext `json:"contexts"`
+}
+
+// contextConfigPath returns the path of the context config file.
+func contextConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".tbnctl-config")
+}
+
+// readContextConfig reads the context config file at the given path. A
+// missing file produces an empty config.
+func readContextConfig(path string) (*contextConfig, error) {
+	cfg := &contextConfig{Contexts: map[string]tbnctlContext{}}
+
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := codec.DecodeFromString(codec.NewJson(), string(bytes), cfg); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %s", path, err)
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]tbnctlContext{}
+	}
+
+	return cfg, nil
+}
+
+// write writes the context config file to the given path. The file is only
+// readable by its owner, since it may contain API keys.
+func (cfg *contextConfig) write(path string) error {
+	txt, err := codec.EncodeToString(codec.NewJson(), cfg)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(txt), 0600)
+}
+
+// contextNames returns the names of all contexts, sorted.
+func (cfg *contextConfig) contextNames() []string {
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contextFromArgs returns the value of the global --context flag, which must
+// be known before flags are parsed, since the context provides flag defaults.
+// Only arguments preceding the sub-command are examined.
+func contextFromArgs(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return ""
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case strings.HasPrefix(name, contextFlag+"="):
+			return strings.TrimPrefix(name, contextFlag+"=")
+		case name == contextFlag && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// loadContext determines the active context from the command line and the
+// context config file. It returns the name and settings of the active
+// context, which are empty if there is none.
+func loadContext(args []string) (string, tbnctlContext, error) {
+	cfg, err := readContextConfig(contextConfigPath())
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := contextFromArgs(args)
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown context %q", name)
+	}
+
+	return name, ctx, nil
+}
+
+func cmdContext(globalConfig globalConfigT) *command.Cmd {
+	return &command.Cmd{
+		Name:        "context",
+		Summary:     "manage named contexts",
+		Usage:       "(list|use|show|set) [<name>] [<setting>=<value>...]",
+		Description: contextDesc,
+		Runner:      &contextRunner{path: contextConfigPath()},
+	}
+}
+
+type contextRunner struct {
+	path string
+}
+
+func (r *contextRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) < 1 {
+		return cmd.BadInput("requires an action: list, use, show, or set")
+	}
+
+	cfg, err := readContextConfig(r.path)
+	if err != nil {
+		return cmd.Error(err)
+	}
+
+	action, args := args[0], args[1:]
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return cmd.BadInput("list takes no arguments")
+		}
+		for _, name := range cfg.contextNames() {
+			marker := " "
+			if name == cfg.Current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		return command.NoError()
+
+	case "use":
+		if len(args) != 1 {
+			return cmd.BadInput("use requires exactly one argument")
+		}
+		if _, ok := cfg.Contexts[args[0]]; !ok {
+			return cmd.BadInputf("unknown context %q", args[0])
+		}
+		cfg.Current = args[0]
+
+	case "show":
+		name := cfg.Current
+		switch {
+		case len(args) == 1:
+			name = args[0]
+		case len(args) > 1:
+			return cmd.BadInput("show takes at most one argument")
+		case name == "":
+			return cmd.BadInput("no current context")
+		}
+		ctx, ok := cfg.Contexts[name]
+		if !ok {
+			return cmd.BadInputf("unknown context %q", name)
+		}
+		fmt.Printf("context: %s\n", name)
+		for _, s := range contextSettings {
+			if value, ok := ctx[s.name]; ok {
+				if s.sensitive {
+					value = "********"
+				}
+				fmt.Printf("%s: %s\n", s.name, value)
+			}
+		}
+		return command.NoError()
+
+	case "set":
+		if len(args) < 2 {
+			return cmd.BadInput("set requires a context name and at least one setting")
+		}
+		if !contextNameRegex.MatchString(args[0]) {
+			return cmd.BadInputf(
+				"bad context name %q: may contain only letters, digits, '.', '_', and '-'",
+				args[0],
+			)
+		}
+		ctx, ok := cfg.Contexts[args[0]]
+		if !ok {
+			ctx = tbnctlContext{}
+		}
+		for _, s := range args[1:] {
+			if err := ctx.set(s); err != nil {
+				return cmd.BadInput(err)
+			}
+		}
+		cfg.Contexts[args[0]] = ctx
+
+	default:
+		return cmd.BadInputf("%q is not a valid context action", action)
+	}
+
+	if err := cfg.write(r.path); err != nil {
+		return cmd.Errorf("could not write %s: %s", r.path, err)
+	}
+
+	return command.NoError()
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflag "flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestContextFromArgs(t *testing.T) {
+	assert.Equal(t, contextFromArgs([]string{"--context=prod", "list"}), "prod")
+	assert.Equal(t, contextFromArgs([]string{"-context", "prod", "list"}), "prod")
+	assert.Equal(t, contextFromArgs([]string{"--api.port=80", "--context", "prod"}), "prod")
+	assert.Equal(t, contextFromArgs([]string{"list", "--context=prod"}), "")
+	assert.Equal(t, contextFromArgs(nil), "")
+}
+
+func TestTbnctlContextSet(t *testing.T) {
+	ctx := tbnctlContext{}
+	assert.Nil(t, ctx.set("api.host=staging.example.com"))
+	assert.Nil(t, ctx.set("api.port=8080"))
+	assert.Nil(t, ctx.set("api.ssl=false"))
+	assert.DeepEqual(t, ctx, tbnctlContext{
+		"api.host": "staging.example.com",
+		"api.port": "8080",
+		"api.ssl":  "false",
+	})
+
+	assert.Nil(t, ctx.set("api.ssl="))
+	_, ok := ctx["api.ssl"]
+	assert.False(t, ok)
+
+	assert.ErrorContains(t, ctx.set("api.port=http"), `bad port "http"`)
+	assert.ErrorContains(t, ctx.set("api.ssl=maybe"), `bad boolean "maybe"`)
+	assert.ErrorContains(t, ctx.set("format=xml"), `bad format "xml"`)
+	assert.ErrorContains(t, ctx.set("nope=1"), `unknown setting "nope"`)
+	assert.ErrorContains(t, ctx.set("api.host"), "malformed setting")
+}
+
+func TestTbnctlContextApplyTo(t *testing.T) {
+	fs := &goflag.FlagSet{}
+	host := fs.String("api.host", "api.example.com", "")
+	port := fs.Int("api.port", 443, "")
+
+	ctx := tbnctlContext{"api.host": "localhost", "username": "someone@example.com"}
+	assert.Nil(t, ctx.applyTo(fs))
+	assert.Equal(t, *host, "localhost")
+	assert.Equal(t, *port, 443)
+
+	// the command line takes precedence
+	assert.Nil(t, fs.Parse([]string{"--api.host=other"}))
+	assert.Equal(t, *host, "other")
+}
+
+func TestContextRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tbnctl-context")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	r := &contextRunner{path: filepath.Join(dir, "config")}
+	cmd := &command.Cmd{}
+
+	// run runs the command, which must fail with an error containing wantErr
+	// if it is non-empty, and returns the resulting config.
+	run := func(wantErr string, args ...string) *contextConfig {
+		cmdErr := r.Run(cmd, args)
+		if wantErr == "" {
+			assert.Equal(t, cmdErr, command.NoError())
+		} else {
+			assert.Equal(t, cmdErr.Code, command.CmdErrCodeBadInput)
+			assert.True(t, strings.Contains(cmdErr.Message, wantErr))
+		}
+		cfg, err := readContextConfig(r.path)
+		assert.Nil(t, err)
+		return cfg
+	}
+
+	cfg := run("", "set", "prod", "api.host=api.example.com", "api.key=secret")
+	assert.DeepEqual(t, cfg.Contexts["prod"], tbnctlContext{
+		"api.host": "api.example.com",
+		"api.key":  "secret",
+	})
+	assert.Equal(t, cfg.Current, "")
+
+	cfg = run(`unknown context "staging"`, "use", "staging")
+	assert.Equal(t, cfg.Current, "")
+
+	cfg = run("", "set", "staging", "api.host=localhost")
+	cfg = run("", "use", "staging")
+	assert.Equal(t, cfg.Current, "staging")
+	assert.DeepEqual(t, cfg.contextNames(), []string{"prod", "staging"})
+
+	cfg = run(`bad context name "../prod"`, "set", "../prod", "api.host=localhost")
+	assert.Equal(t, len(cfg.Contexts), 2)
+
+	cfg = run("api.port", "set", "staging", "api.port=nope")
+	assert.DeepEqual(t, cfg.Contexts["staging"], tbnctlContext{"api.host": "localhost"})
+
+	run(`"nope" is not a valid context action`, "nope")
+}
+
+func TestTokenCachePathPerContext(t *testing.T) {
+	assert.NotEqual(t, TokenCachePath("prod"), TokenCachePath("staging"))
+	assert.NotEqual(t, TokenCachePath(""), TokenCachePath("prod"))
+}
@@ -29,24 +29,22 @@ type createRunner struct {
 }
 
 func (gc *createRunner) run(svc typelessIface) error {
-	txt, err := editOrStdin(
-		func() (interface{}, error) { return svc.Zero(), nil },
+	return editOrStdin(
+		func() (interface{}, error) { return gc.cfg.scopeZero(svc.Zero()), nil },
 		gc.cfg.globalConfigT,
+		func(txt string) (string, error) {
+			dest, err := svc.ObjFromString(txt, gc.cfg.codec)
+			if err != nil {
+				return "", err
+			}
+			obj, err := svc.Create(dest)
+			if err != nil {
+				return "", err
+			}
+			gc.cfg.PrintResult(obj)
+			return "", nil
+		},
 	)
-	if err != nil {
-		return err
-	}
-
-	dest, err := svc.ObjFromString(txt, gc.cfg.codec)
-	if err != nil {
-		return err
-	}
-	obj, err := svc.Create(dest)
-	if err != nil {
-		return err
-	}
-	gc.cfg.PrintResult(obj)
-	return nil
 }
 
 func (gc *createRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
@@ -22,16 +22,34 @@ import (
 
 	"github.com/turbinelabs/api"
 	"github.com/turbinelabs/api/service"
-	"github.com/turbinelabs/cli/terminal"
 	"github.com/turbinelabs/nonstdlib/log/console"
-	tbnos "github.com/turbinelabs/nonstdlib/os"
 )
 
 type proxyMod struct {
-	proxy           api.Proxy
+	proxy             api.Proxy
+	domainsToRemove   map[api.DomainKey]api.Domain
+	listenersToRemove map[api.ListenerKey]api.Listener
+}
+
+type listenerMod struct {
+	listener        api.Listener
 	domainsToRemove map[api.DomainKey]api.Domain
 }
 
+// sharedRulesMod is a SharedRules from which the constraints referring to
+// deleted Clusters have been removed.
+type sharedRulesMod struct {
+	sr       api.SharedRules
+	clusters map[api.ClusterKey]api.Cluster
+}
+
+// routeMod is a Route from which the constraints referring to deleted
+// Clusters have been removed.
+type routeMod struct {
+	route    api.Route
+	clusters map[api.ClusterKey]api.Cluster
+}
+
 type deleter struct {
 	svc *unifiedSvc
 
@@ -44,7 +62,21 @@ type deleter struct {
 	proxyMods map[api.ProxyKey]*proxyMod
 	listeners map[api.ListenerKey]api.Listener
 
+	listenerMods map[api.ListenerKey]*listenerMod
+	srMods       map[api.SharedRulesKey]*sharedRulesMod
+	routeMods    map[api.RouteKey]*routeMod
+
 	domainsForReport map[api.DomainKey]api.Domain
+
+	// concurrency is the number of deletions made at once.
+	concurrency int
+
+	// journal records completed steps, so that execution may be resumed.
+	journal *deleteJournal
+
+	// dryRun is true if svc only records changes, in which case no journal
+	// is created or updated.
+	dryRun bool
 }
 
 func newDeleter(svc *unifiedSvc) *deleter {
@@ -57,7 +89,11 @@ func newDeleter(svc *unifiedSvc) *deleter {
 		proxies:          make(map[api.ProxyKey]api.Proxy),
 		proxyMods:        make(map[api.ProxyKey]*proxyMod),
 		listeners:        make(map[api.ListenerKey]api.Listener),
+		listenerMods:     make(map[api.ListenerKey]*listenerMod),
+		srMods:           make(map[api.SharedRulesKey]*sharedRulesMod),
+		routeMods:        make(map[api.RouteKey]*routeMod),
 		domainsForReport: make(map[api.DomainKey]api.Domain),
+		concurrency:      defaultDeleteConcurrency,
 	}
 }
 
@@ -118,6 +154,285 @@ func (d *deleter) addZoneKey(zk api.ZoneKey) error {
 		d.proxies[p.ProxyKey] = p
 	}
 
+	listeners, err := d.svc.Listener().Index(service.ListenerFilter{ZoneKey: zk})
+	if err != nil {
+		return err
+	}
+	for _, l := range listeners {
+		d.listeners[l.ListenerKey] = l
+	}
+
+	return nil
+}
+
+func (d *deleter) proxyMod(p api.Proxy) *proxyMod {
+	pm, ok := d.proxyMods[p.ProxyKey]
+	if !ok {
+		pm = &proxyMod{
+			p,
+			map[api.DomainKey]api.Domain{},
+			map[api.ListenerKey]api.Listener{},
+		}
+		d.proxyMods[p.ProxyKey] = pm
+	}
+	return pm
+}
+
+func (d *deleter) addProxyKey(pk api.ProxyKey) error {
+	if _, ok := d.proxies[pk]; ok {
+		return nil
+	}
+
+	p, err := d.svc.Proxy().Get(pk)
+	if err != nil {
+		return err
+	}
+	if (api.Proxy{}.Equals(p)) {
+		return fmt.Errorf("no proxy found for key %s", pk)
+	}
+
+	d.proxies[pk] = p
+
+	// Listeners and Domains used by no other Proxy are deleted along with
+	// this one.
+	for _, lk := range p.ListenerKeys {
+		ps, err := d.svc.Proxy().Index(service.ProxyFilter{ListenerKeys: []api.ListenerKey{lk}})
+		if err != nil {
+			return err
+		}
+		if !onlyProxy(ps, pk) {
+			continue
+		}
+
+		l, err := d.svc.Listener().Get(lk)
+		if err != nil {
+			return err
+		}
+		d.listeners[lk] = l
+	}
+
+	for _, dk := range p.DomainKeys {
+		ps, err := d.svc.Proxy().Index(service.ProxyFilter{DomainKeys: []api.DomainKey{dk}})
+		if err != nil {
+			return err
+		}
+		if !onlyProxy(ps, pk) {
+			continue
+		}
+
+		if err := d.addDomainKey(dk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// onlyProxy returns true if no Proxy other than the one with the given key is
+// among ps.
+func onlyProxy(ps api.Proxies, pk api.ProxyKey) bool {
+	for _, p := range ps {
+		if p.ProxyKey != pk {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutCluster returns the constraints in ccs which do not refer to the
+// given Cluster, and whether any were removed. If constraints are removed, the
+// weights of the remainder are scaled so that their total is unchanged.
+func withoutCluster(ccs api.ClusterConstraints, ck api.ClusterKey) (api.ClusterConstraints, bool) {
+	var total, kept uint64
+	result := api.ClusterConstraints{}
+	for _, cc := range ccs {
+		total += uint64(cc.Weight)
+		if cc.ClusterKey == ck {
+			continue
+		}
+		kept += uint64(cc.Weight)
+		result = append(result, cc)
+	}
+
+	if len(result) == len(ccs) {
+		return ccs, false
+	}
+
+	if kept == 0 {
+		return result, true
+	}
+
+	assigned := uint64(0)
+	for i := range result {
+		if i == len(result)-1 {
+			result[i].Weight = uint32(total - assigned)
+			break
+		}
+		w := uint64(result[i].Weight) * total / kept
+		result[i].Weight = uint32(w)
+		assigned += w
+	}
+
+	return result, true
+}
+
+// withoutClusterAll removes constraints referring to the given Cluster from
+// each group of ac, returning whether any were removed.
+func withoutClusterAll(ac *api.AllConstraints, ck api.ClusterKey) bool {
+	var light, dark, tap bool
+	ac.Light, light = withoutCluster(ac.Light, ck)
+	ac.Dark, dark = withoutCluster(ac.Dark, ck)
+	ac.Tap, tap = withoutCluster(ac.Tap, ck)
+	return light || dark || tap
+}
+
+// withoutClusterRules returns a copy of rs with constraints referring to the
+// given Cluster removed, and whether any were removed. Rules left without
+// light constraints are dropped.
+func withoutClusterRules(rs api.Rules, ck api.ClusterKey) (api.Rules, bool) {
+	changed := false
+	result := api.Rules{}
+	for _, r := range rs {
+		if withoutClusterAll(&r.Constraints, ck) {
+			changed = true
+			if len(r.Constraints.Light) == 0 {
+				continue
+			}
+		}
+		result = append(result, r)
+	}
+
+	if !changed {
+		return rs, false
+	}
+
+	return result, true
+}
+
+func (d *deleter) addClusterKey(ck api.ClusterKey) error {
+	if _, ok := d.clusters[ck]; ok {
+		return nil
+	}
+
+	c, err := d.svc.Cluster().Get(ck)
+	if err != nil {
+		return err
+	}
+	if (api.Cluster{}.Equals(c)) {
+		return fmt.Errorf("no cluster found for key %s", ck)
+	}
+
+	d.clusters[ck] = c
+
+	// SharedRules whose default light constraints refer only to the Cluster
+	// are deleted, along with their Routes. Otherwise the constraints
+	// referring to the Cluster are removed.
+	srs, err := d.svc.SharedRules().Index(service.SharedRulesFilter{ZoneKey: c.ZoneKey})
+	if err != nil {
+		return err
+	}
+	for _, sr := range srs {
+		srk := sr.SharedRulesKey
+		if _, ok := d.srs[srk]; ok {
+			continue
+		}
+
+		mod, hasMod := d.srMods[srk]
+		cur := sr
+		if hasMod {
+			cur = mod.sr
+		}
+
+		defaultChanged := withoutClusterAll(&cur.Default, ck)
+		rules, rulesChanged := withoutClusterRules(cur.Rules, ck)
+		if !defaultChanged && !rulesChanged {
+			continue
+		}
+
+		if len(cur.Default.Light) == 0 {
+			delete(d.srMods, srk)
+			if err := d.addSharedRules(sr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cur.Rules = rules
+		if !hasMod {
+			mod = &sharedRulesMod{clusters: map[api.ClusterKey]api.Cluster{}}
+			d.srMods[srk] = mod
+		}
+		mod.sr = cur
+		mod.clusters[ck] = c
+	}
+
+	// Routes with rules referring to the Cluster have those constraints
+	// removed.
+	rs, err := d.svc.Route().Index(service.RouteFilter{ZoneKey: c.ZoneKey})
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		rk := r.RouteKey
+		if _, ok := d.routes[rk]; ok {
+			continue
+		}
+
+		mod, hasMod := d.routeMods[rk]
+		cur := r
+		if hasMod {
+			cur = mod.route
+		}
+
+		rules, changed := withoutClusterRules(cur.Rules, ck)
+		if !changed {
+			continue
+		}
+
+		cur.Rules = rules
+		if !hasMod {
+			mod = &routeMod{clusters: map[api.ClusterKey]api.Cluster{}}
+			d.routeMods[rk] = mod
+		}
+		mod.route = cur
+		mod.clusters[ck] = c
+
+		if _, ok := d.domainsForReport[r.DomainKey]; !ok {
+			dom, err := d.svc.Domain().Get(r.DomainKey)
+			if err != nil {
+				return err
+			}
+			d.domainsForReport[dom.DomainKey] = dom
+		}
+	}
+
+	return nil
+}
+
+func (d *deleter) addListenerKey(lk api.ListenerKey) error {
+	if _, ok := d.listeners[lk]; ok {
+		return nil
+	}
+
+	l, err := d.svc.Listener().Get(lk)
+	if err != nil {
+		return err
+	}
+	if (api.Listener{}.Equals(l)) {
+		return fmt.Errorf("no listener found for key %s", lk)
+	}
+
+	d.listeners[lk] = l
+
+	// find proxies for which the listener needs to be removed
+	ps, err := d.svc.Proxy().Index(service.ProxyFilter{ListenerKeys: []api.ListenerKey{lk}})
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		d.proxyMod(p).listenersToRemove[lk] = l
+	}
+
 	return nil
 }
 
@@ -162,13 +477,26 @@ func (d *deleter) addDomain(dom api.Domain) error {
 		return err
 	}
 	for _, p := range ps {
-		if _, ok := d.proxyMods[p.ProxyKey]; !ok {
-			d.proxyMods[p.ProxyKey] = &proxyMod{p, map[api.DomainKey]api.Domain{dom.DomainKey: dom}}
-		} else {
-			d.proxyMods[p.ProxyKey].domainsToRemove[dom.DomainKey] = dom
+		if _, ok := d.proxies[p.ProxyKey]; !ok {
+			d.proxyMod(p).domainsToRemove[dom.DomainKey] = dom
 		}
 	}
 
+	// find listeners for which the domain needs to be removed
+	ls, err := d.svc.Listener().Index(service.ListenerFilter{DomainKeys: []api.DomainKey{dk}})
+	if err != nil {
+		return err
+	}
+	for _, l := range ls {
+		if _, ok := d.listeners[l.ListenerKey]; ok {
+			continue
+		}
+		if _, ok := d.listenerMods[l.ListenerKey]; !ok {
+			d.listenerMods[l.ListenerKey] = &listenerMod{l, map[api.DomainKey]api.Domain{}}
+		}
+		d.listenerMods[l.ListenerKey].domainsToRemove[dom.DomainKey] = dom
+	}
+
 	return nil
 }
 
@@ -308,53 +636,111 @@ func proxyStr(p api.Proxy) string {
 	return fmt.Sprintf("Proxy(%s:%s)", p.ProxyKey, p.Name)
 }
 
+func listenerStr(l api.Listener) string {
+	return fmt.Sprintf("Listener(%s:%s:%s:%d)", l.ListenerKey, l.Name, l.IP, l.Port)
+}
+
 func zoneStr(z api.Zone) string {
 	return fmt.Sprintf("Zone(%s:%s)", z.ZoneKey, z.Name)
 }
 
-func proxyModStr(pm *proxyMod, indent, verb string) string {
+func domainsToRemoveStr(doms map[api.DomainKey]api.Domain, indent, verb string) string {
 	dNames := []string{}
-	for dk, dom := range pm.domainsToRemove {
+	for _, dk := range sortedKeys(doms) {
+		dom := doms[api.DomainKey(dk)]
 		dNames = append(dNames, fmt.Sprintf("%s:%s:%d", dk, dom.Name, dom.Port))
 	}
 	noun := "Domain"
 	if len(dNames) > 1 {
 		noun += "s"
 	}
+	return fmt.Sprintf("\n%s  %s %s: %s", indent, verb, noun, strings.Join(dNames, ", "))
+}
+
+func proxyModStr(pm *proxyMod, indent, verb string) string {
+	str := fmt.Sprintf("%sProxy(%s:%s):", indent, pm.proxy.ProxyKey, pm.proxy.Name)
+	if len(pm.domainsToRemove) > 0 {
+		str += domainsToRemoveStr(pm.domainsToRemove, indent, verb)
+	}
+	if len(pm.listenersToRemove) > 0 {
+		lNames := []string{}
+		for _, lk := range sortedKeys(pm.listenersToRemove) {
+			l := pm.listenersToRemove[api.ListenerKey(lk)]
+			lNames = append(lNames, fmt.Sprintf("%s:%s", lk, l.Name))
+		}
+		noun := "Listener"
+		if len(lNames) > 1 {
+			noun += "s"
+		}
+		str += fmt.Sprintf("\n%s  %s %s: %s", indent, verb, noun, strings.Join(lNames, ", "))
+	}
+	return str
+}
+
+func clustersToRemoveStr(cs map[api.ClusterKey]api.Cluster, indent, verb string) string {
+	cNames := []string{}
+	for _, ck := range sortedKeys(cs) {
+		c := cs[api.ClusterKey(ck)]
+		cNames = append(cNames, fmt.Sprintf("%s:%s", ck, c.Name))
+	}
+	noun := "Cluster"
+	if len(cNames) > 1 {
+		noun += "s"
+	}
 	return fmt.Sprintf(
-		"%sProxy(%s:%s):\n%s  %s %s: %s",
-		indent,
-		pm.proxy.ProxyKey,
-		pm.proxy.Name,
+		"\n%s  %s constraints for %s: %s",
 		indent,
 		verb,
 		noun,
-		strings.Join(dNames, ", "),
+		strings.Join(cNames, ", "),
+	)
+}
+
+func sharedRulesModStr(sm *sharedRulesMod, indent, verb string) string {
+	return indent + srStr(sm.sr) + ":" + clustersToRemoveStr(sm.clusters, indent, verb)
+}
+
+func routeModStr(rm *routeMod, d api.Domain, indent, verb string) string {
+	return indent + routeStr(rm.route, d) + ":" + clustersToRemoveStr(rm.clusters, indent, verb)
+}
+
+func listenerModStr(lm *listenerMod, indent, verb string) string {
+	return fmt.Sprintf(
+		"%sListener(%s:%s):%s",
+		indent,
+		lm.listener.ListenerKey,
+		lm.listener.Name,
+		domainsToRemoveStr(lm.domainsToRemove, indent, verb),
 	)
 }
 
 func (d *deleter) report() string {
 	str := "Deep deletion will delete the following objects:\n"
 
-	for _, r := range d.routes {
+	for _, rk := range sortedKeys(d.routes) {
+		r := d.routes[api.RouteKey(rk)]
 		dom := d.domainsForReport[r.DomainKey]
 		str += "  " + routeStr(r, dom) + "\n"
 	}
 
-	for _, sr := range d.srs {
-		str += "  " + srStr(sr) + "\n"
+	for _, srk := range sortedKeys(d.srs) {
+		str += "  " + srStr(d.srs[api.SharedRulesKey(srk)]) + "\n"
 	}
 
-	for _, p := range d.proxies {
-		str += "  " + proxyStr(p) + "\n"
+	for _, pk := range sortedKeys(d.proxies) {
+		str += "  " + proxyStr(d.proxies[api.ProxyKey(pk)]) + "\n"
 	}
 
-	for _, dom := range d.domains {
-		str += "  " + domainStr(dom) + "\n"
+	for _, lk := range sortedKeys(d.listeners) {
+		str += "  " + listenerStr(d.listeners[api.ListenerKey(lk)]) + "\n"
 	}
 
-	for _, c := range d.clusters {
-		str += "  " + clusterStr(c) + "\n"
+	for _, dk := range sortedKeys(d.domains) {
+		str += "  " + domainStr(d.domains[api.DomainKey(dk)]) + "\n"
+	}
+
+	for _, ck := range sortedKeys(d.clusters) {
+		str += "  " + clusterStr(d.clusters[api.ClusterKey(ck)]) + "\n"
 	}
 
 	if d.zoneIsSet() {
@@ -363,146 +749,85 @@ func (d *deleter) report() string {
 
 	if len(d.proxyMods) != 0 {
 		str += "Additionally, the following proxies will be modified:\n"
-		for _, pm := range d.proxyMods {
-			str += proxyModStr(pm, "  ", "Remove") + "\n"
+		for _, pk := range sortedKeys(d.proxyMods) {
+			str += proxyModStr(d.proxyMods[api.ProxyKey(pk)], "  ", "Remove") + "\n"
 		}
 	}
 
-	str += "Proceed?"
-
-	return str
-}
-
-func (d *deleter) execute() error {
-	if !d.zoneIsSet() {
-		d.addOrphans()
-	}
-
-	if ok, err := terminal.Ask(tbnos.New(), d.report()); err != nil {
-		return err
-	} else if !ok {
-		return fmt.Errorf("canceled deep deletion")
-	}
-
-	for _, r := range d.routes {
-		dom := d.domainsForReport[r.DomainKey]
-		console.Info().Printf("Deleting %s", routeStr(r, dom))
-		if err := d.svc.Route().Delete(r.RouteKey, r.Checksum); err != nil {
-			return err
+	if len(d.listenerMods) != 0 {
+		str += "Additionally, the following listeners will be modified:\n"
+		for _, lk := range sortedKeys(d.listenerMods) {
+			str += listenerModStr(d.listenerMods[api.ListenerKey(lk)], "  ", "Remove") + "\n"
 		}
-		console.Info().Printf("Deleted %s", routeStr(r, dom))
 	}
 
-	for _, sr := range d.srs {
-		console.Info().Printf("Deleting %s", srStr(sr))
-		if err := d.svc.SharedRules().Delete(sr.SharedRulesKey, sr.Checksum); err != nil {
-			return err
+	if len(d.srMods) != 0 {
+		str += "Additionally, the following shared_rules will be modified:\n"
+		for _, srk := range sortedKeys(d.srMods) {
+			str += sharedRulesModStr(d.srMods[api.SharedRulesKey(srk)], "  ", "Remove") + "\n"
 		}
-		console.Info().Printf("Deleted %s", srStr(sr))
 	}
 
-	for _, p := range d.proxies {
-		console.Info().Printf("Deleting %s", proxyStr(p))
-		if err := d.svc.Proxy().Delete(p.ProxyKey, p.Checksum); err != nil {
-			return err
+	if len(d.routeMods) != 0 {
+		str += "Additionally, the following routes will be modified:\n"
+		for _, rk := range sortedKeys(d.routeMods) {
+			rm := d.routeMods[api.RouteKey(rk)]
+			dom := d.domainsForReport[rm.route.DomainKey]
+			str += routeModStr(rm, dom, "  ", "Remove") + "\n"
 		}
-		console.Info().Printf("Deleted %s", proxyStr(p))
 	}
 
-	for _, pm := range d.proxyMods {
-		p := pm.proxy
-		var dks []api.DomainKey
-		for _, dk := range p.DomainKeys {
-			if _, ok := pm.domainsToRemove[dk]; !ok {
-				// only add back in if not to be removed
-				dks = append(dks, dk)
-			}
-		}
-		p.DomainKeys = dks
-
-		console.Info().Println(proxyModStr(pm, "", "Deleting"))
-		if _, err := d.svc.Proxy().Modify(p); err != nil {
-			return err
-		}
-		console.Info().Println(proxyModStr(pm, "", "Deleted"))
-	}
+	str += "Proceed?"
 
-	for _, dom := range d.domains {
-		console.Info().Printf("Deleting %s", domainStr(dom))
-		if err := d.svc.Domain().Delete(dom.DomainKey, dom.Checksum); err != nil {
-			return err
-		}
-		console.Info().Printf("Deleted %s", domainStr(dom))
-	}
+	return str
+}
 
-	for _, c := range d.clusters {
-		console.Info().Printf("Deleting %s", clusterStr(c))
-		if err := d.svc.Cluster().Delete(c.ClusterKey, c.Checksum); err != nil {
-			return err
-		}
-		console.Info().Printf("Deleted %s", clusterStr(c))
+// newDeepDeleter returns a deleter populated by the given function. Shared
+// rules orphaned by the deletion of their Routes are included, unless a Zone
+// is being deleted, in which case they are included already.
+func newDeepDeleter(svc *unifiedSvc, add func(*deleter) error) (*deleter, error) {
+	d := newDeleter(svc)
+	if err := add(d); err != nil {
+		return nil, err
 	}
-
-	if d.zoneIsSet() {
-		console.Info().Printf("Deleting %s", zoneStr(d.zone))
-		if err := d.svc.Zone().Delete(d.zone.ZoneKey, d.zone.Checksum); err != nil {
-			return err
+	if !d.zoneIsSet() {
+		if err := d.addOrphans(); err != nil {
+			return nil, err
 		}
-		console.Info().Printf("Deleted %s", zoneStr(d.zone))
 	}
-
-	return nil
+	return d, nil
 }
 
-func (a clusterAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	console.Error().Println("warning: --deep ignored for cluster delete")
-	return a.Delete(k, cs)
+func (a clusterAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addClusterKey(api.ClusterKey(k)) })
 }
 
-func (a domainAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	d := newDeleter(svc)
-	if err := d.addDomainKey(api.DomainKey(k)); err != nil {
-		return err
-	}
-	return d.execute()
+func (a domainAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addDomainKey(api.DomainKey(k)) })
 }
 
-func (a proxyAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	console.Error().Println("warning: --deep ignored for proxy delete")
-	return a.Delete(k, cs)
+func (a proxyAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addProxyKey(api.ProxyKey(k)) })
 }
 
-// per #5630 we should figure out what to do with listeners in a deep delete
-func (a listenerAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	console.Error().Println("warning: --deep ignored for listener delete")
-	return a.Delete(k, cs)
+func (a listenerAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addListenerKey(api.ListenerKey(k)) })
 }
 
-func (a routeAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	d := newDeleter(svc)
-	if err := d.addRouteKey(api.RouteKey(k)); err != nil {
-		return err
-	}
-	return d.execute()
+func (a routeAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addRouteKey(api.RouteKey(k)) })
 }
 
-func (a sharedRulesAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	d := newDeleter(svc)
-	if err := d.addSharedRulesKey(api.SharedRulesKey(k)); err != nil {
-		return err
-	}
-	return d.execute()
+func (a sharedRulesAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addSharedRulesKey(api.SharedRulesKey(k)) })
 }
 
-func (a userAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
+// DeepDeleter returns nil, since Users have no dependent objects.
+func (a userAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
 	console.Error().Println("warning: --deep ignored for user delete")
-	return a.Delete(k, cs)
+	return nil, nil
 }
 
-func (a zoneAdapter) DeepDelete(k string, cs api.Checksum, svc *unifiedSvc) error {
-	d := newDeleter(svc)
-	if err := d.addZoneKey(api.ZoneKey(k)); err != nil {
-		return err
-	}
-	return d.execute()
+func (a zoneAdapter) DeepDeleter(k string, svc *unifiedSvc) (*deleter, error) {
+	return newDeepDeleter(svc, func(d *deleter) error { return d.addZoneKey(api.ZoneKey(k)) })
 }
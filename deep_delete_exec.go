@@ -0,0 +1,345 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/cli/terminal"
+	"github.com/turbinelabs/nonstdlib/log/console"
+	tbnos "github.com/turbinelabs/nonstdlib/os"
+)
+
+const defaultDeleteConcurrency = 4
+
+// sortedKeys returns the keys of a map with string-typed keys, such as the
+// maps of objects held by a deleter, in sorted order.
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deleteStep is a single deletion or modification made by a deleter.
+type deleteStep struct {
+	id    string
+	start string
+	done  string
+	run   func() error
+}
+
+func deleteObjStep(ot objecttype.ObjectType, key, desc string, run func() error) deleteStep {
+	return deleteStep{stepID(changeDelete, ot, key), "Deleting " + desc, "Deleted " + desc, run}
+}
+
+// layers returns the steps of the deleter, grouped such that every step in a
+// layer depends only on steps in earlier layers. Within each layer, steps are
+// ordered by object type and key.
+func (d *deleter) layers() [][]deleteStep {
+	var (
+		// Routes and Proxies refer to other objects but are not referred
+		// to, so they can go first, along with modifications which only
+		// remove references to Clusters and Domains.
+		first []deleteStep
+		// SharedRules can be deleted once their Routes are gone, and
+		// Proxies stop referring to Listeners and Domains.
+		second []deleteStep
+		// Listeners and Clusters can be deleted once no longer referred to
+		// by Proxies, SharedRules or Routes.
+		third []deleteStep
+		// Domains are referred to by Routes, Proxies and Listeners.
+		fourth []deleteStep
+		// The Zone contains everything else.
+		last []deleteStep
+	)
+
+	for _, rk := range sortedKeys(d.routes) {
+		r := d.routes[api.RouteKey(rk)]
+		first = append(first, deleteObjStep(objecttype.Route, rk, routeStr(r, d.domainsForReport[r.DomainKey]), func() error {
+			return d.svc.Route().Delete(r.RouteKey, r.Checksum)
+		}))
+	}
+
+	for _, rk := range sortedKeys(d.routeMods) {
+		rm := d.routeMods[api.RouteKey(rk)]
+		if _, ok := d.routes[rm.route.RouteKey]; ok {
+			continue
+		}
+		dom := d.domainsForReport[rm.route.DomainKey]
+		first = append(first, deleteStep{
+			stepID(changeModify, objecttype.Route, rk),
+			routeModStr(rm, dom, "", "Deleting"),
+			routeModStr(rm, dom, "", "Deleted"),
+			func() error {
+				_, err := d.svc.Route().Modify(rm.route)
+				return err
+			},
+		})
+	}
+
+	for _, pk := range sortedKeys(d.proxies) {
+		p := d.proxies[api.ProxyKey(pk)]
+		first = append(first, deleteObjStep(objecttype.Proxy, pk, proxyStr(p), func() error {
+			return d.svc.Proxy().Delete(p.ProxyKey, p.Checksum)
+		}))
+	}
+
+	for _, srk := range sortedKeys(d.srMods) {
+		sm := d.srMods[api.SharedRulesKey(srk)]
+		if _, ok := d.srs[sm.sr.SharedRulesKey]; ok {
+			continue
+		}
+		first = append(first, deleteStep{
+			stepID(changeModify, objecttype.SharedRules, srk),
+			sharedRulesModStr(sm, "", "Deleting"),
+			sharedRulesModStr(sm, "", "Deleted"),
+			func() error {
+				_, err := d.svc.SharedRules().Modify(sm.sr)
+				return err
+			},
+		})
+	}
+
+	for _, lk := range sortedKeys(d.listenerMods) {
+		lm := d.listenerMods[api.ListenerKey(lk)]
+		l := lm.listener
+		if _, ok := d.listeners[l.ListenerKey]; ok {
+			continue
+		}
+		var dks []api.DomainKey
+		for _, dk := range l.DomainKeys {
+			if _, ok := lm.domainsToRemove[dk]; !ok {
+				dks = append(dks, dk)
+			}
+		}
+		l.DomainKeys = dks
+		first = append(first, deleteStep{
+			stepID(changeModify, objecttype.Listener, lk),
+			listenerModStr(lm, "", "Deleting"),
+			listenerModStr(lm, "", "Deleted"),
+			func() error {
+				_, err := d.svc.Listener().Modify(l)
+				return err
+			},
+		})
+	}
+
+	for _, srk := range sortedKeys(d.srs) {
+		sr := d.srs[api.SharedRulesKey(srk)]
+		second = append(second, deleteObjStep(objecttype.SharedRules, srk, srStr(sr), func() error {
+			return d.svc.SharedRules().Delete(sr.SharedRulesKey, sr.Checksum)
+		}))
+	}
+
+	for _, pk := range sortedKeys(d.proxyMods) {
+		pm := d.proxyMods[api.ProxyKey(pk)]
+		p := pm.proxy
+		if _, ok := d.proxies[p.ProxyKey]; ok {
+			continue
+		}
+		var dks []api.DomainKey
+		for _, dk := range p.DomainKeys {
+			if _, ok := pm.domainsToRemove[dk]; !ok {
+				// only add back in if not to be removed
+				dks = append(dks, dk)
+			}
+		}
+		p.DomainKeys = dks
+		var lks []api.ListenerKey
+		for _, lk := range p.ListenerKeys {
+			if _, ok := pm.listenersToRemove[lk]; !ok {
+				lks = append(lks, lk)
+			}
+		}
+		p.ListenerKeys = lks
+		second = append(second, deleteStep{
+			stepID(changeModify, objecttype.Proxy, pk),
+			proxyModStr(pm, "", "Deleting"),
+			proxyModStr(pm, "", "Deleted"),
+			func() error {
+				_, err := d.svc.Proxy().Modify(p)
+				return err
+			},
+		})
+	}
+
+	for _, lk := range sortedKeys(d.listeners) {
+		l := d.listeners[api.ListenerKey(lk)]
+		third = append(third, deleteObjStep(objecttype.Listener, lk, listenerStr(l), func() error {
+			return d.svc.Listener().Delete(l.ListenerKey, l.Checksum)
+		}))
+	}
+
+	for _, ck := range sortedKeys(d.clusters) {
+		c := d.clusters[api.ClusterKey(ck)]
+		third = append(third, deleteObjStep(objecttype.Cluster, ck, clusterStr(c), func() error {
+			return d.svc.Cluster().Delete(c.ClusterKey, c.Checksum)
+		}))
+	}
+
+	for _, dk := range sortedKeys(d.domains) {
+		dom := d.domains[api.DomainKey(dk)]
+		fourth = append(fourth, deleteObjStep(objecttype.Domain, dk, domainStr(dom), func() error {
+			return d.svc.Domain().Delete(dom.DomainKey, dom.Checksum)
+		}))
+	}
+
+	if d.zoneIsSet() {
+		z := d.zone
+		last = append(last, deleteObjStep(objecttype.Zone, string(z.ZoneKey), zoneStr(z), func() error {
+			return d.svc.Zone().Delete(z.ZoneKey, z.Checksum)
+		}))
+	}
+
+	return [][]deleteStep{first, second, third, fourth, last}
+}
+
+// progress reports the completion of steps, and records them in a journal,
+// if present.
+type progress struct {
+	mu      sync.Mutex
+	done    int
+	total   int
+	journal *deleteJournal
+}
+
+func (p *progress) step(s deleteStep) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	console.Info().Printf("[%d/%d] %s", p.done, p.total, s.done)
+	if p.journal != nil {
+		return p.journal.complete(s.id)
+	}
+	return nil
+}
+
+// runLayer runs the given steps, at most concurrency at a time. No further
+// steps are started once one fails, and the first error is returned.
+func runLayer(steps []deleteStep, concurrency int, p *progress) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	sem := make(chan struct{}, concurrency)
+	for _, s := range steps {
+		sem <- struct{}{}
+		if failed() {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(s deleteStep) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			console.Debug().Println(s.start)
+			err := s.run()
+			if err == nil {
+				err = p.step(s)
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// execute performs the deletions and modifications planned by the deleter,
+// layer by layer, running up to d.concurrency steps at a time within each
+// layer. If ask is true, the plan is reported and confirmation is requested
+// first. Unless d.dryRun is true, completed steps are recorded in d.journal,
+// which is created if not set; if a step fails, the journal is kept so that
+// the deletion can be resumed, and otherwise it is removed.
+func (d *deleter) execute(ask bool) error {
+	if ask {
+		if ok, err := terminal.Ask(tbnos.New(), d.report()); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("canceled deep deletion")
+		}
+	}
+
+	var journal *deleteJournal
+	if !d.dryRun {
+		if d.journal == nil {
+			j, err := newDeleteJournal(d.plan())
+			if err != nil {
+				return fmt.Errorf("could not create deletion journal: %v", err)
+			}
+			d.journal = j
+		}
+		journal = d.journal
+	}
+
+	layers := d.layers()
+	p := &progress{journal: journal}
+	for _, l := range layers {
+		p.total += len(l)
+	}
+
+	for _, l := range layers {
+		if err := runLayer(l, d.concurrency, p); err != nil {
+			if journal == nil {
+				return err
+			}
+			return fmt.Errorf(
+				"%v; to continue, run: tbnctl delete --deep --resume %s",
+				err,
+				journal.path,
+			)
+		}
+	}
+
+	if journal != nil {
+		if err := journal.remove(); err != nil {
+			console.Error().Printf("could not remove deletion journal: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/codec"
+)
+
+// stepID identifies a step of a deep deletion, for recording in a
+// deleteJournal.
+func stepID(action string, ot objecttype.ObjectType, key string) string {
+	return fmt.Sprintf("%s %s %s", action, ot.Name, key)
+}
+
+// deleteJournal records the plan of a deep deletion and the steps completed so
+// far, so that a deletion which fails partway through can be resumed. It is
+// rewritten to disk as each step completes.
+type deleteJournal struct {
+	Plan      deletePlan `json:"plan"`
+	Completed []string   `json:"completed"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// newDeleteJournal creates a journal for the given plan in a new temporary
+// file.
+func newDeleteJournal(p deletePlan) (*deleteJournal, error) {
+	f, err := ioutil.TempFile("", "tbnctl-delete-")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	j := &deleteJournal{Plan: p, Completed: []string{}, path: f.Name()}
+	if err := j.write(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// readDeleteJournal reads a journal written by a previous deep deletion.
+// Subsequent progress is recorded in the same file.
+func readDeleteJournal(path string) (*deleteJournal, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &deleteJournal{path: path}
+	if err := codec.DecodeFromString(codec.NewJson(), string(b), j); err != nil {
+		return nil, fmt.Errorf("could not decode journal %s: %v", path, err)
+	}
+	return j, nil
+}
+
+func (j *deleteJournal) write() error {
+	buf := &bytes.Buffer{}
+	if err := codec.NewJson().Encode(j, buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, buf.Bytes(), 0600)
+}
+
+// completed returns the set of steps completed so far.
+func (j *deleteJournal) completed() map[string]bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	done := map[string]bool{}
+	for _, id := range j.Completed {
+		done[id] = true
+	}
+	return done
+}
+
+// complete records the completion of a step.
+func (j *deleteJournal) complete(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Completed = append(j.Completed, id)
+	return j.write()
+}
+
+// remove deletes the journal once the deletion is complete.
+func (j *deleteJournal) remove() error {
+	return os.Remove(j.path)
+}
@@ -0,0 +1,516 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+)
+
+// planObject identifies an object to be deleted or modified by a deletePlan,
+// and the checksum it had when the plan was made.
+type planObject struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+// planModification describes the references to be removed from an object
+// which remains after a deep deletion.
+type planModification struct {
+	planObject
+
+	RemoveDomainKeys   []string `json:"remove_domain_keys,omitempty"`
+	RemoveListenerKeys []string `json:"remove_listener_keys,omitempty"`
+	RemoveClusterKeys  []string `json:"remove_cluster_keys,omitempty"`
+}
+
+// deletePlan is the machine-readable form of a deleter. It may be saved, then
+// executed with newDeleterFromPlan, which verifies that none of the objects
+// have changed in between.
+type deletePlan struct {
+	Routes      []planObject `json:"routes"`
+	SharedRules []planObject `json:"shared_rules"`
+	Proxies     []planObject `json:"proxies"`
+	Listeners   []planObject `json:"listeners"`
+	Domains     []planObject `json:"domains"`
+	Clusters    []planObject `json:"clusters"`
+	Zone        *planObject  `json:"zone,omitempty"`
+
+	ProxyModifications       []planModification `json:"proxy_modifications"`
+	ListenerModifications    []planModification `json:"listener_modifications"`
+	SharedRulesModifications []planModification `json:"shared_rules_modifications"`
+	RouteModifications       []planModification `json:"route_modifications"`
+}
+
+func routeAddr(r api.Route, d api.Domain) string {
+	return fmt.Sprintf("%s%s", d.Addr(), r.Path)
+}
+
+// plan returns the deletePlan corresponding to the deleter. Objects of each
+// type are sorted by key.
+func (d *deleter) plan() deletePlan {
+	p := deletePlan{
+		Routes:                   []planObject{},
+		SharedRules:              []planObject{},
+		Proxies:                  []planObject{},
+		Listeners:                []planObject{},
+		Domains:                  []planObject{},
+		Clusters:                 []planObject{},
+		ProxyModifications:       []planModification{},
+		ListenerModifications:    []planModification{},
+		SharedRulesModifications: []planModification{},
+		RouteModifications:       []planModification{},
+	}
+
+	for _, rk := range sortedKeys(d.routes) {
+		r := d.routes[api.RouteKey(rk)]
+		name := routeAddr(r, d.domainsForReport[r.DomainKey])
+		p.Routes = append(p.Routes, planObject{string(r.RouteKey), name, r.Checksum.Checksum})
+	}
+
+	for _, srk := range sortedKeys(d.srs) {
+		sr := d.srs[api.SharedRulesKey(srk)]
+		p.SharedRules = append(
+			p.SharedRules,
+			planObject{string(sr.SharedRulesKey), sr.Name, sr.Checksum.Checksum},
+		)
+	}
+
+	for _, pk := range sortedKeys(d.proxies) {
+		px := d.proxies[api.ProxyKey(pk)]
+		p.Proxies = append(p.Proxies, planObject{string(px.ProxyKey), px.Name, px.Checksum.Checksum})
+	}
+
+	for _, lk := range sortedKeys(d.listeners) {
+		l := d.listeners[api.ListenerKey(lk)]
+		p.Listeners = append(p.Listeners, planObject{string(l.ListenerKey), l.Name, l.Checksum.Checksum})
+	}
+
+	for _, dk := range sortedKeys(d.domains) {
+		dom := d.domains[api.DomainKey(dk)]
+		p.Domains = append(p.Domains, planObject{string(dom.DomainKey), dom.Addr(), dom.Checksum.Checksum})
+	}
+
+	for _, ck := range sortedKeys(d.clusters) {
+		c := d.clusters[api.ClusterKey(ck)]
+		p.Clusters = append(p.Clusters, planObject{string(c.ClusterKey), c.Name, c.Checksum.Checksum})
+	}
+
+	if d.zoneIsSet() {
+		p.Zone = &planObject{string(d.zone.ZoneKey), d.zone.Name, d.zone.Checksum.Checksum}
+	}
+
+	for _, pk := range sortedKeys(d.proxyMods) {
+		pm := d.proxyMods[api.ProxyKey(pk)]
+		if _, ok := d.proxies[pm.proxy.ProxyKey]; ok {
+			continue
+		}
+		p.ProxyModifications = append(p.ProxyModifications, planModification{
+			planObject:         planObject{string(pm.proxy.ProxyKey), pm.proxy.Name, pm.proxy.Checksum.Checksum},
+			RemoveDomainKeys:   sortedKeys(pm.domainsToRemove),
+			RemoveListenerKeys: sortedKeys(pm.listenersToRemove),
+		})
+	}
+
+	for _, lk := range sortedKeys(d.listenerMods) {
+		lm := d.listenerMods[api.ListenerKey(lk)]
+		l := lm.listener
+		if _, ok := d.listeners[l.ListenerKey]; ok {
+			continue
+		}
+		p.ListenerModifications = append(p.ListenerModifications, planModification{
+			planObject:       planObject{string(l.ListenerKey), l.Name, l.Checksum.Checksum},
+			RemoveDomainKeys: sortedKeys(lm.domainsToRemove),
+		})
+	}
+
+	for _, srk := range sortedKeys(d.srMods) {
+		sm := d.srMods[api.SharedRulesKey(srk)]
+		sr := sm.sr
+		if _, ok := d.srs[sr.SharedRulesKey]; ok {
+			continue
+		}
+		p.SharedRulesModifications = append(p.SharedRulesModifications, planModification{
+			planObject:        planObject{string(sr.SharedRulesKey), sr.Name, sr.Checksum.Checksum},
+			RemoveClusterKeys: sortedKeys(sm.clusters),
+		})
+	}
+
+	for _, rk := range sortedKeys(d.routeMods) {
+		rm := d.routeMods[api.RouteKey(rk)]
+		r := rm.route
+		if _, ok := d.routes[r.RouteKey]; ok {
+			continue
+		}
+		name := routeAddr(r, d.domainsForReport[r.DomainKey])
+		p.RouteModifications = append(p.RouteModifications, planModification{
+			planObject:        planObject{string(r.RouteKey), name, r.Checksum.Checksum},
+			RemoveClusterKeys: sortedKeys(rm.clusters),
+		})
+	}
+
+	return p
+}
+
+// planLoader fetches the objects named in a deletePlan into a deleter. When
+// making a fresh start, it verifies that their checksums are unchanged. When
+// resuming, steps which are already complete are skipped, as are objects
+// which no longer exist, and current checksums are used.
+type planLoader struct {
+	d         *deleter
+	resuming  bool
+	completed map[string]bool
+}
+
+// include returns whether the object named by po, as fetched from the API,
+// should be included in the deleter.
+func (pl planLoader) include(
+	ot objecttype.ObjectType,
+	po planObject,
+	cs api.Checksum,
+	missing bool,
+) (bool, error) {
+	if pl.resuming {
+		return !missing, nil
+	}
+
+	if missing {
+		return false, fmt.Errorf("no %s found for key %s", ot.Name, po.Key)
+	}
+
+	if cs.Checksum != po.Checksum {
+		return false, fmt.Errorf(
+			"%s %s (%s) has changed since the plan was made; make a new plan",
+			ot.Name,
+			po.Name,
+			po.Key,
+		)
+	}
+
+	return true, nil
+}
+
+func (pl planLoader) done(action string, ot objecttype.ObjectType, po planObject) bool {
+	return pl.completed[stepID(action, ot, po.Key)]
+}
+
+func (pl planLoader) domain(dk api.DomainKey) (api.Domain, error) {
+	if dom, ok := pl.d.domains[dk]; ok {
+		return dom, nil
+	}
+	if dom, ok := pl.d.domainsForReport[dk]; ok {
+		return dom, nil
+	}
+	dom, err := pl.d.svc.Domain().Get(dk)
+	if err != nil {
+		return api.Domain{}, err
+	}
+	if pl.resuming && (api.Domain{}.Equals(dom)) {
+		// already deleted; keep the key for reporting
+		dom.DomainKey = dk
+	}
+	pl.d.domainsForReport[dk] = dom
+	return dom, nil
+}
+
+func (pl planLoader) cluster(ck api.ClusterKey) (api.Cluster, error) {
+	if c, ok := pl.d.clusters[ck]; ok {
+		return c, nil
+	}
+	c, err := pl.d.svc.Cluster().Get(ck)
+	if err != nil {
+		return api.Cluster{}, err
+	}
+	if (api.Cluster{}.Equals(c)) {
+		c.ClusterKey = ck
+	}
+	return c, nil
+}
+
+func (pl planLoader) route(action string, po planObject) (api.Route, bool, error) {
+	if pl.done(action, objecttype.Route, po) {
+		return api.Route{}, false, nil
+	}
+	r, err := pl.d.svc.Route().Get(api.RouteKey(po.Key))
+	if err != nil {
+		return api.Route{}, false, err
+	}
+	ok, err := pl.include(objecttype.Route, po, r.Checksum, api.Route{}.Equals(r))
+	if !ok || err != nil {
+		return api.Route{}, false, err
+	}
+	if _, err := pl.domain(r.DomainKey); err != nil {
+		return api.Route{}, false, err
+	}
+	return r, true, nil
+}
+
+func (pl planLoader) load(p deletePlan) error {
+	d := pl.d
+
+	if p.Zone != nil && !pl.done(changeDelete, objecttype.Zone, *p.Zone) {
+		z, err := d.svc.Zone().Get(api.ZoneKey(p.Zone.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Zone, *p.Zone, z.Checksum, api.Zone{}.Equals(z))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.zone = z
+		}
+	}
+
+	for _, po := range p.Clusters {
+		if pl.done(changeDelete, objecttype.Cluster, po) {
+			continue
+		}
+		c, err := d.svc.Cluster().Get(api.ClusterKey(po.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Cluster, po, c.Checksum, api.Cluster{}.Equals(c))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.clusters[c.ClusterKey] = c
+		}
+	}
+
+	for _, po := range p.Domains {
+		if pl.done(changeDelete, objecttype.Domain, po) {
+			continue
+		}
+		dom, err := d.svc.Domain().Get(api.DomainKey(po.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Domain, po, dom.Checksum, api.Domain{}.Equals(dom))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.domains[dom.DomainKey] = dom
+		}
+	}
+
+	for _, po := range p.Listeners {
+		if pl.done(changeDelete, objecttype.Listener, po) {
+			continue
+		}
+		l, err := d.svc.Listener().Get(api.ListenerKey(po.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Listener, po, l.Checksum, api.Listener{}.Equals(l))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.listeners[l.ListenerKey] = l
+		}
+	}
+
+	for _, po := range p.Proxies {
+		if pl.done(changeDelete, objecttype.Proxy, po) {
+			continue
+		}
+		px, err := d.svc.Proxy().Get(api.ProxyKey(po.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Proxy, po, px.Checksum, api.Proxy{}.Equals(px))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.proxies[px.ProxyKey] = px
+		}
+	}
+
+	for _, po := range p.SharedRules {
+		if pl.done(changeDelete, objecttype.SharedRules, po) {
+			continue
+		}
+		sr, err := d.svc.SharedRules().Get(api.SharedRulesKey(po.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.SharedRules, po, sr.Checksum, api.SharedRules{}.Equals(sr))
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.srs[sr.SharedRulesKey] = sr
+		}
+	}
+
+	for _, po := range p.Routes {
+		r, ok, err := pl.route(changeDelete, po)
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.routes[r.RouteKey] = r
+		}
+	}
+
+	for _, pm := range p.ProxyModifications {
+		if pl.done(changeModify, objecttype.Proxy, pm.planObject) {
+			continue
+		}
+		px, err := d.svc.Proxy().Get(api.ProxyKey(pm.Key))
+		if err != nil {
+			return err
+		}
+		ok, err := pl.include(objecttype.Proxy, pm.planObject, px.Checksum, api.Proxy{}.Equals(px))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mod := d.proxyMod(px)
+		for _, dk := range pm.RemoveDomainKeys {
+			dom, err := pl.domain(api.DomainKey(dk))
+			if err != nil {
+				return err
+			}
+			mod.domainsToRemove[dom.DomainKey] = dom
+		}
+		for _, lk := range pm.RemoveListenerKeys {
+			l, ok := d.listeners[api.ListenerKey(lk)]
+			if !ok {
+				if !pl.resuming {
+					return fmt.Errorf("proxy %s: listener %s is not deleted by the plan", pm.Name, lk)
+				}
+				// already deleted
+				l.ListenerKey = api.ListenerKey(lk)
+			}
+			mod.listenersToRemove[l.ListenerKey] = l
+		}
+	}
+
+	for _, lm := range p.ListenerModifications {
+		if pl.done(changeModify, objecttype.Listener, lm.planObject) {
+			continue
+		}
+		l, err := d.svc.Listener().Get(api.ListenerKey(lm.Key))
+		if err != nil {
+			return err
+		}
+		missing := api.Listener{}.Equals(l)
+		ok, err := pl.include(objecttype.Listener, lm.planObject, l.Checksum, missing)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mod := &listenerMod{l, map[api.DomainKey]api.Domain{}}
+		for _, dk := range lm.RemoveDomainKeys {
+			dom, err := pl.domain(api.DomainKey(dk))
+			if err != nil {
+				return err
+			}
+			mod.domainsToRemove[dom.DomainKey] = dom
+		}
+		d.listenerMods[l.ListenerKey] = mod
+	}
+
+	for _, sm := range p.SharedRulesModifications {
+		if pl.done(changeModify, objecttype.SharedRules, sm.planObject) {
+			continue
+		}
+		sr, err := d.svc.SharedRules().Get(api.SharedRulesKey(sm.Key))
+		if err != nil {
+			return err
+		}
+		missing := api.SharedRules{}.Equals(sr)
+		ok, err := pl.include(objecttype.SharedRules, sm.planObject, sr.Checksum, missing)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mod := &sharedRulesMod{clusters: map[api.ClusterKey]api.Cluster{}}
+		for _, ck := range sm.RemoveClusterKeys {
+			c, err := pl.cluster(api.ClusterKey(ck))
+			if err != nil {
+				return err
+			}
+			withoutClusterAll(&sr.Default, c.ClusterKey)
+			sr.Rules, _ = withoutClusterRules(sr.Rules, c.ClusterKey)
+			mod.clusters[c.ClusterKey] = c
+		}
+		mod.sr = sr
+		d.srMods[sr.SharedRulesKey] = mod
+	}
+
+	for _, rm := range p.RouteModifications {
+		r, ok, err := pl.route(changeModify, rm.planObject)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mod := &routeMod{clusters: map[api.ClusterKey]api.Cluster{}}
+		for _, ck := range rm.RemoveClusterKeys {
+			c, err := pl.cluster(api.ClusterKey(ck))
+			if err != nil {
+				return err
+			}
+			r.Rules, _ = withoutClusterRules(r.Rules, c.ClusterKey)
+			mod.clusters[c.ClusterKey] = c
+		}
+		mod.route = r
+		d.routeMods[r.RouteKey] = mod
+	}
+
+	return nil
+}
+
+// newDeleterFromPlan returns a deleter which will carry out the given plan.
+// An error is returned if any object in the plan has been modified since the
+// plan was made.
+func newDeleterFromPlan(svc *unifiedSvc, p deletePlan) (*deleter, error) {
+	d := newDeleter(svc)
+	if err := (planLoader{d: d}).load(p); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newDeleterFromJournal returns a deleter which will carry out the steps of
+// the journal's plan which have not been completed, using the current
+// versions of the objects involved. Progress is recorded in the journal.
+func newDeleterFromJournal(svc *unifiedSvc, j *deleteJournal) (*deleter, error) {
+	d := newDeleter(svc)
+	pl := planLoader{d: d, resuming: true, completed: j.completed()}
+	if err := pl.load(j.Plan); err != nil {
+		return nil, err
+	}
+	d.journal = j
+	return d, nil
+}
@@ -0,0 +1,364 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestWithoutClusterRenormalizes(t *testing.T) {
+	ccs := api.ClusterConstraints{
+		{ClusterKey: "a", Weight: 50},
+		{ClusterKey: "b", Weight: 25},
+		{ClusterKey: "c", Weight: 25},
+	}
+
+	got, changed := withoutCluster(ccs, "a")
+	assert.True(t, changed)
+	assert.DeepEqual(t, got, api.ClusterConstraints{
+		{ClusterKey: "b", Weight: 50},
+		{ClusterKey: "c", Weight: 50},
+	})
+	assert.Equal(t, ccs[1].Weight, uint32(25))
+
+	got, changed = withoutCluster(ccs, "x")
+	assert.False(t, changed)
+	assert.DeepEqual(t, got, ccs)
+}
+
+func TestWithoutClusterRulesDropsEmptyRules(t *testing.T) {
+	rs := api.Rules{
+		{RuleKey: "r1", Constraints: api.AllConstraints{Light: api.ClusterConstraints{{ClusterKey: "a", Weight: 1}}}},
+		{
+			RuleKey: "r2",
+			Constraints: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "b", Weight: 1}},
+				Dark:  api.ClusterConstraints{{ClusterKey: "a", Weight: 1}},
+			},
+		},
+	}
+
+	got, changed := withoutClusterRules(rs, "a")
+	assert.True(t, changed)
+	assert.DeepEqual(t, got, api.Rules{
+		{
+			RuleKey: "r2",
+			Constraints: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "b", Weight: 1}},
+				Dark:  api.ClusterConstraints{},
+			},
+		},
+	})
+}
+
+func TestAddClusterKeyModifiesOrDeletesReferrers(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	msr := service.NewMockSharedRules(ctrl)
+	mr := service.NewMockRoute(ctrl)
+	md := service.NewMockDomain(ctrl)
+	all.EXPECT().Cluster().Return(mc).AnyTimes()
+	all.EXPECT().SharedRules().Return(msr).AnyTimes()
+	all.EXPECT().Route().Return(mr).AnyTimes()
+	all.EXPECT().Domain().Return(md).AnyTimes()
+
+	c := api.Cluster{ClusterKey: "ck", ZoneKey: "zk", Name: "old"}
+	mc.EXPECT().Get(api.ClusterKey("ck")).Return(c, nil)
+
+	only := api.SharedRules{
+		SharedRulesKey: "only",
+		Default:        api.AllConstraints{Light: api.ClusterConstraints{{ClusterKey: "ck", Weight: 1}}},
+	}
+	shared := api.SharedRules{
+		SharedRulesKey: "shared",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{{ClusterKey: "ck", Weight: 1}, {ClusterKey: "new", Weight: 1}},
+		},
+	}
+	msr.EXPECT().Index(service.SharedRulesFilter{ZoneKey: "zk"}).Return(api.SharedRulesSlice{only, shared}, nil)
+
+	onlyRoute := api.Route{RouteKey: "r1", DomainKey: "dk", SharedRulesKey: "only"}
+	otherRoute := api.Route{
+		RouteKey:       "r2",
+		DomainKey:      "dk",
+		SharedRulesKey: "shared",
+		Rules: api.Rules{
+			{Constraints: api.AllConstraints{Light: api.ClusterConstraints{{ClusterKey: "ck", Weight: 1}}}},
+		},
+	}
+	mr.EXPECT().Index(service.RouteFilter{SharedRulesKey: "only"}).Return(api.Routes{onlyRoute}, nil)
+	mr.EXPECT().Index(service.RouteFilter{ZoneKey: "zk"}).Return(api.Routes{onlyRoute, otherRoute}, nil)
+	md.EXPECT().Get(api.DomainKey("dk")).Return(api.Domain{DomainKey: "dk"}, nil)
+
+	d := newDeleter(&unifiedSvc{all, admin})
+	assert.Nil(t, d.addClusterKey("ck"))
+
+	assert.DeepEqual(t, d.clusters, map[api.ClusterKey]api.Cluster{"ck": c})
+	assert.DeepEqual(t, d.srs, map[api.SharedRulesKey]api.SharedRules{"only": only})
+	assert.DeepEqual(t, d.routes, map[api.RouteKey]api.Route{"r1": onlyRoute})
+
+	assert.Equal(t, len(d.srMods), 1)
+	assert.DeepEqual(
+		t,
+		d.srMods["shared"].sr.Default.Light,
+		api.ClusterConstraints{{ClusterKey: "new", Weight: 2}},
+	)
+
+	assert.Equal(t, len(d.routeMods), 1)
+	assert.DeepEqual(t, d.routeMods["r2"].route.Rules, api.Rules{})
+}
+
+func TestDeleterFromPlanVerifiesChecksums(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	msr := service.NewMockSharedRules(ctrl)
+	all.EXPECT().Cluster().Return(mc).AnyTimes()
+	all.EXPECT().SharedRules().Return(msr).AnyTimes()
+
+	c := api.Cluster{ClusterKey: "ck", Name: "old", Checksum: api.Checksum{Checksum: "c1"}}
+	sr := api.SharedRules{
+		SharedRulesKey: "srk",
+		Name:           "sr",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{{ClusterKey: "ck", Weight: 1}, {ClusterKey: "new", Weight: 1}},
+		},
+		Checksum: api.Checksum{Checksum: "s1"},
+	}
+
+	svc := &unifiedSvc{all, admin}
+	d := newDeleter(svc)
+	d.clusters["ck"] = c
+	mod := &sharedRulesMod{sr: sr, clusters: map[api.ClusterKey]api.Cluster{"ck": c}}
+	d.srMods["srk"] = mod
+
+	p := d.plan()
+	assert.DeepEqual(t, p.Clusters, []planObject{{"ck", "old", "c1"}})
+	assert.Equal(t, len(p.SharedRulesModifications), 1)
+	assert.DeepEqual(t, p.SharedRulesModifications[0].RemoveClusterKeys, []string{"ck"})
+
+	txt, err := codec.EncodeToString(codec.NewJson(), p)
+	assert.Nil(t, err)
+	var decoded deletePlan
+	assert.Nil(t, codec.DecodeFromString(codec.NewJson(), txt, &decoded))
+
+	mc.EXPECT().Get(api.ClusterKey("ck")).Return(c, nil)
+	msr.EXPECT().Get(api.SharedRulesKey("srk")).Return(sr, nil)
+
+	loaded, err := newDeleterFromPlan(svc, decoded)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, loaded.clusters, d.clusters)
+	assert.DeepEqual(
+		t,
+		loaded.srMods["srk"].sr.Default.Light,
+		api.ClusterConstraints{{ClusterKey: "new", Weight: 2}},
+	)
+
+	changed := c
+	changed.Checksum = api.Checksum{Checksum: "c2"}
+	mc.EXPECT().Get(api.ClusterKey("ck")).Return(changed, nil)
+
+	loaded, err = newDeleterFromPlan(svc, decoded)
+	assert.Nil(t, loaded)
+	assert.ErrorContains(t, err, "cluster old (ck) has changed since the plan was made")
+}
+
+func TestLayersAreSortedAndOrderedByDependency(t *testing.T) {
+	d := newDeleter(nil)
+	d.zone = api.Zone{ZoneKey: "z", Name: "z"}
+	d.clusters["c2"] = api.Cluster{ClusterKey: "c2", Name: "b"}
+	d.clusters["c1"] = api.Cluster{ClusterKey: "c1", Name: "a"}
+	d.domains["d1"] = api.Domain{DomainKey: "d1", Name: "d", Port: 80}
+	d.routes["r2"] = api.Route{RouteKey: "r2", DomainKey: "d1", Path: "/b"}
+	d.routes["r1"] = api.Route{RouteKey: "r1", DomainKey: "d1", Path: "/a"}
+	d.domainsForReport["d1"] = d.domains["d1"]
+	d.srs["sr"] = api.SharedRules{SharedRulesKey: "sr", Name: "sr"}
+	d.listeners["l"] = api.Listener{ListenerKey: "l", Name: "l", Port: 80}
+
+	got := [][]string{}
+	for _, l := range d.layers() {
+		descs := []string{}
+		for _, s := range l {
+			descs = append(descs, s.done)
+		}
+		got = append(got, descs)
+	}
+
+	assert.DeepEqual(t, got, [][]string{
+		{"Deleted Route(r1:d:80/a)", "Deleted Route(r2:d:80/b)"},
+		{"Deleted SharedRules(sr:sr)"},
+		{"Deleted Listener(l:l::80)", "Deleted Cluster(c1:a)", "Deleted Cluster(c2:b)"},
+		{"Deleted Domain(d1:d:80)"},
+		{"Deleted Zone(z:z)"},
+	})
+}
+
+func TestRunLayerBoundsConcurrencyAndStopsOnError(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		maxSeen int
+		ran     int
+	)
+
+	failure := errors.New("boom")
+	steps := []deleteStep{}
+	for i := 0; i < 10; i++ {
+		i := i
+		steps = append(steps, deleteStep{"id", "start", "done", func() error {
+			mu.Lock()
+			running++
+			ran++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			if i == 2 {
+				return failure
+			}
+			return nil
+		}})
+	}
+
+	err := runLayer(steps, 2, &progress{total: len(steps)})
+	assert.Equal(t, err, failure)
+	assert.True(t, maxSeen <= 2)
+	assert.True(t, ran < len(steps))
+
+	ran = 0
+	assert.Nil(t, runLayer(steps[3:], 3, &progress{total: 7}))
+	assert.Equal(t, ran, 7)
+}
+
+func TestDeleterFromJournalSkipsCompletedSteps(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	all.EXPECT().Cluster().Return(mc).AnyTimes()
+
+	j, err := newDeleteJournal(deletePlan{
+		Routes:   []planObject{{"r1", "d:80/", "r"}},
+		Clusters: []planObject{{"c1", "gone", "c"}, {"c2", "changed", "c"}},
+	})
+	assert.Nil(t, err)
+	defer j.remove()
+
+	assert.Nil(t, j.complete(stepID(changeDelete, objecttype.Route, "r1")))
+
+	j, err = readDeleteJournal(j.path)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, j.Completed, []string{"delete route r1"})
+
+	c2 := api.Cluster{ClusterKey: "c2", Name: "changed", Checksum: api.Checksum{Checksum: "new"}}
+	mc.EXPECT().Get(api.ClusterKey("c1")).Return(api.Cluster{}, nil)
+	mc.EXPECT().Get(api.ClusterKey("c2")).Return(c2, nil)
+
+	d, err := newDeleterFromJournal(&unifiedSvc{all, admin}, j)
+	assert.Nil(t, err)
+	assert.Equal(t, len(d.routes), 0)
+	assert.DeepEqual(t, d.clusters, map[api.ClusterKey]api.Cluster{"c2": c2})
+	assert.Equal(t, d.journal, j)
+}
+
+func TestExecuteDryRunSkipsJournal(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	all.EXPECT().Cluster().Return(mc).AnyTimes()
+
+	c := api.Cluster{ClusterKey: "c1", Name: "c", Checksum: api.Checksum{Checksum: "cs"}}
+	mc.EXPECT().Get(api.ClusterKey("c1")).Return(c, nil).Times(2)
+
+	rec := &dryRunRecorder{codec: codec.NewJson(), out: &bytes.Buffer{}}
+	svc := newDryRunSvc(&unifiedSvc{all, admin}, rec)
+	p := deletePlan{Clusters: []planObject{{"c1", "c", "cs"}}}
+
+	d, err := newDeleterFromPlan(svc, p)
+	assert.Nil(t, err)
+	d.dryRun = true
+	assert.Nil(t, d.execute(false))
+	assert.Nil(t, d.journal)
+	assert.Equal(t, len(rec.ops), 1)
+
+	// resuming under --dry-run leaves the journal as it was
+	j, err := newDeleteJournal(p)
+	assert.Nil(t, err)
+	defer j.remove()
+
+	d, err = newDeleterFromJournal(svc, j)
+	assert.Nil(t, err)
+	d.dryRun = true
+	assert.Nil(t, d.execute(false))
+	assert.Equal(t, len(rec.ops), 2)
+
+	j, err = readDeleteJournal(j.path)
+	assert.Nil(t, err)
+	assert.Equal(t, len(j.Completed), 0)
+}
+
+func TestDeletePlanOutputUnsupportedLeavesObject(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+	users := objs.gc.apiClient.User()
+	u, err := users.Create(api.User{LoginEmail: "someone@example.com"})
+	assert.Nil(t, err)
+
+	r := &delRunner{&delCfg{
+		globalConfigT: objs.gc,
+		deep:          true,
+		planOutput:    planOutputJSON,
+		yes:           true,
+		concurrency:   1,
+	}}
+	cerr := r.run(&command.Cmd{}, []string{"user", string(u.UserKey)})
+	assert.Equal(t, cerr.Code, command.CmdErrCodeBadInput)
+
+	_, err = users.Get(u.UserKey)
+	assert.Nil(t, err)
+}
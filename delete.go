@@ -17,14 +17,52 @@ limitations under the License.
 package main
 
 import (
+	"io/ioutil"
+
 	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	"github.com/turbinelabs/nonstdlib/log/console"
 )
 
+const planOutputJSON = "json"
+
+const deleteDesc = `Delete an object from the Turbine Labs API.
+
+With --deep, objects which depend on the object are also deleted, and
+references to them are removed from objects which remain. The plan is shown,
+and confirmation requested, before anything is changed; --yes skips the
+confirmation. Objects are deleted in dependency order: Routes and Proxies
+first, then SharedRules, then Listeners and Clusters, then Domains, and
+finally the Zone. Up to --concurrency objects are deleted at once within each
+of these groups, and progress is reported as each completes.
+
+Completed steps are recorded in a journal file. If a deep deletion fails
+partway through, the journal is kept and its name reported; the deletion can
+then be continued with --resume=<journal>. Objects which remain are fetched
+again, so changes made since the failure, for example to checksums, do not
+prevent the deletion from completing.
+
+With --plan-output=json, the plan is printed as JSON and nothing is changed.
+The saved plan may later be carried out with --plan=<file>, in which case no
+object type or key is required. Each object is checked against the checksum
+recorded in the plan, and nothing is changed if any object has been modified
+since the plan was made.
+
+object type is one of: `
+
 type delCfg struct {
 	*globalConfigT
 
-	key  string
-	deep bool
+	key         string
+	deep        bool
+	yes         bool
+	planOutput  string
+	planFile    string
+	resume      string
+	concurrency int
+	force       bool
+	selectors   tbnflag.Strings
 }
 
 func (dc *delCfg) Key() string         { return dc.key }
@@ -34,23 +72,198 @@ type delRunner struct {
 	cfg *delCfg
 }
 
-func (gc *delRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+// runResume continues a deep deletion from the journal it left behind.
+func (gc *delRunner) runResume(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) > 0 || gc.cfg.key != "" {
+		return cmd.BadInput("--resume takes no object type or key")
+	}
+
+	j, err := readDeleteJournal(gc.cfg.resume)
+	if err != nil {
+		return cmd.Errorf("could not read journal: %s", err)
+	}
+
+	d, err := newDeleterFromJournal(gc.cfg.apiClient, j)
+	if err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	d.concurrency = gc.cfg.concurrency
+	d.dryRun = gc.cfg.dryRunEnabled()
+	if err := d.execute(!gc.cfg.yes); err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
+
+// runSelector deletes every object matched by --selector, after asking once
+// for confirmation. Failures are reported, and the remaining objects are
+// still deleted.
+func (gc *delRunner) runSelector(cmd *command.Cmd, args []string) command.CmdErr {
+	if gc.cfg.deep || gc.cfg.planFile != "" || gc.cfg.planOutput != "" || gc.cfg.resume != "" {
+		return cmd.BadInput("--selector cannot be combined with --deep, --plan, --plan-output, or --resume")
+	}
+
+	sel, err := parseBulkSelector(gc.cfg.selectors.Strings)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
 	svc, err := gc.cfg.UntypedSvc(&args)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
 
+	if len(args) > 0 || gc.cfg.key != "" {
+		return cmd.BadInput("--selector takes no object key")
+	}
+
+	objs, err := gc.cfg.selectObjects(svc, sel, gc.cfg.force)
+	if err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+	if len(objs) == 0 {
+		return cmd.Errorf("no %s matches the selector", svc.Type().Name)
+	}
+
+	if ok, err := confirmBulk(svc, "deleted", objs, gc.cfg.yes); err != nil {
+		return cmd.Error(err)
+	} else if !ok {
+		return cmd.Error("canceled bulk deletion")
+	}
+
+	deleted := []interface{}{}
+	for _, obj := range objs {
+		if err := svc.Delete(svc.Key(obj), svc.Checksum(obj)); err != nil {
+			console.Error().Printf(
+				"could not delete %s %s: %s\n",
+				svc.Type().Name,
+				svc.Key(obj),
+				gc.cfg.prettyErr(err),
+			)
+			continue
+		}
+		deleted = append(deleted, obj)
+	}
+	gc.cfg.PrintResult(deleted)
+
+	if failed := len(objs) - len(deleted); failed > 0 {
+		return cmd.Errorf("%d of %d deletions failed", failed, len(objs))
+	}
+
+	return command.NoError()
+}
+
+// runPlan carries out a deletePlan previously saved with --plan-output.
+func (gc *delRunner) runPlan(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) > 0 || gc.cfg.key != "" {
+		return cmd.BadInput("--plan takes no object type or key")
+	}
+
+	bytes, err := ioutil.ReadFile(gc.cfg.planFile)
+	if err != nil {
+		return cmd.Errorf("could not read %s: %s", gc.cfg.planFile, err)
+	}
+
+	var p deletePlan
+	if err := codec.DecodeFromString(codec.NewJson(), string(bytes), &p); err != nil {
+		return cmd.BadInputf("could not decode plan %s: %s", gc.cfg.planFile, err)
+	}
+
+	d, err := newDeleterFromPlan(gc.cfg.apiClient, p)
+	if err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	if gc.cfg.planOutput != "" {
+		return gc.printPlan(d)
+	}
+
+	d.concurrency = gc.cfg.concurrency
+	d.dryRun = gc.cfg.dryRunEnabled()
+	if err := d.execute(!gc.cfg.yes); err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
+
+func (gc *delRunner) printPlan(d *deleter) command.CmdErr {
+	gc.cfg.codec = codec.NewJson()
+	gc.cfg.PrintResult(d.plan())
+	return command.NoError()
+}
+
+func (gc *delRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	switch gc.cfg.planOutput {
+	case "", planOutputJSON:
+	default:
+		return cmd.BadInputf("unsupported --plan-output %q", gc.cfg.planOutput)
+	}
+
+	if gc.cfg.concurrency < 1 {
+		return cmd.BadInput("--concurrency must be at least 1")
+	}
+
+	if len(gc.cfg.selectors.Strings) > 0 {
+		return gc.runSelector(cmd, args)
+	}
+
+	if gc.cfg.resume != "" {
+		if gc.cfg.planFile != "" || gc.cfg.planOutput != "" {
+			return cmd.BadInput("--resume cannot be combined with --plan or --plan-output")
+		}
+		return gc.runResume(cmd, args)
+	}
+
+	if gc.cfg.planFile != "" {
+		return gc.runPlan(cmd, args)
+	}
+
+	if gc.cfg.planOutput != "" && !gc.cfg.deep {
+		return cmd.BadInput("--plan-output requires --deep")
+	}
+
+	svc, err := gc.cfg.UntypedKeyedSvc(&args)
+	if err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
 	if cerr := updateKeyed(cmd, &args, gc.cfg); cerr != command.NoError() {
 		return cerr
 	}
 
+	if gc.cfg.key, err = gc.cfg.resolveKey(svc, gc.cfg.key); err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
 	obj, err := svc.Get(gc.cfg.key)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
 
+	if err := gc.cfg.checkZoneScope(svc.Type(), gc.cfg.key, obj, gc.cfg.force); err != nil {
+		return cmd.Error(err)
+	}
+
+	var d *deleter
 	if gc.cfg.deep {
-		err = svc.DeepDelete(gc.cfg.key, svc.Checksum(obj), gc.cfg.apiClient)
+		if d, err = svc.DeepDeleter(gc.cfg.key, gc.cfg.apiClient); err != nil {
+			return gc.cfg.PrettyCmdErr(cmd, err)
+		}
+		if gc.cfg.planOutput != "" {
+			if d == nil {
+				return cmd.BadInputf("--plan-output is not supported for %s", svc.Type().Name)
+			}
+			return gc.printPlan(d)
+		}
+	}
+
+	if d != nil {
+		d.concurrency = gc.cfg.concurrency
+		d.dryRun = gc.cfg.dryRunEnabled()
+		err = d.execute(!gc.cfg.yes)
 	} else {
 		err = svc.Delete(gc.cfg.key, svc.Checksum(obj))
 	}
@@ -79,7 +292,7 @@ func cmdDelete(cfg globalConfigT) *command.Cmd {
 		Name:        "delete",
 		Summary:     "delete an object from Turbine Labs API",
 		Usage:       "[OPTIONS] <object type> <object key>",
-		Description: "object type is one of: " + objTypeNames(),
+		Description: deleteDesc + objTypeNames() + "\n\n" + selectorDesc + "\n\n" + bulkSelectorDesc,
 		Runner:      runner,
 	}
 
@@ -97,5 +310,49 @@ func cmdDelete(cfg globalConfigT) *command.Cmd {
 		"if true, delete the entire object graph below the specified object",
 	)
 
+	cmd.Flags.BoolVar(
+		&runner.cfg.yes,
+		"yes",
+		false,
+		"if true, perform a deep or bulk deletion without asking for confirmation",
+	)
+
+	cmd.Flags.StringVar(
+		&runner.cfg.planOutput,
+		"plan-output",
+		"",
+		"if set to json, print the deep deletion plan as JSON instead of deleting anything",
+	)
+
+	cmd.Flags.StringVar(
+		&runner.cfg.planFile,
+		"plan",
+		"",
+		"carry out a deep deletion plan previously saved with --plan-output",
+	)
+
+	cmd.Flags.BoolVar(&runner.cfg.force, "force", false, zoneForceDesc)
+
+	runner.cfg.selectors = tbnflag.NewStrings()
+	cmd.Flags.Var(
+		&runner.cfg.selectors,
+		bulkSelectorFlag,
+		"delete every object matching this field_name=field_value term; may be repeated",
+	)
+
+	cmd.Flags.StringVar(
+		&runner.cfg.resume,
+		"resume",
+		"",
+		"continue a failed deep deletion from the journal file it reported",
+	)
+
+	cmd.Flags.IntVar(
+		&runner.cfg.concurrency,
+		"concurrency",
+		defaultDeleteConcurrency,
+		"the maximum number of objects to delete at once during a deep deletion",
+	)
+
 	return cmd
 }
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/nonstdlib/log/console"
+)
+
+const diffZoneDesc = `Show the differences between two Zones, or between a Zone
+and a Zone document in the format produced by export-zone.
+
+Both sides are exported with object keys replaced by names, so that objects are
+matched between Zones by name (Clusters, Proxies and SharedRules), by name and
+port (Domains), or by domain and path (Routes). Object keys, checksums and
+Cluster Instances are ignored.
+
+Each object which was added, removed or changed is printed along with the
+fields which differ. If --json is set, the differences are printed as a JSON
+array instead.
+
+If --values or --set is given, the document given with -f is treated as a Go
+text/template and rendered with the given values before it is decoded.
+
+The exit status is 0 if there are no differences, and 1 if there are
+differences or an error occurs.`
+
+func cmdDiffZone(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "diff-zone",
+		Summary:     "show the differences between two Zones",
+		Usage:       "[OPTIONS] <from-zone> (<to-zone>|-f <file>)",
+		Description: diffZoneDesc,
+	}
+
+	r := &diffZoneRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.file,
+		"f",
+		"",
+		"A file containing a Zone document to compare against, instead of a second Zone.",
+	)
+
+	cmd.Flags.BoolVar(
+		&r.json,
+		"json",
+		false,
+		"If true, print the differences as JSON.",
+	)
+
+	r.templates = newZoneTemplateFlags(&cmd.Flags)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type diffZoneRunner struct {
+	cfg       globalConfigT
+	file      string
+	json      bool
+	templates *zoneTemplateFlags
+}
+
+func (r *diffZoneRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *diffZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	switch {
+	case r.file == "" && len(args) != 2:
+		return cmd.BadInput("requires exactly two arguments")
+	case r.file != "" && len(args) != 1:
+		return cmd.BadInput("requires exactly one argument when -f is specified")
+	}
+
+	from, err := exportZone(r.cfg.apiClient, args[0])
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	var to *zoneObjects
+	if r.file != "" {
+		bytes, err := ioutil.ReadFile(r.file)
+		if err != nil {
+			return cmd.Errorf("could not read %s: %s", r.file, err)
+		}
+
+		txt, err := r.templates.render(templateName(r.file), string(bytes))
+		if err != nil {
+			return cmd.BadInput(err)
+		}
+
+		to = newZoneObjects()
+		if err := codec.DecodeFromString(r.cfg.codec, txt, to); err != nil {
+			return cmd.BadInputf("could not decode zone document: %s", err)
+		}
+	} else {
+		to, err = exportZone(r.cfg.apiClient, args[1])
+		if err != nil {
+			return r.cfg.PrettyCmdErr(cmd, err)
+		}
+	}
+
+	from.normalize()
+	to.normalize()
+
+	diffs, err := diffZones(from, to)
+	if err != nil {
+		return cmd.Error(err)
+	}
+
+	if r.json {
+		return r.printJSON(cmd, diffs)
+	}
+
+	for _, d := range diffs {
+		fmt.Print(d)
+	}
+
+	if len(diffs) > 0 {
+		return cmd.Errorf("found %d difference(s)", len(diffs))
+	}
+
+	return command.NoError()
+}
+
+// printJSON prints diffs to stdout as JSON. The JSON is encoded before
+// anything is written, and the difference count goes to stderr, so that
+// stdout holds nothing but the JSON array.
+func (r *diffZoneRunner) printJSON(cmd *command.Cmd, diffs []objectDiff) command.CmdErr {
+	txt, err := codec.EncodeToString(codec.NewJson(), diffs)
+	if err != nil {
+		return cmd.Error(err)
+	}
+	fmt.Println(strings.TrimSpace(txt))
+
+	if len(diffs) > 0 {
+		console.Error().Printf("found %d difference(s)\n", len(diffs))
+		// the count has been reported; exit non-zero without repeating it
+		return command.CmdErr{Cmd: cmd, Code: command.CmdErrCodeError}
+	}
+
+	return command.NoError()
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/codec"
+)
+
+// dryRunOp is a single mutating operation that would have been performed
+// against the API.
+type dryRunOp struct {
+	Seq    int         `json:"seq"`
+	Action string      `json:"action"`
+	Type   string      `json:"type"`
+	Key    string      `json:"key"`
+	Object interface{} `json:"object,omitempty"`
+}
+
+// dryRunRecorder prints mutating operations, in order, as they are
+// attempted. It may be used concurrently.
+type dryRunRecorder struct {
+	codec codec.Codec
+	out   io.Writer
+	ops   []dryRunOp
+	mu    sync.Mutex
+}
+
+// record prints and appends an operation. It must be called with mu held.
+func (r *dryRunRecorder) record(action string, ot objecttype.ObjectType, key string, obj interface{}) {
+	op := dryRunOp{len(r.ops) + 1, action, ot.Name, key, obj}
+	r.ops = append(r.ops, op)
+
+	fmt.Fprintf(r.out, "dry run: would %s %s %s\n", action, ot.Name, key)
+	if err := r.codec.Encode(op, r.out); err != nil {
+		fmt.Fprintln(r.out, op)
+	}
+	fmt.Fprintln(r.out)
+}
+
+// newKey produces a placeholder key for an object that would have been
+// created, so that objects which refer to it can be recorded as well. It must
+// be called with mu held.
+func (r *dryRunRecorder) newKey(ot objecttype.ObjectType) string {
+	return fmt.Sprintf("dry-run-%s-%d", ot.Name, len(r.ops)+1)
+}
+
+// newDryRunSvc wraps the given unifiedSvc such that Create, Modify, and Delete
+// calls are recorded rather than being passed to the API. Calls that only
+// read from the API are passed through.
+func newDryRunSvc(svc *unifiedSvc, rec *dryRunRecorder) *unifiedSvc {
+	isvc := newInterceptedSvc(svc, rec)
+	isvc.Admin = dryRunAdmin{isvc.Admin, rec}
+	return isvc
+}
+
+var _ interceptor = &dryRunRecorder{}
+
+func (r *dryRunRecorder) Create(a typelessIface, obj interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.newKey(a.Type())
+	obj = a.WithKey(obj, key)
+	r.record(changeCreate, a.Type(), key, obj)
+	return obj, nil
+}
+
+func (r *dryRunRecorder) Modify(a typelessIface, obj interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(changeModify, a.Type(), a.Key(obj), obj)
+	return obj, nil
+}
+
+func (r *dryRunRecorder) Delete(a typelessIface, key string, _ api.Checksum) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(changeDelete, a.Type(), key, nil)
+	return nil
+}
+
+// dryRunAdmin additionally records changes to AccessTokens, which are not
+// supported by typelessIface.
+type dryRunAdmin struct {
+	service.Admin
+	rec *dryRunRecorder
+}
+
+func (s dryRunAdmin) AccessToken() service.AccessToken {
+	return dryRunAccessToken{s.Admin.AccessToken(), s.rec}
+}
+
+type dryRunAccessToken struct {
+	service.AccessToken
+	rec *dryRunRecorder
+}
+
+func (s dryRunAccessToken) Create(at api.AccessToken) (api.AccessToken, error) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	at.AccessTokenKey = api.AccessTokenKey(s.rec.newKey(objecttype.AccessToken))
+	s.rec.record(changeCreate, objecttype.AccessToken, string(at.AccessTokenKey), at)
+	return at, nil
+}
+
+func (s dryRunAccessToken) Delete(k api.AccessTokenKey, _ api.Checksum) error {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.rec.record(changeDelete, objecttype.AccessToken, string(k), nil)
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestDryRunRecordsMutations(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	all.EXPECT().Cluster().Return(mc).Times(4)
+
+	existing := api.Cluster{ClusterKey: "ck", Name: "existing"}
+	mc.EXPECT().Get(api.ClusterKey("ck")).Return(existing, nil)
+
+	buf := &bytes.Buffer{}
+	rec := &dryRunRecorder{codec: codec.NewJson(), out: buf}
+	svc := newDryRunSvc(&unifiedSvc{all, admin}, rec)
+
+	created, err := svc.Cluster().Create(api.Cluster{Name: "new"})
+	assert.Nil(t, err)
+	assert.Equal(t, created.ClusterKey, api.ClusterKey("dry-run-cluster-1"))
+
+	got, err := svc.Cluster().Get("ck")
+	assert.Nil(t, err)
+	assert.True(t, got.Equals(existing))
+
+	got.RequireTLS = true
+	_, err = svc.Cluster().Modify(got)
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.Cluster().Delete("ck", api.Checksum{}))
+
+	assert.Equal(t, len(rec.ops), 3)
+	for i, action := range []string{changeCreate, changeModify, changeDelete} {
+		assert.Equal(t, rec.ops[i].Seq, i+1)
+		assert.Equal(t, rec.ops[i].Action, action)
+		assert.Equal(t, rec.ops[i].Type, objecttype.Cluster.Name)
+	}
+	assert.NotEqual(t, buf.Len(), 0)
+}
+
+func TestDryRunRecordsConcurrentAccessTokens(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	admin.EXPECT().AccessToken().Return(service.NewMockAccessToken(ctrl)).AnyTimes()
+
+	rec := &dryRunRecorder{codec: codec.NewJson(), out: &bytes.Buffer{}}
+	svc := newDryRunSvc(&unifiedSvc{all, admin}, rec)
+
+	const n = 10
+	keys := make([]api.AccessTokenKey, n)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			at, err := svc.AccessToken().Create(api.AccessToken{Description: "t"})
+			assert.Nil(t, err)
+			keys[i] = at.AccessTokenKey
+			assert.Nil(t, svc.AccessToken().Delete(at.AccessTokenKey, api.Checksum{}))
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[api.AccessTokenKey]bool{}
+	for _, k := range keys {
+		seen[k] = true
+	}
+	assert.Equal(t, len(seen), n)
+	assert.Equal(t, len(rec.ops), 2*n)
+}
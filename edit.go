@@ -18,14 +18,22 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	"github.com/turbinelabs/nonstdlib/log/console"
 )
 
 type editCfg struct {
 	*globalConfigT
 
-	key string
+	key       string
+	force     bool
+	selectors tbnflag.Strings
+	yes       bool
 }
 
 type editRunner struct {
@@ -36,30 +44,242 @@ func (c *editCfg) Key() string         { return c.key }
 func (c *editCfg) UpdateKey(nk string) { c.key = nk }
 
 func (gc *editRunner) run(svc typelessIface) error {
-	objstr, err := editOrStdin(
+	// base is the version of the object presented in the editor, if any.
+	var base interface{}
+
+	return editOrStdin(
 		func() (interface{}, error) {
 			if gc.cfg.key == "" {
 				return nil, errors.New("object key must be specified")
 			}
-			return svc.Get(gc.cfg.key)
+			obj, err := svc.Get(gc.cfg.key)
+			base = obj
+			return obj, err
 		},
 		gc.cfg.globalConfigT,
+		func(txt string) (string, error) {
+			return gc.apply(svc, txt, &base)
+		},
 	)
+}
+
+// apply decodes and modifies the object. If the object was modified
+// concurrently, the edited text is preserved in a temporary file and the
+// changes made to base are merged onto the current version, which becomes
+// the new base. If the merge conflicts, text with conflict markers is returned
+// along with an error.
+func (gc *editRunner) apply(svc typelessIface, txt string, base *interface{}) (string, error) {
+	if hasConflictMarkers(txt) {
+		return "", errors.New("unresolved conflict markers")
+	}
+
+	for {
+		dest, err := svc.ObjFromString(txt, gc.cfg.codec)
+		if err != nil {
+			return "", err
+		}
+
+		// the edited object may have been moved out of the Zone
+		if err := gc.cfg.checkZoneScope(svc.Type(), svc.Key(dest), dest, gc.cfg.force); err != nil {
+			return "", err
+		}
+
+		obj, modErr := svc.Modify(dest)
+		if modErr == nil {
+			gc.cfg.PrintResult(obj)
+			return "", nil
+		}
+
+		current, conflict := checksumConflict(svc, svc.Key(dest), svc.Checksum(dest))
+		if !conflict {
+			return "", modErr
+		}
+
+		path, err := preserveEdit(txt)
+		if err != nil {
+			return "", fmt.Errorf("%v (%v)", modErr, err)
+		}
+		console.Error().Printf(
+			"%s %s was modified while you were editing it; your version is saved in %s\n",
+			svc.Type().Name,
+			svc.Key(dest),
+			path,
+		)
+
+		if *base == nil {
+			return "", fmt.Errorf("%v: changes read from STDIN cannot be merged", modErr)
+		}
+
+		merged, conflicts, err := gc.merge(*base, dest, current)
+		*base = current
+		if err != nil {
+			return "", err
+		}
+
+		if len(conflicts) > 0 {
+			return merged, fmt.Errorf(
+				"the object was modified while you were editing it, and your changes to the following fields conflict with the current version:\n\n    %s\n\nResolve the conflicts between %q and %q and remove the markers. Your version is saved in %s.",
+				strings.Join(conflicts, "\n    "),
+				conflictStart,
+				conflictEnd,
+				path,
+			)
+		}
+
+		console.Info().Println("merged your changes onto the current version")
+		txt = merged
+	}
+}
+
+// runSelector presents every object matched by --selector in the editor, as a
+// list, and modifies those which were changed, after asking once for
+// confirmation.
+func (gc *editRunner) runSelector(cmd *command.Cmd, svc typelessIface, args []string) command.CmdErr {
+	if len(args) > 0 || gc.cfg.key != "" {
+		return cmd.BadInput("--selector takes no object key")
+	}
+
+	sel, err := parseBulkSelector(gc.cfg.selectors.Strings)
 	if err != nil {
-		return err
+		return cmd.BadInput(err)
 	}
 
-	dest, err := svc.ObjFromString(objstr, gc.cfg.codec)
+	objs, err := gc.cfg.selectObjects(svc, sel, gc.cfg.force)
 	if err != nil {
-		return err
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+	if len(objs) == 0 {
+		return cmd.Errorf("no %s matches the selector", svc.Type().Name)
 	}
 
-	obj, err := svc.Modify(dest)
+	err = editOrStdin(
+		func() (interface{}, error) { return objs, nil },
+		gc.cfg.globalConfigT,
+		func(txt string) (string, error) { return gc.applyBulk(svc, txt, objs) },
+	)
 	if err != nil {
-		return err
+		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
-	gc.cfg.PrintResult(obj)
-	return nil
+
+	return command.NoError()
+}
+
+// applyBulk decodes a list of objects, each of which must be one of the
+// selected objects, and modifies those which differ from their selected
+// version. If any modifications fail, the objects which failed are returned as
+// text, along with an error.
+func (gc *editRunner) applyBulk(svc typelessIface, txt string, selected []interface{}) (string, error) {
+	byKey := map[string]interface{}{}
+	for _, obj := range selected {
+		byKey[svc.Key(obj)] = obj
+	}
+
+	items := []interface{}{}
+	if err := codec.DecodeFromString(gc.cfg.codec, txt, &items); err != nil {
+		return "", fmt.Errorf("expected a list of %s objects: %v", svc.Type().Name, err)
+	}
+
+	changed := []interface{}{}
+	for _, item := range items {
+		itemTxt, err := codec.EncodeToString(gc.cfg.codec, item)
+		if err != nil {
+			return "", err
+		}
+		dest, err := svc.ObjFromString(itemTxt, gc.cfg.codec)
+		if err != nil {
+			return "", err
+		}
+
+		orig, ok := byKey[svc.Key(dest)]
+		if !ok {
+			return "", fmt.Errorf("%s %s is not one of the selected objects", svc.Type().Name, svc.Key(dest))
+		}
+		if err := gc.cfg.checkZoneScope(svc.Type(), svc.Key(dest), dest, gc.cfg.force); err != nil {
+			return "", err
+		}
+
+		same, err := sameObj(orig, dest)
+		if err != nil {
+			return "", err
+		}
+		if !same {
+			changed = append(changed, dest)
+		}
+	}
+
+	if len(changed) == 0 {
+		console.Info().Println("no objects were changed")
+		return "", nil
+	}
+
+	if ok, err := confirmBulk(svc, "modified", changed, gc.cfg.yes); err != nil {
+		return "", err
+	} else if !ok {
+		return "", errEditCanceled
+	}
+
+	modified := []interface{}{}
+	failed := []interface{}{}
+	for _, dest := range changed {
+		obj, err := svc.Modify(dest)
+		if err != nil {
+			console.Error().Printf(
+				"could not modify %s %s: %s\n",
+				svc.Type().Name,
+				svc.Key(dest),
+				gc.cfg.prettyErr(err),
+			)
+			failed = append(failed, dest)
+			continue
+		}
+		modified = append(modified, obj)
+	}
+
+	if len(modified) > 0 {
+		gc.cfg.PrintResult(modified)
+	}
+
+	if len(failed) > 0 {
+		next, err := codec.EncodeToString(gc.cfg.codec, failed)
+		if err != nil {
+			return "", err
+		}
+		return next, fmt.Errorf("%d of %d modifications failed", len(failed), len(changed))
+	}
+
+	return "", nil
+}
+
+// merge performs a three-way merge of the changes made to base in mine onto
+// theirs. It returns the merged text and the paths of any conflicting fields,
+// which are surrounded by conflict markers in the text.
+func (gc *editRunner) merge(base, mine, theirs interface{}) (string, []string, error) {
+	generic := make([]interface{}, 3)
+	for i, obj := range []interface{}{base, mine, theirs} {
+		g, err := toGeneric(obj)
+		if err != nil {
+			return "", nil, err
+		}
+		generic[i] = g
+	}
+
+	gm, gt, conflicts := mergeValues("", generic[0], generic[1], generic[2])
+
+	mstr, err := codec.EncodeToString(gc.cfg.codec, gm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(conflicts) == 0 {
+		return mstr, nil, nil
+	}
+
+	tstr, err := codec.EncodeToString(gc.cfg.codec, gt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return markConflicts(mstr, tstr), conflicts, nil
 }
 
 func (gc *editRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
@@ -67,15 +287,33 @@ func (gc *editRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 		return err
 	}
 
-	svc, err := gc.cfg.UntypedSvc(&args)
+	svc, err := gc.cfg.UntypedKeyedSvc(&args)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
 
+	if len(gc.cfg.selectors.Strings) > 0 {
+		return gc.runSelector(cmd, svc, args)
+	}
+
 	if cerr := updateKeyed(cmd, &args, gc.cfg); cerr != command.NoError() {
 		return cerr
 	}
 
+	if gc.cfg.key, err = gc.cfg.resolveKey(svc, gc.cfg.key); err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	if gc.cfg.zoneKey != "" && !gc.cfg.force {
+		obj, err := svc.Get(gc.cfg.key)
+		if err != nil {
+			return gc.cfg.PrettyCmdErr(cmd, err)
+		}
+		if err := gc.cfg.checkZoneScope(svc.Type(), gc.cfg.key, obj, gc.cfg.force); err != nil {
+			return cmd.Error(err)
+		}
+	}
+
 	err = gc.run(svc)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
@@ -84,6 +322,13 @@ func (gc *editRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 	return command.NoError()
 }
 
+const editConflictDesc = `{{bold "Concurrent Modification"}}
+
+If the object is modified by someone else while it is being edited, your
+changes are merged onto the new version. If the changes conflict, the editor
+is re-opened with conflict markers around the conflicting fields. Your edited
+version is always saved to a temporary file first, so that it is not lost.`
+
 func cmdEdit(cfg globalConfigT) *command.Cmd {
 	runner := &editRunner{&editCfg{}}
 	runner.cfg.globalConfigT = &cfg
@@ -92,7 +337,7 @@ func cmdEdit(cfg globalConfigT) *command.Cmd {
 		Name:        "edit",
 		Summary:     "edit an object from Turbine Labs API",
 		Usage:       "[OPTIONS] <object type> [object key]",
-		Description: "object type is one of: " + objTypeNames() + "\n\n" + editingEditorHelp(),
+		Description: "object type is one of: " + objTypeNames() + "\n\n" + editingEditorHelp() + "\n\n" + editConflictDesc + "\n\n" + selectorDesc + "\n\n" + bulkSelectorDesc,
 		Runner:      runner,
 	}
 
@@ -103,5 +348,16 @@ func cmdEdit(cfg globalConfigT) *command.Cmd {
 		"[deprecated] key of the object to retrieve, if not provided will read input from stdin",
 	)
 
+	cmd.Flags.BoolVar(&runner.cfg.force, "force", false, zoneForceDesc)
+
+	runner.cfg.selectors = tbnflag.NewStrings()
+	cmd.Flags.Var(
+		&runner.cfg.selectors,
+		bulkSelectorFlag,
+		"edit every object matching this field_name=field_value term; may be repeated",
+	)
+
+	cmd.Flags.BoolVar(&runner.cfg.yes, "yes", false, bulkYesDesc)
+
 	return cmd
 }
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	conflictStart = "<<<<<<< yours"
+	conflictSep   = "======="
+	conflictEnd   = ">>>>>>> current"
+)
+
+// mergeValues performs a three-way merge of values decoded from JSON. Changes
+// made in either mine or theirs, relative to base, are combined. Maps are
+// merged key by key; other values, including slices, are merged as a whole.
+// Where both mine and theirs change a value differently, the path is reported
+// as a conflict, and the two returned values hold mine and theirs,
+// respectively. Elsewhere the returned values are identical.
+func mergeValues(path string, base, mine, theirs interface{}) (interface{}, interface{}, []string) {
+	switch {
+	case reflect.DeepEqual(mine, theirs), reflect.DeepEqual(base, theirs):
+		return mine, mine, nil
+	case reflect.DeepEqual(base, mine):
+		return theirs, theirs, nil
+	}
+
+	m, mok := mine.(map[string]interface{})
+	t, tok := theirs.(map[string]interface{})
+	if !mok || !tok {
+		return mine, theirs, []string{path}
+	}
+	b, _ := base.(map[string]interface{})
+
+	keySet := map[string]bool{}
+	for k := range m {
+		keySet[k] = true
+	}
+	for k := range t {
+		keySet[k] = true
+	}
+	keys := []string{}
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resultMine := map[string]interface{}{}
+	resultTheirs := map[string]interface{}{}
+	conflicts := []string{}
+	for _, k := range keys {
+		rm, rt, cs := mergeValues(joinPath(path, k), b[k], m[k], t[k])
+		if rm != nil {
+			resultMine[k] = rm
+		}
+		if rt != nil {
+			resultTheirs[k] = rt
+		}
+		conflicts = append(conflicts, cs...)
+	}
+
+	return resultMine, resultTheirs, conflicts
+}
+
+// diffLines returns the longest common subsequence of lines in a and b, as
+// pairs of indices.
+func diffLines(a, b []string) [][2]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	pairs := [][2]int{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return pairs
+}
+
+// markConflicts combines two texts which differ only where a merge
+// conflicted, surrounding each differing region with conflict markers.
+func markConflicts(mine, theirs string) string {
+	a := strings.Split(strings.TrimRight(mine, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(theirs, "\n"), "\n")
+
+	result := []string{}
+	i, j := 0, 0
+	emit := func(ni, nj int) {
+		if ni > i || nj > j {
+			result = append(result, conflictStart)
+			result = append(result, a[i:ni]...)
+			result = append(result, conflictSep)
+			result = append(result, b[j:nj]...)
+			result = append(result, conflictEnd)
+		}
+	}
+
+	for _, p := range diffLines(a, b) {
+		emit(p[0], p[1])
+		result = append(result, a[p[0]])
+		i, j = p[0]+1, p[1]+1
+	}
+	emit(len(a), len(b))
+
+	return strings.Join(result, "\n") + "\n"
+}
+
+// hasConflictMarkers returns true if any line of txt is a conflict marker.
+func hasConflictMarkers(txt string) bool {
+	for _, line := range strings.Split(txt, "\n") {
+		switch strings.TrimSpace(line) {
+		case conflictStart, conflictSep, conflictEnd:
+			return true
+		}
+	}
+	return false
+}
+
+// commentBlock formats the lines of msg as comments.
+func commentBlock(msg string) string {
+	lines := strings.Split(strings.TrimRight(msg, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight("# "+l, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// stripCommentBlock removes the leading run of comment lines from txt, even
+// if they no longer match what commentBlock produced. Comments after the
+// first non-comment line are left alone.
+func stripCommentBlock(txt string) string {
+	for txt != "" {
+		line := txt
+		rest := ""
+		if i := strings.Index(txt, "\n"); i >= 0 {
+			line, rest = txt[:i], txt[i+1:]
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			break
+		}
+		txt = rest
+	}
+	return txt
+}
+
+// preserveEdit saves edited text to a temporary file, so that it is not
+// lost if it cannot be applied, and returns the file's name.
+func preserveEdit(txt string) (string, error) {
+	f, err := ioutil.TempFile("", "tbnctl-edit-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(txt); err != nil {
+		return "", fmt.Errorf("could not save edited text to %s: %v", f.Name(), err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestMergeValuesClean(t *testing.T) {
+	base := map[string]interface{}{"name": "c", "tls": false, "checksum": "1", "gone": 1.0}
+	mine := map[string]interface{}{"name": "c", "tls": true, "checksum": "1"}
+	theirs := map[string]interface{}{"name": "d", "tls": false, "checksum": "2", "gone": 1.0}
+
+	m, th, conflicts := mergeValues("", base, mine, theirs)
+	assert.Equal(t, len(conflicts), 0)
+	assert.DeepEqual(t, m, th)
+	assert.DeepEqual(t, m, map[string]interface{}{"name": "d", "tls": true, "checksum": "2"})
+}
+
+func TestMergeValuesConflict(t *testing.T) {
+	base := map[string]interface{}{"a": map[string]interface{}{"x": 1.0, "y": 1.0}}
+	mine := map[string]interface{}{"a": map[string]interface{}{"x": 2.0, "y": 1.0}}
+	theirs := map[string]interface{}{"a": map[string]interface{}{"x": 3.0, "y": 4.0}}
+
+	m, th, conflicts := mergeValues("", base, mine, theirs)
+	assert.DeepEqual(t, conflicts, []string{"a.x"})
+	assert.DeepEqual(t, m, map[string]interface{}{"a": map[string]interface{}{"x": 2.0, "y": 4.0}})
+	assert.DeepEqual(t, th, map[string]interface{}{"a": map[string]interface{}{"x": 3.0, "y": 4.0}})
+}
+
+func TestMarkConflicts(t *testing.T) {
+	mine := "{\n  \"a\": 2,\n  \"b\": 1\n}\n"
+	theirs := "{\n  \"a\": 3,\n  \"b\": 1\n}\n"
+
+	marked := markConflicts(mine, theirs)
+	assert.Equal(
+		t,
+		marked,
+		"{\n"+conflictStart+"\n  \"a\": 2,\n"+conflictSep+"\n  \"a\": 3,\n"+conflictEnd+"\n  \"b\": 1\n}\n",
+	)
+	assert.True(t, hasConflictMarkers(marked))
+	assert.False(t, hasConflictMarkers(mine))
+	assert.Equal(t, markConflicts(mine, mine), mine)
+}
+
+func TestCommentBlock(t *testing.T) {
+	txt := commentBlock("an error\n\nwith details") + "{}\n"
+	assert.Equal(t, txt, "# an error\n#\n# with details\n{}\n")
+	assert.Equal(t, stripCommentBlock(txt), "{}\n")
+	assert.Equal(t, stripCommentBlock("{}\n"), "{}\n")
+
+	body := "a: 1\n# not part of the block\nb: 2\n"
+	assert.Equal(t, stripCommentBlock(commentBlock("an error")+body), body)
+	assert.Equal(t, stripCommentBlock(body), body)
+
+	edited := "# an error, edited\n  # reindented\n#\n" + body
+	assert.Equal(t, stripCommentBlock(edited), body)
+	assert.Equal(t, stripCommentBlock("# only comments"), "")
+}
+
+func TestPreserveEdit(t *testing.T) {
+	path, err := preserveEdit("edited")
+	assert.Nil(t, err)
+	defer os.Remove(path)
+
+	b, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "edited")
+}
+
+func TestEditApplyMergesConcurrentChanges(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	mc := service.NewMockCluster(ctrl)
+
+	base := api.Cluster{ClusterKey: "ck", Name: "c", Checksum: api.Checksum{Checksum: "1"}}
+	mine := base
+	mine.RequireTLS = true
+	current := base
+	current.Name = "renamed"
+	current.Checksum = api.Checksum{Checksum: "2"}
+	merged := current
+	merged.RequireTLS = true
+
+	gomock.InOrder(
+		mc.EXPECT().Modify(mine).Return(api.Cluster{}, errors.New("conflict")),
+		mc.EXPECT().Get(api.ClusterKey("ck")).Return(current, nil),
+		mc.EXPECT().Modify(merged).Return(merged, nil),
+	)
+
+	cdc := codec.NewJson()
+	txt, err := codec.EncodeToString(cdc, mine)
+	assert.Nil(t, err)
+
+	r := &editRunner{&editCfg{globalConfigT: &globalConfigT{codec: cdc}}}
+	var b interface{} = base
+	next, err := r.apply(clusterAdapter{mc}, txt, &b)
+	assert.Nil(t, err)
+	assert.Equal(t, next, "")
+	assert.DeepEqual(t, b, current)
+}
+
+func TestEditApplyRejectsConflictMarkers(t *testing.T) {
+	r := &editRunner{&editCfg{globalConfigT: &globalConfigT{}}}
+	var b interface{}
+	_, err := r.apply(nil, "{\n"+conflictStart+"\n"+conflictSep+"\n"+conflictEnd+"\n}\n", &b)
+	assert.ErrorContains(t, err, "conflict markers")
+}
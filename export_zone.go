@@ -18,11 +18,19 @@ package main
 
 import (
 	"github.com/turbinelabs/cli/command"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
 )
 
 const exportZoneDesc = `Export a Zone from the Turbine Labs API. Object keys are
 replaced with human-readable names, and referential integrity is maintained. The
-output of export-zone is suitable for input into import-zone.`
+output of export-zone is suitable for input into import-zone.
+
+A subset of the Zone may be exported by selecting Routes with --domains and
+--routes-prefix, and Clusters with --clusters. The SharedRules and Clusters
+referenced by the selected Routes, and the Domains they belong to, are included
+automatically, so that the result is consistent. Proxies and Listeners are not
+included in a partial export. A partial export can be applied with apply, but
+not with --prune, which would delete the rest of the Zone.`
 
 func cmdExportZone(globalConfig globalConfigT) *command.Cmd {
 	cmd := &command.Cmd{
@@ -32,12 +40,38 @@ func cmdExportZone(globalConfig globalConfigT) *command.Cmd {
 		Description: exportZoneDesc,
 	}
 
-	cmd.Runner = &exportZoneRunner{globalConfig}
+	r := &exportZoneRunner{
+		cfg:      globalConfig,
+		domains:  tbnflag.NewStrings(),
+		clusters: tbnflag.NewStrings(),
+	}
+
+	cmd.Flags.Var(
+		&r.domains,
+		"domains",
+		"Comma-delimited addresses (name:port) of Domains whose Routes should be exported.",
+	)
+	cmd.Flags.Var(
+		&r.clusters,
+		"clusters",
+		"Comma-delimited names of Clusters to export.",
+	)
+	cmd.Flags.StringVar(
+		&r.routesPrefix,
+		"routes-prefix",
+		"",
+		"If set, only Routes with paths beginning with this prefix are exported.",
+	)
+
+	cmd.Runner = r
 	return cmd
 }
 
 type exportZoneRunner struct {
-	cfg globalConfigT
+	cfg          globalConfigT
+	domains      tbnflag.Strings
+	clusters     tbnflag.Strings
+	routesPrefix string
 }
 
 func (r *exportZoneRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
@@ -58,6 +92,16 @@ func (r *exportZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
 		return r.cfg.PrettyCmdErr(cmd, err)
 	}
 
+	f := zoneFilter{
+		domains:      r.domains.Strings,
+		clusters:     r.clusters.Strings,
+		routesPrefix: r.routesPrefix,
+	}
+	zo, err = f.apply(zo)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
 	r.cfg.PrintResult(zo)
 
 	return command.NoError()
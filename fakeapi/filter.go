@@ -0,0 +1,116 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+const prefixSuffix = "Prefix"
+
+// matchesAny returns true if obj matches any of the filters, or if there are
+// no filters.
+func matchesAny(filters []interface{}, obj interface{}) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if matches(f, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if obj matches the filter. Each non-zero field of the
+// filter must match the field of obj with the same name. Slice fields match if
+// every element of the filter's slice is present in the object's slice, and
+// pointer fields match if they point to equal values. A filter field whose
+// name ends in "Prefix", such as PathPrefix, matches if the corresponding
+// object field (Path) begins with it. Non-zero filter fields with no
+// corresponding object field never match.
+func matches(filter, obj interface{}) bool {
+	fv := reflect.ValueOf(filter)
+	ov := reflect.ValueOf(obj)
+
+	for i := 0; i < fv.NumField(); i++ {
+		name := fv.Type().Field(i).Name
+		want := fv.Field(i)
+		if isZero(want) {
+			continue
+		}
+
+		if strings.HasSuffix(name, prefixSuffix) {
+			got := ov.FieldByName(strings.TrimSuffix(name, prefixSuffix))
+			if !got.IsValid() || got.Kind() != reflect.String ||
+				!strings.HasPrefix(got.String(), want.String()) {
+				return false
+			}
+			continue
+		}
+
+		got := ov.FieldByName(name)
+		if !got.IsValid() || !fieldMatches(want, got) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fieldMatches(want, got reflect.Value) bool {
+	switch want.Kind() {
+	case reflect.Slice:
+		if got.Kind() != reflect.Slice {
+			return false
+		}
+		for i := 0; i < want.Len(); i++ {
+			found := false
+			for j := 0; j < got.Len(); j++ {
+				if reflect.DeepEqual(want.Index(i).Interface(), got.Index(j).Interface()) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Ptr:
+		if got.Kind() != reflect.Ptr || got.IsNil() {
+			return false
+		}
+		return reflect.DeepEqual(want.Elem().Interface(), got.Elem().Interface())
+
+	default:
+		return reflect.DeepEqual(want.Interface(), got.Interface())
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+	}
+}
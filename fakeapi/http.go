@@ -0,0 +1,180 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	apierror "github.com/turbinelabs/api/http/error"
+)
+
+const (
+	// apiPrefix is the path prefix of all API resources.
+	apiPrefix = "/v1.0/"
+
+	// adminPrefix is the path prefix, within apiPrefix, of admin resources.
+	adminPrefix = "admin/"
+
+	// filtersParam is the query parameter holding a JSON-encoded array of
+	// service filters for Index requests.
+	filtersParam = "filters"
+
+	// checksumParam is the query parameter holding the checksum for Delete
+	// and Cluster instance requests.
+	checksumParam = "checksum"
+
+	instancesPath = "instances"
+)
+
+var adminTables = map[string]bool{"user": true, "access_token": true}
+
+// envelope wraps every response body.
+type envelope struct {
+	Result interface{}     `json:"result,omitempty"`
+	Error  *apierror.Error `json:"error,omitempty"`
+}
+
+// Handler returns an http.Handler serving the Store with the resource paths
+// and JSON envelope of the Turbine Labs API:
+//
+//	GET    /v1.0/<type>?filters=<json>    index
+//	POST   /v1.0/<type>                   create
+//	GET    /v1.0/<type>/<key>             get
+//	PUT    /v1.0/<type>/<key>             modify
+//	DELETE /v1.0/<type>/<key>?checksum=   delete
+//
+// where <type> is one of zone, cluster, domain, listener, proxy, shared_rules
+// or route, or admin/user or admin/access_token. Cluster instances are added
+// with POST /v1.0/cluster/<key>/instances?checksum=, and removed with
+// DELETE /v1.0/cluster/<key>/instances/<host>:<port>?checksum=. API keys are
+// accepted but not checked.
+func (s *Store) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Store) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	result, err := s.route(r)
+	if err != nil {
+		writeEnvelope(w, err.status, envelope{Error: err.wireError()})
+		return
+	}
+	writeEnvelope(w, http.StatusOK, envelope{Result: result})
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, e envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (s *Store) route(r *http.Request) (interface{}, *storeError) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		return nil, notFound("path", r.URL.Path)
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	admin := strings.HasPrefix(path, adminPrefix)
+	path = strings.TrimPrefix(path, adminPrefix)
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	t, ok := s.tables[parts[0]]
+	if !ok || admin != adminTables[t.name] {
+		return nil, notFound("path", r.URL.Path)
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		return s.serveIndex(t, r)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		obj, err := decodeObject(t, r)
+		if err != nil {
+			return nil, err
+		}
+		return s.create(t.name, obj)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		return s.get(t.name, parts[1])
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		obj, err := decodeObject(t, r)
+		if err != nil {
+			return nil, err
+		}
+		v := reflect.New(t.objType).Elem()
+		v.Set(reflect.ValueOf(obj))
+		v.FieldByName(t.keyField).SetString(parts[1])
+		return s.modify(t.name, v.Interface())
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		return nil, s.delete(t.name, parts[1], r.URL.Query().Get(checksumParam))
+	case len(parts) >= 3 && t.name == "cluster" && parts[2] == instancesPath:
+		return s.serveInstances(parts[1], parts[3:], r)
+	}
+
+	return nil, methodNotAllowed(r)
+}
+
+func (s *Store) serveIndex(t *table, r *http.Request) (interface{}, *storeError) {
+	var filters []interface{}
+	if q := r.URL.Query().Get(filtersParam); q != "" {
+		fv := reflect.New(reflect.SliceOf(t.filterType))
+		if err := json.Unmarshal([]byte(q), fv.Interface()); err != nil {
+			return nil, badRequest("could not decode filters: %s", err)
+		}
+		for i := 0; i < fv.Elem().Len(); i++ {
+			filters = append(filters, fv.Elem().Index(i).Interface())
+		}
+	}
+	return s.index(t.name, filters), nil
+}
+
+func decodeObject(t *table, r *http.Request) (interface{}, *storeError) {
+	v := reflect.New(t.objType)
+	if err := json.NewDecoder(r.Body).Decode(v.Interface()); err != nil {
+		return nil, badRequest("could not decode %s: %s", t.name, err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+func (s *Store) serveInstances(key string, rest []string, r *http.Request) (interface{}, *storeError) {
+	ck := api.ClusterKey(key)
+	cs := api.Checksum{Checksum: r.URL.Query().Get(checksumParam)}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var i api.Instance
+		if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+			return nil, badRequest("could not decode instance: %s", err)
+		}
+		return s.addInstance(ck, cs, i)
+
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		idx := strings.LastIndex(rest[0], ":")
+		if idx < 0 {
+			return nil, badRequest("malformed instance %q: expected <host>:<port>", rest[0])
+		}
+		port, err := strconv.Atoi(rest[0][idx+1:])
+		if err != nil {
+			return nil, badRequest("malformed instance %q: bad port", rest[0])
+		}
+		return s.removeInstance(ck, cs, api.Instance{Host: rest[0][:idx], Port: port})
+	}
+
+	return nil, methodNotAllowed(r)
+}
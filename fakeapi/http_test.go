@@ -0,0 +1,158 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/api"
+	apiclient "github.com/turbinelabs/api/client"
+	apihttp "github.com/turbinelabs/api/http"
+	apierror "github.com/turbinelabs/api/http/error"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+func do(t *testing.T, h http.Handler, method, path, body string, result interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	e := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *apierror.Error `json:"error"`
+	}{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &e))
+	if result != nil && e.Result != nil {
+		assert.Nil(t, json.Unmarshal(e.Result, result))
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	h := New().Handler()
+
+	var z api.Zone
+	assert.Equal(t, do(t, h, "POST", "/v1.0/zone", `{"name": "z"}`, &z), http.StatusOK)
+	assert.Equal(t, z.Name, "z")
+
+	var c api.Cluster
+	body := `{"name": "c", "zone_key": "` + string(z.ZoneKey) + `"}`
+	assert.Equal(t, do(t, h, "POST", "/v1.0/cluster", body, &c), http.StatusOK)
+
+	var cs api.Clusters
+	filters := url.QueryEscape(`[{"name": "c"}]`)
+	assert.Equal(t, do(t, h, "GET", "/v1.0/cluster?filters="+filters, "", &cs), http.StatusOK)
+	assert.DeepEqual(t, cs, api.Clusters{c})
+
+	filters = url.QueryEscape(`[{"name": "nope"}]`)
+	assert.Equal(t, do(t, h, "GET", "/v1.0/cluster?filters="+filters, "", &cs), http.StatusOK)
+	assert.Equal(t, len(cs), 0)
+
+	var modified api.Cluster
+	body = `{"name": "c", "zone_key": "` + string(z.ZoneKey) + `", "checksum": "bad"}`
+	path := "/v1.0/cluster/" + string(c.ClusterKey)
+	assert.Equal(t, do(t, h, "PUT", path, body, &modified), http.StatusConflict)
+
+	body = `{"name": "c", "zone_key": "` + string(z.ZoneKey) + `", "require_tls": true, ` +
+		`"checksum": "` + c.Checksum.Checksum + `"}`
+	assert.Equal(t, do(t, h, "PUT", path, body, &modified), http.StatusOK)
+	assert.True(t, modified.RequireTLS)
+
+	instances := path + "/instances?checksum=" + modified.Checksum.Checksum
+	assert.Equal(t, do(t, h, "POST", instances, `{"host": "h", "port": 80}`, &c), http.StatusOK)
+	assert.DeepEqual(t, c.Instances, api.Instances{{Host: "h", Port: 80}})
+
+	instance := path + "/instances/h:80?checksum=" + c.Checksum.Checksum
+	assert.Equal(t, do(t, h, "DELETE", instance, "", &c), http.StatusOK)
+	assert.Equal(t, len(c.Instances), 0)
+
+	assert.Equal(t, do(t, h, "DELETE", path+"?checksum="+c.Checksum.Checksum, "", nil), http.StatusOK)
+	assert.Equal(t, do(t, h, "GET", path, "", nil), http.StatusNotFound)
+
+	assert.Equal(t, do(t, h, "GET", "/v1.0/user", "", nil), http.StatusNotFound)
+	assert.Equal(t, do(t, h, "GET", "/v1.0/admin/user", "", nil), http.StatusOK)
+}
+
+func assertAPIErrorCode(t *testing.T, err error, code apierror.ErrorCode) {
+	e, ok := err.(*apierror.Error)
+	if !ok {
+		t.Errorf("got %#v, want *apierror.Error", err)
+		return
+	}
+	assert.Equal(t, e.Code, code)
+}
+
+func TestHandlerServesAPIClient(t *testing.T) {
+	server := httptest.NewServer(New().Handler())
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	endpoint, err := apihttp.NewEndpoint(apihttp.HTTP, u.Host)
+	assert.Nil(t, err)
+	svc, err := apiclient.NewAll(endpoint, "key", apiclient.App("fakeapi"))
+	assert.Nil(t, err)
+
+	z, err := svc.Zone().Create(api.Zone{Name: "z"})
+	assert.Nil(t, err)
+	c, err := svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: "c"})
+	assert.Nil(t, err)
+	_, err = svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: "d"})
+	assert.Nil(t, err)
+
+	cs, err := svc.Cluster().Index(service.ClusterFilter{Name: "c"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, cs, api.Clusters{c})
+
+	cs, err = svc.Cluster().Index(service.ClusterFilter{ZoneKey: z.ZoneKey})
+	assert.Nil(t, err)
+	assert.Equal(t, len(cs), 2)
+
+	bad := c
+	bad.Checksum = api.Checksum{Checksum: "bad"}
+	_, err = svc.Cluster().Modify(bad)
+	assertAPIErrorCode(t, err, conflictCode)
+
+	c.RequireTLS = true
+	c, err = svc.Cluster().Modify(c)
+	assert.Nil(t, err)
+	assert.True(t, c.RequireTLS)
+
+	c, err = svc.Cluster().AddInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, c.Instances, api.Instances{{Host: "h", Port: 80}})
+
+	_, err = svc.Cluster().AddInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assertAPIErrorCode(t, err, conflictCode)
+
+	c, err = svc.Cluster().RemoveInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assert.Nil(t, err)
+	assert.Equal(t, len(c.Instances), 0)
+
+	assert.Nil(t, svc.Cluster().Delete(c.ClusterKey, c.Checksum))
+	_, err = svc.Cluster().Get(c.ClusterKey)
+	assertAPIErrorCode(t, err, notFoundCode)
+
+	_, err = svc.Cluster().Create(api.Cluster{ZoneKey: "nope", Name: "c"})
+	assertAPIErrorCode(t, err, badRequestCode)
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeapi
+
+import (
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+)
+
+// All returns an implementation of service.All backed by the Store.
+func (s *Store) All() service.All {
+	return allService{s}
+}
+
+// Admin returns an implementation of service.Admin backed by the Store.
+func (s *Store) Admin() service.Admin {
+	return adminService{s}
+}
+
+type allService struct{ s *Store }
+
+func (a allService) Zone() service.Zone               { return zoneService{a.s} }
+func (a allService) Cluster() service.Cluster         { return clusterService{a.s} }
+func (a allService) Domain() service.Domain           { return domainService{a.s} }
+func (a allService) Listener() service.Listener       { return listenerService{a.s} }
+func (a allService) Proxy() service.Proxy             { return proxyService{a.s} }
+func (a allService) SharedRules() service.SharedRules { return sharedRulesService{a.s} }
+func (a allService) Route() service.Route             { return routeService{a.s} }
+
+type adminService struct{ s *Store }
+
+func (a adminService) User() service.User               { return userService{a.s} }
+func (a adminService) AccessToken() service.AccessToken { return accessTokenService{a.s} }
+
+type zoneService struct{ s *Store }
+
+func (svc zoneService) Index(filters ...service.ZoneFilter) (api.Zones, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Zones{}
+	for _, obj := range svc.s.index("zone", fs) {
+		result = append(result, obj.(api.Zone))
+	}
+	return result, nil
+}
+
+func (svc zoneService) Get(key api.ZoneKey) (api.Zone, error) {
+	obj, err := svc.s.get("zone", string(key))
+	if err != nil {
+		return api.Zone{}, err.apiError()
+	}
+	return obj.(api.Zone), nil
+}
+
+func (svc zoneService) Create(o api.Zone) (api.Zone, error) {
+	obj, err := svc.s.create("zone", o)
+	if err != nil {
+		return api.Zone{}, err.apiError()
+	}
+	return obj.(api.Zone), nil
+}
+
+func (svc zoneService) Modify(o api.Zone) (api.Zone, error) {
+	obj, err := svc.s.modify("zone", o)
+	if err != nil {
+		return api.Zone{}, err.apiError()
+	}
+	return obj.(api.Zone), nil
+}
+
+func (svc zoneService) Delete(key api.ZoneKey, cs api.Checksum) error {
+	return svc.s.delete("zone", string(key), cs.Checksum).apiError()
+}
+
+type clusterService struct{ s *Store }
+
+func (svc clusterService) Index(filters ...service.ClusterFilter) (api.Clusters, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Clusters{}
+	for _, obj := range svc.s.index("cluster", fs) {
+		result = append(result, obj.(api.Cluster))
+	}
+	return result, nil
+}
+
+func (svc clusterService) Get(key api.ClusterKey) (api.Cluster, error) {
+	obj, err := svc.s.get("cluster", string(key))
+	if err != nil {
+		return api.Cluster{}, err.apiError()
+	}
+	return obj.(api.Cluster), nil
+}
+
+func (svc clusterService) Create(o api.Cluster) (api.Cluster, error) {
+	obj, err := svc.s.create("cluster", o)
+	if err != nil {
+		return api.Cluster{}, err.apiError()
+	}
+	return obj.(api.Cluster), nil
+}
+
+func (svc clusterService) Modify(o api.Cluster) (api.Cluster, error) {
+	obj, err := svc.s.modify("cluster", o)
+	if err != nil {
+		return api.Cluster{}, err.apiError()
+	}
+	return obj.(api.Cluster), nil
+}
+
+func (svc clusterService) Delete(key api.ClusterKey, cs api.Checksum) error {
+	return svc.s.delete("cluster", string(key), cs.Checksum).apiError()
+}
+
+func (svc clusterService) AddInstance(
+	key api.ClusterKey,
+	cs api.Checksum,
+	i api.Instance,
+) (api.Cluster, error) {
+	c, err := svc.s.addInstance(key, cs, i)
+	return c, err.apiError()
+}
+
+func (svc clusterService) RemoveInstance(
+	key api.ClusterKey,
+	cs api.Checksum,
+	i api.Instance,
+) (api.Cluster, error) {
+	c, err := svc.s.removeInstance(key, cs, i)
+	return c, err.apiError()
+}
+
+type domainService struct{ s *Store }
+
+func (svc domainService) Index(filters ...service.DomainFilter) (api.Domains, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Domains{}
+	for _, obj := range svc.s.index("domain", fs) {
+		result = append(result, obj.(api.Domain))
+	}
+	return result, nil
+}
+
+func (svc domainService) Get(key api.DomainKey) (api.Domain, error) {
+	obj, err := svc.s.get("domain", string(key))
+	if err != nil {
+		return api.Domain{}, err.apiError()
+	}
+	return obj.(api.Domain), nil
+}
+
+func (svc domainService) Create(o api.Domain) (api.Domain, error) {
+	obj, err := svc.s.create("domain", o)
+	if err != nil {
+		return api.Domain{}, err.apiError()
+	}
+	return obj.(api.Domain), nil
+}
+
+func (svc domainService) Modify(o api.Domain) (api.Domain, error) {
+	obj, err := svc.s.modify("domain", o)
+	if err != nil {
+		return api.Domain{}, err.apiError()
+	}
+	return obj.(api.Domain), nil
+}
+
+func (svc domainService) Delete(key api.DomainKey, cs api.Checksum) error {
+	return svc.s.delete("domain", string(key), cs.Checksum).apiError()
+}
+
+type listenerService struct{ s *Store }
+
+func (svc listenerService) Index(filters ...service.ListenerFilter) (api.Listeners, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Listeners{}
+	for _, obj := range svc.s.index("listener", fs) {
+		result = append(result, obj.(api.Listener))
+	}
+	return result, nil
+}
+
+func (svc listenerService) Get(key api.ListenerKey) (api.Listener, error) {
+	obj, err := svc.s.get("listener", string(key))
+	if err != nil {
+		return api.Listener{}, err.apiError()
+	}
+	return obj.(api.Listener), nil
+}
+
+func (svc listenerService) Create(o api.Listener) (api.Listener, error) {
+	obj, err := svc.s.create("listener", o)
+	if err != nil {
+		return api.Listener{}, err.apiError()
+	}
+	return obj.(api.Listener), nil
+}
+
+func (svc listenerService) Modify(o api.Listener) (api.Listener, error) {
+	obj, err := svc.s.modify("listener", o)
+	if err != nil {
+		return api.Listener{}, err.apiError()
+	}
+	return obj.(api.Listener), nil
+}
+
+func (svc listenerService) Delete(key api.ListenerKey, cs api.Checksum) error {
+	return svc.s.delete("listener", string(key), cs.Checksum).apiError()
+}
+
+type proxyService struct{ s *Store }
+
+func (svc proxyService) Index(filters ...service.ProxyFilter) (api.Proxies, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Proxies{}
+	for _, obj := range svc.s.index("proxy", fs) {
+		result = append(result, obj.(api.Proxy))
+	}
+	return result, nil
+}
+
+func (svc proxyService) Get(key api.ProxyKey) (api.Proxy, error) {
+	obj, err := svc.s.get("proxy", string(key))
+	if err != nil {
+		return api.Proxy{}, err.apiError()
+	}
+	return obj.(api.Proxy), nil
+}
+
+func (svc proxyService) Create(o api.Proxy) (api.Proxy, error) {
+	obj, err := svc.s.create("proxy", o)
+	if err != nil {
+		return api.Proxy{}, err.apiError()
+	}
+	return obj.(api.Proxy), nil
+}
+
+func (svc proxyService) Modify(o api.Proxy) (api.Proxy, error) {
+	obj, err := svc.s.modify("proxy", o)
+	if err != nil {
+		return api.Proxy{}, err.apiError()
+	}
+	return obj.(api.Proxy), nil
+}
+
+func (svc proxyService) Delete(key api.ProxyKey, cs api.Checksum) error {
+	return svc.s.delete("proxy", string(key), cs.Checksum).apiError()
+}
+
+type sharedRulesService struct{ s *Store }
+
+func (svc sharedRulesService) Index(filters ...service.SharedRulesFilter) (api.SharedRulesSlice, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.SharedRulesSlice{}
+	for _, obj := range svc.s.index("shared_rules", fs) {
+		result = append(result, obj.(api.SharedRules))
+	}
+	return result, nil
+}
+
+func (svc sharedRulesService) Get(key api.SharedRulesKey) (api.SharedRules, error) {
+	obj, err := svc.s.get("shared_rules", string(key))
+	if err != nil {
+		return api.SharedRules{}, err.apiError()
+	}
+	return obj.(api.SharedRules), nil
+}
+
+func (svc sharedRulesService) Create(o api.SharedRules) (api.SharedRules, error) {
+	obj, err := svc.s.create("shared_rules", o)
+	if err != nil {
+		return api.SharedRules{}, err.apiError()
+	}
+	return obj.(api.SharedRules), nil
+}
+
+func (svc sharedRulesService) Modify(o api.SharedRules) (api.SharedRules, error) {
+	obj, err := svc.s.modify("shared_rules", o)
+	if err != nil {
+		return api.SharedRules{}, err.apiError()
+	}
+	return obj.(api.SharedRules), nil
+}
+
+func (svc sharedRulesService) Delete(key api.SharedRulesKey, cs api.Checksum) error {
+	return svc.s.delete("shared_rules", string(key), cs.Checksum).apiError()
+}
+
+type routeService struct{ s *Store }
+
+func (svc routeService) Index(filters ...service.RouteFilter) (api.Routes, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := api.Routes{}
+	for _, obj := range svc.s.index("route", fs) {
+		result = append(result, obj.(api.Route))
+	}
+	return result, nil
+}
+
+func (svc routeService) Get(key api.RouteKey) (api.Route, error) {
+	obj, err := svc.s.get("route", string(key))
+	if err != nil {
+		return api.Route{}, err.apiError()
+	}
+	return obj.(api.Route), nil
+}
+
+func (svc routeService) Create(o api.Route) (api.Route, error) {
+	obj, err := svc.s.create("route", o)
+	if err != nil {
+		return api.Route{}, err.apiError()
+	}
+	return obj.(api.Route), nil
+}
+
+func (svc routeService) Modify(o api.Route) (api.Route, error) {
+	obj, err := svc.s.modify("route", o)
+	if err != nil {
+		return api.Route{}, err.apiError()
+	}
+	return obj.(api.Route), nil
+}
+
+func (svc routeService) Delete(key api.RouteKey, cs api.Checksum) error {
+	return svc.s.delete("route", string(key), cs.Checksum).apiError()
+}
+
+type userService struct{ s *Store }
+
+func (svc userService) Index(filters ...service.UserFilter) ([]api.User, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := []api.User{}
+	for _, obj := range svc.s.index("user", fs) {
+		result = append(result, obj.(api.User))
+	}
+	return result, nil
+}
+
+func (svc userService) Get(key api.UserKey) (api.User, error) {
+	obj, err := svc.s.get("user", string(key))
+	if err != nil {
+		return api.User{}, err.apiError()
+	}
+	return obj.(api.User), nil
+}
+
+func (svc userService) Create(o api.User) (api.User, error) {
+	obj, err := svc.s.create("user", o)
+	if err != nil {
+		return api.User{}, err.apiError()
+	}
+	return obj.(api.User), nil
+}
+
+func (svc userService) Modify(o api.User) (api.User, error) {
+	obj, err := svc.s.modify("user", o)
+	if err != nil {
+		return api.User{}, err.apiError()
+	}
+	return obj.(api.User), nil
+}
+
+func (svc userService) Delete(key api.UserKey, cs api.Checksum) error {
+	return svc.s.delete("user", string(key), cs.Checksum).apiError()
+}
+
+type accessTokenService struct{ s *Store }
+
+func (svc accessTokenService) Index(filters ...service.AccessTokenFilter) ([]api.AccessToken, error) {
+	fs := make([]interface{}, len(filters))
+	for i, f := range filters {
+		fs[i] = f
+	}
+	result := []api.AccessToken{}
+	for _, obj := range svc.s.index("access_token", fs) {
+		result = append(result, obj.(api.AccessToken))
+	}
+	return result, nil
+}
+
+func (svc accessTokenService) Get(key api.AccessTokenKey) (api.AccessToken, error) {
+	obj, err := svc.s.get("access_token", string(key))
+	if err != nil {
+		return api.AccessToken{}, err.apiError()
+	}
+	return obj.(api.AccessToken), nil
+}
+
+func (svc accessTokenService) Create(o api.AccessToken) (api.AccessToken, error) {
+	obj, err := svc.s.create("access_token", o)
+	if err != nil {
+		return api.AccessToken{}, err.apiError()
+	}
+	return obj.(api.AccessToken), nil
+}
+
+func (svc accessTokenService) Delete(key api.AccessTokenKey, cs api.Checksum) error {
+	return svc.s.delete("access_token", string(key), cs.Checksum).apiError()
+}
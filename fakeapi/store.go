@@ -0,0 +1,551 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakeapi provides an in-memory implementation of the Turbine Labs
+// API, for offline development and testing. A Store implements the
+// service.All and service.Admin interfaces directly, and can be served over
+// HTTP with Handler.
+//
+// The Store generates object keys and checksums, rejects modifications and
+// deletions whose checksum does not match the stored object, and applies
+// Index filters with the same semantics as the API: zero-valued filter fields
+// are ignored, and an object matching any filter is returned. Objects with a
+// ZoneKey must refer to an existing Zone, and a Zone may not be deleted while
+// objects refer to it. Other references between objects are not checked.
+package fakeapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/turbinelabs/api"
+	apierror "github.com/turbinelabs/api/http/error"
+	"github.com/turbinelabs/api/service"
+)
+
+// Error codes with which storeErrors are served, classifying them as the API
+// does.
+const (
+	notFoundCode         apierror.ErrorCode = "NotFoundError"
+	badRequestCode       apierror.ErrorCode = "BadRequestError"
+	conflictCode         apierror.ErrorCode = "ConflictError"
+	methodNotAllowedCode apierror.ErrorCode = "MethodNotAllowedError"
+)
+
+// storeError is an error produced by the Store, along with the HTTP status
+// and error code with which it is served.
+type storeError struct {
+	status  int
+	code    apierror.ErrorCode
+	message string
+}
+
+func (e *storeError) Error() string {
+	return e.message
+}
+
+// wireError returns the storeError as the error served in API responses.
+func (e *storeError) wireError() *apierror.Error {
+	return &apierror.Error{Message: e.message, Code: e.code}
+}
+
+// apiError converts a storeError to the error type returned by API clients,
+// so that callers handle it the same way.
+func (e *storeError) apiError() error {
+	if e == nil {
+		return nil
+	}
+	return e.wireError()
+}
+
+func notFound(name, key string) *storeError {
+	return &storeError{http.StatusNotFound, notFoundCode, fmt.Sprintf("%s %s not found", name, key)}
+}
+
+func badRequest(format string, args ...interface{}) *storeError {
+	return &storeError{http.StatusBadRequest, badRequestCode, fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...interface{}) *storeError {
+	return &storeError{http.StatusConflict, conflictCode, fmt.Sprintf(format, args...)}
+}
+
+func methodNotAllowed(r *http.Request) *storeError {
+	return &storeError{
+		http.StatusMethodNotAllowed,
+		methodNotAllowedCode,
+		r.Method + " not supported for " + r.URL.Path,
+	}
+}
+
+// table holds the objects of a single type, in insertion order.
+type table struct {
+	// name is the name of the object type, as used in API paths.
+	name string
+
+	// objType and filterType are the types of the objects stored, and of the
+	// service filters used to index them.
+	objType    reflect.Type
+	filterType reflect.Type
+
+	// keyField is the name of the field holding the object key.
+	keyField string
+
+	// unique, if non-nil, returns a string which must be unique among the
+	// objects in the table.
+	unique func(obj interface{}) string
+
+	// prepare, if non-nil, is called on objects before they are stored.
+	prepare func(obj interface{}) interface{}
+
+	objs  map[string]interface{}
+	order []string
+}
+
+// zoned returns true if the objects in the table belong to a Zone.
+func (t *table) zoned() bool {
+	_, ok := t.objType.FieldByName("ZoneKey")
+	return ok && t.keyField != "ZoneKey"
+}
+
+func (t *table) key(obj interface{}) string {
+	return reflect.ValueOf(obj).FieldByName(t.keyField).String()
+}
+
+func zoneKey(obj interface{}) string {
+	return reflect.ValueOf(obj).FieldByName("ZoneKey").String()
+}
+
+func checksum(obj interface{}) string {
+	return reflect.ValueOf(obj).FieldByName("Checksum").FieldByName("Checksum").String()
+}
+
+// withKeyAndChecksum returns a copy of obj with the given key and checksum.
+func (t *table) withKeyAndChecksum(obj interface{}, key, cs string) interface{} {
+	v := reflect.New(t.objType).Elem()
+	v.Set(reflect.ValueOf(obj))
+	v.FieldByName(t.keyField).SetString(key)
+	v.FieldByName("Checksum").FieldByName("Checksum").SetString(cs)
+	return v.Interface()
+}
+
+// copy returns a deep copy of obj, so that objects passed to or returned by
+// the Store share no slices or pointers with stored objects.
+func (t *table) copy(obj interface{}) interface{} {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	v := reflect.New(t.objType)
+	if err := json.Unmarshal(b, v.Interface()); err != nil {
+		panic(err)
+	}
+	return v.Elem().Interface()
+}
+
+// Store is an in-memory implementation of the Turbine Labs API. It is safe
+// for concurrent use.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// New returns an empty Store.
+func New() *Store {
+	s := &Store{tables: map[string]*table{}}
+
+	for _, t := range []*table{
+		newTable("zone", api.Zone{}, service.ZoneFilter{}, "ZoneKey", uniqueZone),
+		newTable("cluster", api.Cluster{}, service.ClusterFilter{}, "ClusterKey", uniqueCluster),
+		newTable("domain", api.Domain{}, service.DomainFilter{}, "DomainKey", uniqueDomain),
+		newTable("listener", api.Listener{}, service.ListenerFilter{}, "ListenerKey", uniqueListener),
+		newTable("proxy", api.Proxy{}, service.ProxyFilter{}, "ProxyKey", uniqueProxy),
+		newTable(
+			"shared_rules",
+			api.SharedRules{},
+			service.SharedRulesFilter{},
+			"SharedRulesKey",
+			uniqueSharedRules,
+		),
+		newTable("route", api.Route{}, service.RouteFilter{}, "RouteKey", uniqueRoute),
+		newTable("user", api.User{}, service.UserFilter{}, "UserKey", uniqueUser),
+		newTable("access_token", api.AccessToken{}, service.AccessTokenFilter{}, "AccessTokenKey", nil),
+	} {
+		s.tables[t.name] = t
+	}
+
+	s.tables["shared_rules"].prepare = func(o interface{}) interface{} {
+		sr := o.(api.SharedRules)
+		sr.Default = withConstraintKeys(sr.Default)
+		sr.Rules = withRuleKeys(sr.Rules)
+		return sr
+	}
+	s.tables["route"].prepare = func(o interface{}) interface{} {
+		r := o.(api.Route)
+		r.Rules = withRuleKeys(r.Rules)
+		return r
+	}
+
+	return s
+}
+
+// The unique functions identify objects which may not coexist. Objects are
+// unique by name within their Zone, except Domains, which are unique by
+// address, and Routes, which are unique by Domain and path. Zones are unique by
+// name and Users by login email.
+func uniqueZone(o interface{}) string { return o.(api.Zone).Name }
+
+func uniqueCluster(o interface{}) string {
+	c := o.(api.Cluster)
+	return fmt.Sprintf("%s/%s", c.ZoneKey, c.Name)
+}
+
+func uniqueDomain(o interface{}) string {
+	d := o.(api.Domain)
+	return fmt.Sprintf("%s/%s", d.ZoneKey, d.Addr())
+}
+
+func uniqueListener(o interface{}) string {
+	l := o.(api.Listener)
+	return fmt.Sprintf("%s/%s", l.ZoneKey, l.Name)
+}
+
+func uniqueProxy(o interface{}) string {
+	p := o.(api.Proxy)
+	return fmt.Sprintf("%s/%s", p.ZoneKey, p.Name)
+}
+
+func uniqueSharedRules(o interface{}) string {
+	sr := o.(api.SharedRules)
+	return fmt.Sprintf("%s/%s", sr.ZoneKey, sr.Name)
+}
+
+func uniqueRoute(o interface{}) string {
+	r := o.(api.Route)
+	return fmt.Sprintf("%s%s", r.DomainKey, r.Path)
+}
+
+func uniqueUser(o interface{}) string { return o.(api.User).LoginEmail }
+
+func newTable(
+	name string,
+	obj interface{},
+	filter interface{},
+	keyField string,
+	unique func(interface{}) string,
+) *table {
+	return &table{
+		name:       name,
+		objType:    reflect.TypeOf(obj),
+		filterType: reflect.TypeOf(filter),
+		keyField:   keyField,
+		unique:     unique,
+		objs:       map[string]interface{}{},
+	}
+}
+
+// newKey returns a random string suitable for use as an object key or
+// checksum.
+func newKey() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// withRuleKeys returns a copy of the Rules with keys assigned to any Rules or
+// ClusterConstraints which have none, as the API does.
+func withRuleKeys(rs api.Rules) api.Rules {
+	if rs == nil {
+		return nil
+	}
+	out := make(api.Rules, len(rs))
+	for i, r := range rs {
+		if r.RuleKey == "" {
+			r.RuleKey = api.RuleKey(newKey())
+		}
+		r.Constraints = withConstraintKeys(r.Constraints)
+		out[i] = r
+	}
+	return out
+}
+
+func withConstraintKeys(ac api.AllConstraints) api.AllConstraints {
+	return api.AllConstraints{
+		Light: withClusterConstraintKeys(ac.Light),
+		Dark:  withClusterConstraintKeys(ac.Dark),
+		Tap:   withClusterConstraintKeys(ac.Tap),
+	}
+}
+
+func withClusterConstraintKeys(ccs api.ClusterConstraints) api.ClusterConstraints {
+	if ccs == nil {
+		return nil
+	}
+	out := make(api.ClusterConstraints, len(ccs))
+	for i, cc := range ccs {
+		if cc.ConstraintKey == "" {
+			cc.ConstraintKey = api.ConstraintKey(newKey())
+		}
+		out[i] = cc
+	}
+	return out
+}
+
+func (s *Store) table(name string) *table {
+	t, ok := s.tables[name]
+	if !ok {
+		panic("unknown object type " + name)
+	}
+	return t
+}
+
+// index returns the objects of the named type matching any of the filters,
+// in the order they were created.
+func (s *Store) index(name string, filters []interface{}) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	result := []interface{}{}
+	for _, key := range t.order {
+		obj := t.objs[key]
+		if matchesAny(filters, obj) {
+			result = append(result, t.copy(obj))
+		}
+	}
+	return result
+}
+
+// get returns the object of the named type with the given key.
+func (s *Store) get(name, key string) (interface{}, *storeError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	obj, ok := t.objs[key]
+	if !ok {
+		return nil, notFound(name, key)
+	}
+	return t.copy(obj), nil
+}
+
+// checkUnique returns an error if another object in the table has the same
+// unique string as obj.
+func (t *table) checkUnique(obj interface{}) *storeError {
+	if t.unique == nil {
+		return nil
+	}
+	u, key := t.unique(obj), t.key(obj)
+	for k, other := range t.objs {
+		if k != key && t.unique(other) == u {
+			return conflict("%s %s already exists", t.name, u)
+		}
+	}
+	return nil
+}
+
+func (s *Store) checkZone(t *table, obj interface{}) *storeError {
+	if !t.zoned() {
+		return nil
+	}
+	zk := zoneKey(obj)
+	if _, ok := s.tables["zone"].objs[zk]; !ok {
+		return badRequest("%s refers to unknown zone %q", t.name, zk)
+	}
+	return nil
+}
+
+// create stores a new object of the named type. A key is generated unless the
+// object already has one, and a checksum is always generated.
+func (s *Store) create(name string, obj interface{}) (interface{}, *storeError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	key := t.key(obj)
+	if key == "" {
+		key = newKey()
+	} else if _, ok := t.objs[key]; ok {
+		return nil, conflict("%s %s already exists", name, key)
+	}
+
+	obj = t.copy(obj)
+	if t.prepare != nil {
+		obj = t.prepare(obj)
+	}
+	obj = t.withKeyAndChecksum(obj, key, newKey())
+
+	if err := s.checkZone(t, obj); err != nil {
+		return nil, err
+	}
+	if err := t.checkUnique(obj); err != nil {
+		return nil, err
+	}
+
+	t.objs[key] = obj
+	t.order = append(t.order, key)
+	return t.copy(obj), nil
+}
+
+// modify replaces an existing object of the named type, provided its checksum
+// matches the stored object. A new checksum is generated.
+func (s *Store) modify(name string, obj interface{}) (interface{}, *storeError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	key := t.key(obj)
+	cur, ok := t.objs[key]
+	if !ok {
+		return nil, notFound(name, key)
+	}
+	if cs := checksum(obj); cs != checksum(cur) {
+		return nil, conflict("%s %s checksum mismatch: have %q, got %q", name, key, checksum(cur), cs)
+	}
+
+	obj = t.copy(obj)
+	if t.prepare != nil {
+		obj = t.prepare(obj)
+	}
+	obj = t.withKeyAndChecksum(obj, key, newKey())
+
+	if err := s.checkZone(t, obj); err != nil {
+		return nil, err
+	}
+	if err := t.checkUnique(obj); err != nil {
+		return nil, err
+	}
+
+	t.objs[key] = obj
+	return t.copy(obj), nil
+}
+
+// delete removes an existing object of the named type, provided the checksum
+// matches the stored object. Zones may only be deleted once no objects refer
+// to them.
+func (s *Store) delete(name, key, cs string) *storeError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	cur, ok := t.objs[key]
+	if !ok {
+		return notFound(name, key)
+	}
+	if cs != checksum(cur) {
+		return conflict("%s %s checksum mismatch: have %q, got %q", name, key, checksum(cur), cs)
+	}
+
+	if name == "zone" {
+		for _, other := range s.tables {
+			if !other.zoned() {
+				continue
+			}
+			for _, obj := range other.objs {
+				if zoneKey(obj) == key {
+					return badRequest("zone %s is not empty: %s %s refers to it", key, other.name, other.key(obj))
+				}
+			}
+		}
+	}
+
+	delete(t.objs, key)
+	for i, k := range t.order {
+		if k == key {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// updateInstances applies f to the Instances of a Cluster, provided the
+// checksum matches the stored Cluster. A new checksum is generated.
+func (s *Store) updateInstances(
+	key api.ClusterKey,
+	cs api.Checksum,
+	f func(api.Instances) (api.Instances, *storeError),
+) (api.Cluster, *storeError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table("cluster")
+	obj, ok := t.objs[string(key)]
+	if !ok {
+		return api.Cluster{}, notFound(t.name, string(key))
+	}
+	c := obj.(api.Cluster)
+	if cs.Checksum != c.Checksum.Checksum {
+		return api.Cluster{}, conflict(
+			"cluster %s checksum mismatch: have %q, got %q",
+			key,
+			c.Checksum.Checksum,
+			cs.Checksum,
+		)
+	}
+
+	c = t.copy(c).(api.Cluster)
+	instances, err := f(c.Instances)
+	if err != nil {
+		return api.Cluster{}, err
+	}
+	c.Instances = instances
+	c.Checksum = api.Checksum{Checksum: newKey()}
+	t.objs[string(key)] = c
+	return t.copy(c).(api.Cluster), nil
+}
+
+// addInstance adds an Instance to a Cluster, provided the checksum matches the
+// stored Cluster. It is an error to add an Instance with the same host and port
+// as an existing one.
+func (s *Store) addInstance(
+	key api.ClusterKey,
+	cs api.Checksum,
+	i api.Instance,
+) (api.Cluster, *storeError) {
+	return s.updateInstances(key, cs, func(is api.Instances) (api.Instances, *storeError) {
+		for _, existing := range is {
+			if existing.Host == i.Host && existing.Port == i.Port {
+				return nil, conflict("cluster %s already has instance %s:%d", key, i.Host, i.Port)
+			}
+		}
+		return append(is, i), nil
+	})
+}
+
+// removeInstance removes the Instance with the same host and port as the given
+// one from a Cluster, provided the checksum matches the stored Cluster.
+func (s *Store) removeInstance(
+	key api.ClusterKey,
+	cs api.Checksum,
+	i api.Instance,
+) (api.Cluster, *storeError) {
+	return s.updateInstances(key, cs, func(is api.Instances) (api.Instances, *storeError) {
+		for j, existing := range is {
+			if existing.Host == i.Host && existing.Port == i.Port {
+				return append(is[:j], is[j+1:]...), nil
+			}
+		}
+		return nil, notFound("instance", fmt.Sprintf("%s:%d", i.Host, i.Port))
+	})
+}
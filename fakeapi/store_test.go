@@ -0,0 +1,198 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/turbinelabs/api"
+	apierror "github.com/turbinelabs/api/http/error"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+func assertAPIError(t *testing.T, err error, contains string) {
+	e, ok := err.(*apierror.Error)
+	if !ok {
+		t.Errorf("got %#v, want *apierror.Error", err)
+		return
+	}
+	if !strings.Contains(e.Message, contains) {
+		t.Errorf("error %q does not contain %q", e.Message, contains)
+	}
+}
+
+func TestStoreCreateGeneratesKeysAndChecksums(t *testing.T) {
+	svc := New().All()
+
+	z, err := svc.Zone().Create(api.Zone{Name: "z"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, z.ZoneKey, api.ZoneKey(""))
+	assert.NotEqual(t, z.Checksum, api.Checksum{})
+
+	got, err := svc.Zone().Get(z.ZoneKey)
+	assert.Nil(t, err)
+	assert.Equal(t, got, z)
+
+	_, err = svc.Zone().Create(api.Zone{Name: "z"})
+	assertAPIError(t, err, "zone z already exists")
+
+	_, err = svc.Cluster().Create(api.Cluster{ZoneKey: "nope", Name: "c"})
+	assertAPIError(t, err, `unknown zone "nope"`)
+
+	sr, err := svc.SharedRules().Create(api.SharedRules{
+		ZoneKey: z.ZoneKey,
+		Name:    "sr",
+		Default: api.AllConstraints{Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 1}}},
+	})
+	assert.Nil(t, err)
+	assert.NotEqual(t, sr.Default.Light[0].ConstraintKey, api.ConstraintKey(""))
+}
+
+func TestStoreChecksums(t *testing.T) {
+	svc := New().All()
+
+	z, err := svc.Zone().Create(api.Zone{Name: "z"})
+	assert.Nil(t, err)
+	c, err := svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: "c"})
+	assert.Nil(t, err)
+
+	stale := c
+	c.RequireTLS = true
+	c, err = svc.Cluster().Modify(c)
+	assert.Nil(t, err)
+	assert.NotEqual(t, c.Checksum, stale.Checksum)
+
+	_, err = svc.Cluster().Modify(stale)
+	assertAPIError(t, err, "checksum mismatch")
+	assertAPIError(t, svc.Cluster().Delete(c.ClusterKey, stale.Checksum), "checksum mismatch")
+
+	c, err = svc.Cluster().AddInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, c.Instances, api.Instances{{Host: "h", Port: 80}})
+
+	_, err = svc.Cluster().AddInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assertAPIError(t, err, "already has instance h:80")
+
+	c, err = svc.Cluster().RemoveInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assert.Nil(t, err)
+	assert.Equal(t, len(c.Instances), 0)
+
+	assertAPIError(t, svc.Zone().Delete(z.ZoneKey, z.Checksum), "is not empty")
+	assert.Nil(t, svc.Cluster().Delete(c.ClusterKey, c.Checksum))
+	assert.Nil(t, svc.Zone().Delete(z.ZoneKey, z.Checksum))
+
+	_, err = svc.Zone().Get(z.ZoneKey)
+	assertAPIError(t, err, "not found")
+}
+
+func TestStoreIndexFilters(t *testing.T) {
+	svc := New().All()
+
+	z, err := svc.Zone().Create(api.Zone{Name: "z"})
+	assert.Nil(t, err)
+	d, err := svc.Domain().Create(api.Domain{ZoneKey: z.ZoneKey, Name: "d", Port: 80})
+	assert.Nil(t, err)
+	other, err := svc.Domain().Create(api.Domain{ZoneKey: z.ZoneKey, Name: "e", Port: 80})
+	assert.Nil(t, err)
+
+	for _, path := range []string{"/api/users", "/api/orders", "/health"} {
+		_, err := svc.Route().Create(api.Route{ZoneKey: z.ZoneKey, DomainKey: d.DomainKey, Path: path})
+		assert.Nil(t, err)
+	}
+
+	p, err := svc.Proxy().Create(api.Proxy{
+		ZoneKey:    z.ZoneKey,
+		Name:       "p",
+		DomainKeys: []api.DomainKey{d.DomainKey, other.DomainKey},
+	})
+	assert.Nil(t, err)
+
+	rs, err := svc.Route().Index()
+	assert.Nil(t, err)
+	assert.Equal(t, len(rs), 3)
+
+	rs, err = svc.Route().Index(service.RouteFilter{PathPrefix: "/api"})
+	assert.Nil(t, err)
+	assert.Equal(t, len(rs), 2)
+	assert.Equal(t, rs[0].Path, "/api/users")
+	assert.Equal(t, rs[1].Path, "/api/orders")
+
+	rs, err = svc.Route().Index(
+		service.RouteFilter{Path: "/health"},
+		service.RouteFilter{Path: "/api/users"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, len(rs), 2)
+
+	ds, err := svc.Domain().Index(service.DomainFilter{Name: "e", Port: 80})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, ds, api.Domains{other})
+
+	ps, err := svc.Proxy().Index(service.ProxyFilter{DomainKeys: []api.DomainKey{other.DomainKey}})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, ps, api.Proxies{p})
+
+	ps, err = svc.Proxy().Index(service.ProxyFilter{DomainKeys: []api.DomainKey{"nope"}})
+	assert.Nil(t, err)
+	assert.Equal(t, len(ps), 0)
+}
+
+func TestStoreCopiesObjects(t *testing.T) {
+	svc := New().All()
+
+	z, err := svc.Zone().Create(api.Zone{Name: "z"})
+	assert.Nil(t, err)
+
+	in := api.SharedRules{
+		ZoneKey: z.ZoneKey,
+		Name:    "sr",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 1}},
+		},
+	}
+	sr, err := svc.SharedRules().Create(in)
+	assert.Nil(t, err)
+	want, err := svc.SharedRules().Get(sr.SharedRulesKey)
+	assert.Nil(t, err)
+
+	in.Default.Light[0].ClusterKey = "created"
+	sr.Default.Light[0].ClusterKey = "returned"
+
+	got, err := svc.SharedRules().Get(sr.SharedRulesKey)
+	assert.Nil(t, err)
+	got.Default.Light[0].ClusterKey = "got"
+
+	srs, err := svc.SharedRules().Index()
+	assert.Nil(t, err)
+	srs[0].Default.Light[0].ClusterKey = "indexed"
+
+	got, err = svc.SharedRules().Get(sr.SharedRulesKey)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, got, want)
+
+	c, err := svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: "c"})
+	assert.Nil(t, err)
+	c, err = svc.Cluster().AddInstance(c.ClusterKey, c.Checksum, api.Instance{Host: "h", Port: 80})
+	assert.Nil(t, err)
+	c.Instances[0].Host = "returned"
+
+	cs, err := svc.Cluster().Index()
+	assert.Nil(t, err)
+	assert.Equal(t, cs[0].Instances[0].Host, "h")
+}
@@ -91,6 +91,22 @@ func (a clusterAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Cluster).Checksum
 }
 
+func (a clusterAdapter) Key(o interface{}) string {
+	return string(o.(api.Cluster).ClusterKey)
+}
+
+func (a clusterAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Cluster)
+	obj.ClusterKey = api.ClusterKey(k)
+	return obj
+}
+
+func (a clusterAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Cluster)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetCluster(svc *unifiedSvc) func(k api.ClusterKey) (api.Cluster, error) {
 	cache := map[api.ClusterKey]api.Cluster{}
 	return func(k api.ClusterKey) (api.Cluster, error) {
@@ -91,6 +91,22 @@ func (a domainAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Domain).Checksum
 }
 
+func (a domainAdapter) Key(o interface{}) string {
+	return string(o.(api.Domain).DomainKey)
+}
+
+func (a domainAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Domain)
+	obj.DomainKey = api.DomainKey(k)
+	return obj
+}
+
+func (a domainAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Domain)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetDomain(svc *unifiedSvc) func(k api.DomainKey) (api.Domain, error) {
 	cache := map[api.DomainKey]api.Domain{}
 	return func(k api.DomainKey) (api.Domain, error) {
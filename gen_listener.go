@@ -91,6 +91,22 @@ func (a listenerAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Listener).Checksum
 }
 
+func (a listenerAdapter) Key(o interface{}) string {
+	return string(o.(api.Listener).ListenerKey)
+}
+
+func (a listenerAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Listener)
+	obj.ListenerKey = api.ListenerKey(k)
+	return obj
+}
+
+func (a listenerAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Listener)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetListener(svc *unifiedSvc) func(k api.ListenerKey) (api.Listener, error) {
 	cache := map[api.ListenerKey]api.Listener{}
 	return func(k api.ListenerKey) (api.Listener, error) {
@@ -91,6 +91,22 @@ func (a proxyAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Proxy).Checksum
 }
 
+func (a proxyAdapter) Key(o interface{}) string {
+	return string(o.(api.Proxy).ProxyKey)
+}
+
+func (a proxyAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Proxy)
+	obj.ProxyKey = api.ProxyKey(k)
+	return obj
+}
+
+func (a proxyAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Proxy)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetProxy(svc *unifiedSvc) func(k api.ProxyKey) (api.Proxy, error) {
 	cache := map[api.ProxyKey]api.Proxy{}
 	return func(k api.ProxyKey) (api.Proxy, error) {
@@ -91,6 +91,22 @@ func (a routeAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Route).Checksum
 }
 
+func (a routeAdapter) Key(o interface{}) string {
+	return string(o.(api.Route).RouteKey)
+}
+
+func (a routeAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Route)
+	obj.RouteKey = api.RouteKey(k)
+	return obj
+}
+
+func (a routeAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Route)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetRoute(svc *unifiedSvc) func(k api.RouteKey) (api.Route, error) {
 	cache := map[api.RouteKey]api.Route{}
 	return func(k api.RouteKey) (api.Route, error) {
@@ -91,6 +91,22 @@ func (a sharedRulesAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.SharedRules).Checksum
 }
 
+func (a sharedRulesAdapter) Key(o interface{}) string {
+	return string(o.(api.SharedRules).SharedRulesKey)
+}
+
+func (a sharedRulesAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.SharedRules)
+	obj.SharedRulesKey = api.SharedRulesKey(k)
+	return obj
+}
+
+func (a sharedRulesAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.SharedRules)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetSharedRules(svc *unifiedSvc) func(k api.SharedRulesKey) (api.SharedRules, error) {
 	cache := map[api.SharedRulesKey]api.SharedRules{}
 	return func(k api.SharedRulesKey) (api.SharedRules, error) {
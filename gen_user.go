@@ -91,6 +91,22 @@ func (a userAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.User).Checksum
 }
 
+func (a userAdapter) Key(o interface{}) string {
+	return string(o.(api.User).UserKey)
+}
+
+func (a userAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.User)
+	obj.UserKey = api.UserKey(k)
+	return obj
+}
+
+func (a userAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.User)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetUser(svc *unifiedSvc) func(k api.UserKey) (api.User, error) {
 	cache := map[api.UserKey]api.User{}
 	return func(k api.UserKey) (api.User, error) {
@@ -91,6 +91,22 @@ func (a zoneAdapter) Checksum(o interface{}) api.Checksum {
 	return o.(api.Zone).Checksum
 }
 
+func (a zoneAdapter) Key(o interface{}) string {
+	return string(o.(api.Zone).ZoneKey)
+}
+
+func (a zoneAdapter) WithKey(o interface{}, k string) interface{} {
+	obj := o.(api.Zone)
+	obj.ZoneKey = api.ZoneKey(k)
+	return obj
+}
+
+func (a zoneAdapter) WithChecksum(o interface{}, cs api.Checksum) interface{} {
+	obj := o.(api.Zone)
+	obj.Checksum = cs
+	return obj
+}
+
 func mkGetZone(svc *unifiedSvc) func(k api.ZoneKey) (api.Zone, error) {
 	cache := map[api.ZoneKey]api.Zone{}
 	return func(k api.ZoneKey) (api.Zone, error) {
@@ -39,7 +39,7 @@ func (gc *getRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 		return err
 	}
 
-	svc, err := gc.cfg.UntypedSvc(&args)
+	svc, err := gc.cfg.UntypedKeyedSvc(&args)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
@@ -48,6 +48,10 @@ func (gc *getRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 		return cerr
 	}
 
+	if gc.cfg.key, err = gc.cfg.resolveKey(svc, gc.cfg.key); err != nil {
+		return gc.cfg.PrettyCmdErr(cmd, err)
+	}
+
 	obj, err := svc.Get(gc.cfg.key)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
@@ -65,7 +69,7 @@ func cmdGet(cfg globalConfigT) *command.Cmd {
 		Name:        "get",
 		Summary:     "retrieve an object from Turbine Labs API",
 		Usage:       "[OPTIONS] <object type> <object key>",
-		Description: "object type is one of: " + objTypeNames(),
+		Description: "object type is one of: " + objTypeNames() + "\n\n" + selectorDesc,
 		Runner:      runner,
 	}
 
@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
 	"github.com/turbinelabs/nonstdlib/editor"
 	tbnos "github.com/turbinelabs/nonstdlib/os"
 )
@@ -28,7 +29,26 @@ const importZoneDesc = `Import a Zone to the Turbine Labs API. Typically the
 input will be the output of a previous call to export-zone, with object keys
 replaced by names. Referential integrity, assuming it is present in the input,
 is maintained in the import. The Zone to be imported is assumed not to exist,
-and import-zone will fail if the Zone is already present.`
+and import-zone will fail if the Zone is already present, unless --merge is
+given.
+
+With --merge, the input is merged into the Zone, which is created if it does not
+exist. References are resolved against objects already in the Zone: Clusters,
+Listeners, Proxies and SharedRules by name, Domains by name and port, and Routes
+by domain and path. Missing objects are created. An existing object which
+differs from the input is a conflict, and is handled according to
+--on-conflict: "keep" leaves the existing object as is, "overwrite" modifies it
+to match the input, and "fail" stops the import. Objects in the Zone which are
+not in the input are left alone. The changes made, including conflicting
+objects which were kept, are printed as a list of actions.
+
+If the import fails
+partway through, objects already created are deleted, unless --no-rollback is
+given.
+
+If --values or --set is given, the input is treated as a Go text/template and
+rendered with the given values before it is decoded. Use the render sub-command
+to preview the result.`
 
 func cmdImportZone(globalConfig globalConfigT) *command.Cmd {
 	cmd := &command.Cmd{
@@ -38,12 +58,32 @@ func cmdImportZone(globalConfig globalConfigT) *command.Cmd {
 		Description: importZoneDesc,
 	}
 
-	cmd.Runner = &importZoneRunner{globalConfig}
+	r := &importZoneRunner{cfg: globalConfig}
+	cmd.Flags.BoolVar(&r.noRollback, "no-rollback", false, noRollbackDesc)
+	cmd.Flags.BoolVar(
+		&r.merge,
+		"merge",
+		false,
+		"If true, merge the input into the Zone, which may already exist.",
+	)
+	cmd.Flags.StringVar(
+		&r.onConflict,
+		"on-conflict",
+		conflictKeep,
+		"With --merge, how to handle existing objects which differ from the input: keep, overwrite, or fail.",
+	)
+	r.templates = newZoneTemplateFlags(&cmd.Flags)
+
+	cmd.Runner = r
 	return cmd
 }
 
 type importZoneRunner struct {
-	cfg globalConfigT
+	cfg        globalConfigT
+	noRollback bool
+	merge      bool
+	onConflict string
+	templates  *zoneTemplateFlags
 }
 
 func (r *importZoneRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
@@ -59,6 +99,17 @@ func (r *importZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
 		return cmd.BadInput("requires exactly one argument")
 	}
 
+	switch r.onConflict {
+	case conflictKeep, conflictOverwrite, conflictFail:
+	default:
+		return cmd.BadInputf(
+			"--on-conflict must be one of %s, %s, or %s",
+			conflictKeep,
+			conflictOverwrite,
+			conflictFail,
+		)
+	}
+
 	var (
 		txt string
 		err error
@@ -75,7 +126,21 @@ func (r *importZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
 		}
 	}
 
-	zo, err := importZone(r.cfg.apiClient, args[0], r.cfg.codec, txt)
+	txt, err = r.templates.render(templateName(""), txt)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	if r.merge {
+		return r.runMerge(cmd, args[0], txt)
+	}
+
+	var zo *zoneObjects
+	err = withRollback(r.cfg.apiClient, r.noRollback, func(svc *unifiedSvc) error {
+		var err error
+		zo, err = importZone(svc, args[0], r.cfg.codec, txt)
+		return err
+	})
 	if err != nil {
 		return r.cfg.PrettyCmdErr(cmd, err)
 	}
@@ -84,3 +149,24 @@ func (r *importZoneRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
 
 	return command.NoError()
 }
+
+func (r *importZoneRunner) runMerge(cmd *command.Cmd, name, txt string) command.CmdErr {
+	zo := newZoneObjects()
+	if err := codec.DecodeFromString(r.cfg.codec, txt, zo); err != nil {
+		return cmd.BadInputf("could not decode zone document: %s", err)
+	}
+	zo.Zone.Name = name
+
+	var changes []zoneChange
+	err := withRollback(r.cfg.apiClient, r.noRollback, func(svc *unifiedSvc) error {
+		var err error
+		changes, err = mergeZone(svc, zo, r.onConflict)
+		return err
+	})
+	r.cfg.PrintResult(changes)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	"github.com/turbinelabs/api"
-	apiflags "github.com/turbinelabs/api/client/flags"
 	"github.com/turbinelabs/api/service"
 	"github.com/turbinelabs/cli/command"
 	tbnflag "github.com/turbinelabs/nonstdlib/flag"
@@ -33,7 +32,8 @@ const (
 	initZoneDesc = `
 initialize a named Zone in the Turbine Labs API, adding zero or more default
 routes for pairs of domain/port and cluster names, and zero or more proxies
-serving one or more domains each.`
+serving one or more domains each. If any step fails, objects created or
+modified up to that point are rolled back, unless --no-rollback is given.`
 
 	domainFormat = "`" + `"domain:port=alias([:alias]*),..."` + "`"
 
@@ -106,7 +106,6 @@ func cmdInitZone(globalConfig globalConfigT) *command.Cmd {
 
 	r := &initZoneRunner{
 		cfg:        &globalConfig,
-		apiFlags:   globalConfig.apiFlags,
 		domainStrs: tbnflag.NewStrings(),
 		routesStrs: tbnflag.NewStrings(),
 		proxyStrs:  tbnflag.NewStrings(),
@@ -121,6 +120,7 @@ func cmdInitZone(globalConfig globalConfigT) *command.Cmd {
 		false,
 		"If true, replace existing Routes, SharedRules, and Proxies. If false, leave them as is.",
 	)
+	cmd.Flags.BoolVar(&r.noRollback, "no-rollback", false, noRollbackDesc)
 
 	cmd.Runner = r
 	return cmd
@@ -128,11 +128,11 @@ func cmdInitZone(globalConfig globalConfigT) *command.Cmd {
 
 type initZoneRunner struct {
 	cfg        *globalConfigT
-	apiFlags   apiflags.ClientFromFlags
 	domainStrs tbnflag.Strings
 	routesStrs tbnflag.Strings
 	proxyStrs  tbnflag.Strings
 	replace    bool
+	noRollback bool
 }
 
 type hostPort struct {
@@ -198,29 +198,23 @@ func (r *initZoneRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 		return cmd.BadInput(err)
 	}
 
-	if err := r.apiFlags.Validate(); err != nil {
-		return cmd.BadInput(err)
-	}
-
 	console.Debug().Println("ZONE NAME: ", zoneName)
 	console.Debug().Printf("  DOMAINS: %+v\n", domains)
 	console.Debug().Printf("   ROUTES: %+v\n", routes)
 	console.Debug().Printf("  PROXIES: %+v\n", proxies)
 
-	svc, err := r.apiFlags.Make()
-	if err != nil {
-		return r.cfg.PrettyCmdErr(cmd, err)
-	}
+	err = withRollback(r.cfg.apiClient, r.noRollback, func(svc *unifiedSvc) error {
+		zkey, err := addZone(svc.Zone(), zoneName)
+		if err != nil {
+			return err
+		}
 
-	zkey, err := addZone(svc.Zone(), zoneName)
+		return addObjects(svc, zkey, domains, routes, proxies, r.replace)
+	})
 	if err != nil {
 		return r.cfg.PrettyCmdErr(cmd, err)
 	}
 
-	if err := addObjects(svc, zkey, domains, routes, proxies, r.replace); err != nil {
-		return r.cfg.PrettyCmdErr(cmd, err)
-	}
-
 	return command.NoError()
 }
 
@@ -0,0 +1,339 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/cli/command"
+	tbnstrings "github.com/turbinelabs/nonstdlib/strings"
+)
+
+const (
+	instancesList        = "list"
+	instancesAdd         = "add"
+	instancesRemove      = "remove"
+	instancesSetMetadata = "set-metadata"
+	instancesDrain       = "drain"
+)
+
+const instancesDesc = `Manage the Instances of a Cluster. The action is one of:
+
+    list          list the Instances of the Cluster.
+    add           add Instances, with optional metadata.
+    remove        remove Instances. Each Instance must exist.
+    set-metadata  set metadata on existing Instances. A key with an empty
+                  value (e.g. "key=") removes the metadatum.
+    drain         remove all Instances matching the given host:port and/or
+                  metadata.
+
+Instances are given as host:port, each followed by zero or more key=value
+metadata. For example:
+
+    tbnctl instances add api 10.0.0.1:8080 version=2 10.0.0.2:8080 version=2
+    tbnctl instances set-metadata api 10.0.0.1:8080 stage=canary
+    tbnctl instances drain api version=1
+
+Instances may also be read from a file with -f, one host:port and its metadata
+per line, separated by whitespace. Blank lines and lines beginning with # are
+ignored.
+
+All changes from a single invocation are made in one modification of the
+Cluster. If the Cluster is modified concurrently, the changes are re-applied
+to the new version and retried. The resulting Instances are printed.`
+
+func cmdInstances(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "instances",
+		Summary:     "manage the Instances of a Cluster",
+		Usage:       "[OPTIONS] <list|add|remove|set-metadata|drain> <cluster> [host:port [key=value]*]*",
+		Description: instancesDesc,
+	}
+
+	r := &instancesRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.file,
+		"f",
+		"",
+		"A file containing Instances, one per line, in addition to those given as arguments.",
+	)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type instancesRunner struct {
+	cfg  globalConfigT
+	file string
+}
+
+// instanceSpec is an Instance, or selector of Instances, given on the command
+// line or in a file. An empty host indicates only metadata was given.
+type instanceSpec struct {
+	host     string
+	port     int
+	metadata api.Metadata
+}
+
+func (s instanceSpec) hostPort() string {
+	return fmt.Sprintf("%s:%d", s.host, s.port)
+}
+
+func (s instanceSpec) matches(i api.Instance) bool {
+	if s.host != "" && (s.host != i.Host || s.port != i.Port) {
+		return false
+	}
+	return hasMetadata(i.Metadata, s.metadata)
+}
+
+// parseInstanceSpecs parses a list of tokens into instanceSpecs. Each
+// host:port begins a new instanceSpec, and key=value tokens are added to the
+// metadata of the preceding one.
+func parseInstanceSpecs(tokens []string) ([]instanceSpec, error) {
+	specs := []instanceSpec{}
+	for _, tok := range tokens {
+		if strings.Contains(tok, "=") {
+			k, v := tbnstrings.SplitFirstEqual(tok)
+			if k == "" {
+				return nil, fmt.Errorf("malformed metadata %q: must be of the form key=value", tok)
+			}
+			if len(specs) == 0 {
+				specs = append(specs, instanceSpec{})
+			}
+			md := &specs[len(specs)-1].metadata
+			*md = append(*md, api.Metadatum{Key: k, Value: v})
+			continue
+		}
+
+		host, port, err := tbnstrings.SplitHostPort(tok)
+		if err != nil {
+			return nil, fmt.Errorf("malformed instance %q: must be of the form host:port", tok)
+		}
+		specs = append(specs, instanceSpec{host: host, port: port})
+	}
+
+	return specs, nil
+}
+
+// parseInstanceFile parses instanceSpecs from text with one Instance per line.
+func parseInstanceFile(txt string) ([]instanceSpec, error) {
+	specs := []instanceSpec{}
+	for i, line := range strings.Split(txt, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineSpecs, err := parseInstanceSpecs(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		specs = append(specs, lineSpecs...)
+	}
+
+	return specs, nil
+}
+
+func requireHostPorts(specs []instanceSpec) error {
+	for _, s := range specs {
+		if s.host == "" {
+			return fmt.Errorf("metadata %s must follow a host:port", formatMetadataSelector(s.metadata))
+		}
+	}
+	return nil
+}
+
+func findInstance(is api.Instances, s instanceSpec) int {
+	for idx, i := range is {
+		if i.Host == s.host && i.Port == s.port {
+			return idx
+		}
+	}
+	return -1
+}
+
+func addInstances(is api.Instances, specs []instanceSpec) (api.Instances, error) {
+	if err := requireHostPorts(specs); err != nil {
+		return nil, err
+	}
+
+	result := append(api.Instances{}, is...)
+	for _, s := range specs {
+		if findInstance(result, s) != -1 {
+			return nil, fmt.Errorf("instance %s already exists", s.hostPort())
+		}
+		result = append(result, api.Instance{Host: s.host, Port: s.port, Metadata: s.metadata})
+	}
+
+	return result, nil
+}
+
+func removeInstances(is api.Instances, specs []instanceSpec) (api.Instances, error) {
+	if err := requireHostPorts(specs); err != nil {
+		return nil, err
+	}
+
+	result := append(api.Instances{}, is...)
+	for _, s := range specs {
+		idx := findInstance(result, s)
+		if idx == -1 {
+			return nil, fmt.Errorf("instance %s does not exist", s.hostPort())
+		}
+		result = append(result[:idx], result[idx+1:]...)
+	}
+
+	return result, nil
+}
+
+func setInstanceMetadata(is api.Instances, specs []instanceSpec) (api.Instances, error) {
+	if err := requireHostPorts(specs); err != nil {
+		return nil, err
+	}
+
+	result := append(api.Instances{}, is...)
+	for _, s := range specs {
+		idx := findInstance(result, s)
+		if idx == -1 {
+			return nil, fmt.Errorf("instance %s does not exist", s.hostPort())
+		}
+
+		md := withMetadata(result[idx].Metadata, s.metadata)
+		trimmed := api.Metadata{}
+		for _, m := range md {
+			if m.Value != "" {
+				trimmed = append(trimmed, m)
+			}
+		}
+		result[idx].Metadata = trimmed
+	}
+
+	return result, nil
+}
+
+func drainInstances(is api.Instances, specs []instanceSpec) (api.Instances, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("drain requires at least one host:port or key=value")
+	}
+
+	result := api.Instances{}
+	for _, i := range is {
+		drain := false
+		for _, s := range specs {
+			if s.matches(i) {
+				drain = true
+				break
+			}
+		}
+		if !drain {
+			result = append(result, i)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *instancesRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *instancesRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) < 2 {
+		return cmd.BadInput("requires an action and a cluster")
+	}
+	action, name := args[0], args[1]
+
+	var op func(api.Instances, []instanceSpec) (api.Instances, error)
+	switch action {
+	case instancesList:
+	case instancesAdd:
+		op = addInstances
+	case instancesRemove:
+		op = removeInstances
+	case instancesSetMetadata:
+		op = setInstanceMetadata
+	case instancesDrain:
+		op = drainInstances
+	default:
+		return cmd.BadInputf("unknown instances action %q", action)
+	}
+
+	specs, err := parseInstanceSpecs(args[2:])
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	if r.file != "" {
+		bytes, err := ioutil.ReadFile(r.file)
+		if err != nil {
+			return cmd.Errorf("could not read %s: %s", r.file, err)
+		}
+		fileSpecs, err := parseInstanceFile(string(bytes))
+		if err != nil {
+			return cmd.BadInputf("%s: %s", r.file, err)
+		}
+		specs = append(specs, fileSpecs...)
+	}
+
+	svc := clusterAdapter{r.cfg.apiClient.Cluster()}
+	key, err := r.cfg.resolveKeyOrName(svc, name)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	obj, err := svc.Get(key)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+	c := obj.(api.Cluster)
+
+	if op == nil {
+		if len(specs) > 0 {
+			return cmd.BadInput("list takes no instances")
+		}
+		r.cfg.PrintResult(c.Instances)
+		return command.NoError()
+	}
+
+	if len(specs) == 0 && action != instancesDrain {
+		return cmd.BadInputf("%s requires at least one instance", action)
+	}
+
+	res, err := modifyWithRetry(svc, key, c, func(obj interface{}) (interface{}, error) {
+		c := obj.(api.Cluster)
+		is, err := op(c.Instances, specs)
+		if err != nil {
+			return nil, err
+		}
+		c.Instances = is
+		return c, nil
+	})
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	r.cfg.PrintResult(res.(api.Cluster).Instances)
+
+	return command.NoError()
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestParseInstanceSpecs(t *testing.T) {
+	specs, err := parseInstanceSpecs([]string{"a:80", "v=1", "s=x", "b:81", "c:82", "v=2"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, specs, []instanceSpec{
+		{host: "a", port: 80, metadata: api.Metadata{{Key: "v", Value: "1"}, {Key: "s", Value: "x"}}},
+		{host: "b", port: 81},
+		{host: "c", port: 82, metadata: api.Metadata{{Key: "v", Value: "2"}}},
+	})
+
+	specs, err = parseInstanceSpecs([]string{"v=1"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, specs, []instanceSpec{{metadata: api.Metadata{{Key: "v", Value: "1"}}}})
+
+	_, err = parseInstanceSpecs([]string{"a"})
+	assert.NonNil(t, err)
+}
+
+func TestParseInstanceFile(t *testing.T) {
+	specs, err := parseInstanceFile("# instances\na:80 v=1\n\n  b:81\n")
+	assert.Nil(t, err)
+	assert.Equal(t, len(specs), 2)
+	assert.Equal(t, specs[1].hostPort(), "b:81")
+
+	_, err = parseInstanceFile("a:80\nnope\n")
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestInstanceOps(t *testing.T) {
+	is := api.Instances{
+		{Host: "a", Port: 80, Metadata: api.Metadata{{Key: "v", Value: "1"}}},
+		{Host: "b", Port: 80, Metadata: api.Metadata{{Key: "v", Value: "2"}}},
+	}
+
+	added, err := addInstances(is, []instanceSpec{{host: "c", port: 80}})
+	assert.Nil(t, err)
+	assert.Equal(t, len(added), 3)
+	assert.Equal(t, len(is), 2)
+
+	_, err = addInstances(is, []instanceSpec{{host: "a", port: 80}})
+	assert.ErrorContains(t, err, "already exists")
+
+	removed, err := removeInstances(is, []instanceSpec{{host: "a", port: 80}})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, removed, is[1:])
+
+	_, err = removeInstances(is, []instanceSpec{{host: "c", port: 80}})
+	assert.ErrorContains(t, err, "does not exist")
+
+	set, err := setInstanceMetadata(is, []instanceSpec{
+		{host: "a", port: 80, metadata: api.Metadata{{Key: "v", Value: ""}, {Key: "s", Value: "x"}}},
+	})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, set[0].Metadata, api.Metadata{{Key: "s", Value: "x"}})
+	assert.DeepEqual(t, is[0].Metadata, api.Metadata{{Key: "v", Value: "1"}})
+
+	drained, err := drainInstances(is, []instanceSpec{{metadata: api.Metadata{{Key: "v", Value: "2"}}}})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, drained, is[:1])
+
+	_, err = drainInstances(is, nil)
+	assert.NonNil(t, err)
+}
+
+func TestModifyWithRetry(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	mc := service.NewMockCluster(ctrl)
+
+	v1 := api.Cluster{ClusterKey: "ck", Name: "c", Checksum: api.Checksum{Checksum: "1"}}
+	v2 := api.Cluster{ClusterKey: "ck", Name: "c", RequireTLS: true, Checksum: api.Checksum{Checksum: "2"}}
+	v3 := v2
+	v3.Instances = api.Instances{{Host: "a", Port: 80}}
+
+	mod1 := v1
+	mod1.Instances = v3.Instances
+	mod2 := v2
+	mod2.Instances = v3.Instances
+
+	gomock.InOrder(
+		mc.EXPECT().Modify(mod1).Return(api.Cluster{}, errors.New("conflict")),
+		mc.EXPECT().Get(api.ClusterKey("ck")).Return(v2, nil),
+		mc.EXPECT().Modify(mod2).Return(v3, nil),
+	)
+
+	res, err := modifyWithRetry(clusterAdapter{mc}, "ck", v1, func(obj interface{}) (interface{}, error) {
+		c := obj.(api.Cluster)
+		c.Instances = api.Instances{{Host: "a", Port: 80}}
+		return c, nil
+	})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, res, v3)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+)
+
+// interceptor is consulted for each Create, Modify, and Delete call made
+// through a service wrapped by newInterceptedSvc. Each method is given a
+// typelessIface for the wrapped service, which it may use to perform the
+// operation (or not).
+type interceptor interface {
+	Create(a typelessIface, obj interface{}) (interface{}, error)
+	Modify(a typelessIface, obj interface{}) (interface{}, error)
+	Delete(a typelessIface, key string, cs api.Checksum) error
+}
+
+// newInterceptedSvc wraps the given unifiedSvc such that Create, Modify, and
+// Delete calls for each object type supported by typelessIface are passed to
+// the given interceptor. Other calls are passed through.
+func newInterceptedSvc(svc *unifiedSvc, i interceptor) *unifiedSvc {
+	return &unifiedSvc{interceptedAll{svc.All, i}, interceptedAdmin{svc.Admin, i}}
+}
+
+type interceptedAll struct {
+	service.All
+	i interceptor
+}
+
+func (s interceptedAll) Zone() service.Zone {
+	return interceptedZone{s.All.Zone(), s.i}
+}
+
+func (s interceptedAll) Cluster() service.Cluster {
+	return interceptedCluster{s.All.Cluster(), s.i}
+}
+
+func (s interceptedAll) Domain() service.Domain {
+	return interceptedDomain{s.All.Domain(), s.i}
+}
+
+func (s interceptedAll) Listener() service.Listener {
+	return interceptedListener{s.All.Listener(), s.i}
+}
+
+func (s interceptedAll) Proxy() service.Proxy {
+	return interceptedProxy{s.All.Proxy(), s.i}
+}
+
+func (s interceptedAll) Route() service.Route {
+	return interceptedRoute{s.All.Route(), s.i}
+}
+
+func (s interceptedAll) SharedRules() service.SharedRules {
+	return interceptedSharedRules{s.All.SharedRules(), s.i}
+}
+
+type interceptedAdmin struct {
+	service.Admin
+	i interceptor
+}
+
+func (s interceptedAdmin) User() service.User {
+	return interceptedUser{s.Admin.User(), s.i}
+}
+
+type interceptedZone struct {
+	service.Zone
+	i interceptor
+}
+
+func (s interceptedZone) Create(z api.Zone) (api.Zone, error) {
+	obj, err := s.i.Create(zoneAdapter{s.Zone}, z)
+	z, _ = obj.(api.Zone)
+	return z, err
+}
+
+func (s interceptedZone) Modify(z api.Zone) (api.Zone, error) {
+	obj, err := s.i.Modify(zoneAdapter{s.Zone}, z)
+	z, _ = obj.(api.Zone)
+	return z, err
+}
+
+func (s interceptedZone) Delete(k api.ZoneKey, cs api.Checksum) error {
+	return s.i.Delete(zoneAdapter{s.Zone}, string(k), cs)
+}
+
+type interceptedCluster struct {
+	service.Cluster
+	i interceptor
+}
+
+func (s interceptedCluster) Create(c api.Cluster) (api.Cluster, error) {
+	obj, err := s.i.Create(clusterAdapter{s.Cluster}, c)
+	c, _ = obj.(api.Cluster)
+	return c, err
+}
+
+func (s interceptedCluster) Modify(c api.Cluster) (api.Cluster, error) {
+	obj, err := s.i.Modify(clusterAdapter{s.Cluster}, c)
+	c, _ = obj.(api.Cluster)
+	return c, err
+}
+
+func (s interceptedCluster) Delete(k api.ClusterKey, cs api.Checksum) error {
+	return s.i.Delete(clusterAdapter{s.Cluster}, string(k), cs)
+}
+
+type interceptedDomain struct {
+	service.Domain
+	i interceptor
+}
+
+func (s interceptedDomain) Create(d api.Domain) (api.Domain, error) {
+	obj, err := s.i.Create(domainAdapter{s.Domain}, d)
+	d, _ = obj.(api.Domain)
+	return d, err
+}
+
+func (s interceptedDomain) Modify(d api.Domain) (api.Domain, error) {
+	obj, err := s.i.Modify(domainAdapter{s.Domain}, d)
+	d, _ = obj.(api.Domain)
+	return d, err
+}
+
+func (s interceptedDomain) Delete(k api.DomainKey, cs api.Checksum) error {
+	return s.i.Delete(domainAdapter{s.Domain}, string(k), cs)
+}
+
+type interceptedListener struct {
+	service.Listener
+	i interceptor
+}
+
+func (s interceptedListener) Create(l api.Listener) (api.Listener, error) {
+	obj, err := s.i.Create(listenerAdapter{s.Listener}, l)
+	l, _ = obj.(api.Listener)
+	return l, err
+}
+
+func (s interceptedListener) Modify(l api.Listener) (api.Listener, error) {
+	obj, err := s.i.Modify(listenerAdapter{s.Listener}, l)
+	l, _ = obj.(api.Listener)
+	return l, err
+}
+
+func (s interceptedListener) Delete(k api.ListenerKey, cs api.Checksum) error {
+	return s.i.Delete(listenerAdapter{s.Listener}, string(k), cs)
+}
+
+type interceptedProxy struct {
+	service.Proxy
+	i interceptor
+}
+
+func (s interceptedProxy) Create(p api.Proxy) (api.Proxy, error) {
+	obj, err := s.i.Create(proxyAdapter{s.Proxy}, p)
+	p, _ = obj.(api.Proxy)
+	return p, err
+}
+
+func (s interceptedProxy) Modify(p api.Proxy) (api.Proxy, error) {
+	obj, err := s.i.Modify(proxyAdapter{s.Proxy}, p)
+	p, _ = obj.(api.Proxy)
+	return p, err
+}
+
+func (s interceptedProxy) Delete(k api.ProxyKey, cs api.Checksum) error {
+	return s.i.Delete(proxyAdapter{s.Proxy}, string(k), cs)
+}
+
+type interceptedRoute struct {
+	service.Route
+	i interceptor
+}
+
+func (s interceptedRoute) Create(r api.Route) (api.Route, error) {
+	obj, err := s.i.Create(routeAdapter{s.Route}, r)
+	r, _ = obj.(api.Route)
+	return r, err
+}
+
+func (s interceptedRoute) Modify(r api.Route) (api.Route, error) {
+	obj, err := s.i.Modify(routeAdapter{s.Route}, r)
+	r, _ = obj.(api.Route)
+	return r, err
+}
+
+func (s interceptedRoute) Delete(k api.RouteKey, cs api.Checksum) error {
+	return s.i.Delete(routeAdapter{s.Route}, string(k), cs)
+}
+
+type interceptedSharedRules struct {
+	service.SharedRules
+	i interceptor
+}
+
+func (s interceptedSharedRules) Create(sr api.SharedRules) (api.SharedRules, error) {
+	obj, err := s.i.Create(sharedRulesAdapter{s.SharedRules}, sr)
+	sr, _ = obj.(api.SharedRules)
+	return sr, err
+}
+
+func (s interceptedSharedRules) Modify(sr api.SharedRules) (api.SharedRules, error) {
+	obj, err := s.i.Modify(sharedRulesAdapter{s.SharedRules}, sr)
+	sr, _ = obj.(api.SharedRules)
+	return sr, err
+}
+
+func (s interceptedSharedRules) Delete(k api.SharedRulesKey, cs api.Checksum) error {
+	return s.i.Delete(sharedRulesAdapter{s.SharedRules}, string(k), cs)
+}
+
+type interceptedUser struct {
+	service.User
+	i interceptor
+}
+
+func (s interceptedUser) Create(u api.User) (api.User, error) {
+	obj, err := s.i.Create(userAdapter{s.User}, u)
+	u, _ = obj.(api.User)
+	return u, err
+}
+
+func (s interceptedUser) Modify(u api.User) (api.User, error) {
+	obj, err := s.i.Modify(userAdapter{s.User}, u)
+	u, _ = obj.(api.User)
+	return u, err
+}
+
+func (s interceptedUser) Delete(k api.UserKey, cs api.Checksum) error {
+	return s.i.Delete(userAdapter{s.User}, string(k), cs)
+}
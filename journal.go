@@ -0,0 +1,147 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/nonstdlib/log/console"
+)
+
+const noRollbackDesc = `If true, objects created or modified before a failure are
+left in place. By default they are deleted or restored to their previous
+versions, in reverse order.`
+
+// journalEntry records an object created or modified through a journal.
+type journalEntry struct {
+	a        typelessIface
+	key      string
+	previous interface{} // nil if the object was created
+}
+
+func (e journalEntry) id() string {
+	return e.a.Type().Name + "/" + e.key
+}
+
+// journal is an interceptor that records objects as they are created or
+// modified, so that the changes may be rolled back if a multi-step operation
+// fails. Deletes are passed through and cannot be rolled back.
+type journal struct {
+	entries []journalEntry
+
+	// checksums holds the most recent checksum for each journaled object
+	checksums map[string]api.Checksum
+}
+
+var _ interceptor = &journal{}
+
+func newJournal() *journal {
+	return &journal{checksums: map[string]api.Checksum{}}
+}
+
+func (j *journal) Create(a typelessIface, obj interface{}) (interface{}, error) {
+	res, err := a.Create(obj)
+	if err != nil {
+		return res, err
+	}
+
+	e := journalEntry{a: a, key: a.Key(res)}
+	j.entries = append(j.entries, e)
+	j.checksums[e.id()] = a.Checksum(res)
+	return res, nil
+}
+
+func (j *journal) Modify(a typelessIface, obj interface{}) (interface{}, error) {
+	key := a.Key(obj)
+	prev, err := a.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.Modify(obj)
+	if err != nil {
+		return res, err
+	}
+
+	e := journalEntry{a: a, key: key, previous: prev}
+	j.entries = append(j.entries, e)
+	j.checksums[e.id()] = a.Checksum(res)
+	return res, nil
+}
+
+func (j *journal) Delete(a typelessIface, key string, cs api.Checksum) error {
+	return a.Delete(key, cs)
+}
+
+// rollback undoes journaled changes in reverse order: created objects are
+// deleted, and modified objects are restored to their previous versions.
+// Because objects are created in dependency order, reversing the journal
+// deletes dependents before their dependencies. A description of each change
+// undone is returned. Rollback stops at the first error.
+func (j *journal) rollback() ([]string, error) {
+	reverted := []string{}
+	for len(j.entries) > 0 {
+		e := j.entries[len(j.entries)-1]
+		cs := j.checksums[e.id()]
+
+		if e.previous == nil {
+			if err := e.a.Delete(e.key, cs); err != nil {
+				return reverted, fmt.Errorf("could not delete %s %s: %v", e.a.Type().Name, e.key, err)
+			}
+			reverted = append(reverted, fmt.Sprintf("deleted %s %s", e.a.Type().Name, e.key))
+		} else {
+			res, err := e.a.Modify(e.a.WithChecksum(e.previous, cs))
+			if err != nil {
+				return reverted, fmt.Errorf("could not restore %s %s: %v", e.a.Type().Name, e.key, err)
+			}
+			j.checksums[e.id()] = e.a.Checksum(res)
+			reverted = append(reverted, fmt.Sprintf("restored %s %s", e.a.Type().Name, e.key))
+		}
+
+		j.entries = j.entries[:len(j.entries)-1]
+	}
+
+	return reverted, nil
+}
+
+// withRollback calls f with a service that journals created and modified
+// objects. If f returns an error, the journaled changes are rolled back and
+// reported, unless noRollback is true, in which case f is called with svc.
+func withRollback(svc *unifiedSvc, noRollback bool, f func(*unifiedSvc) error) error {
+	if noRollback {
+		return f(svc)
+	}
+
+	j := newJournal()
+	err := f(newInterceptedSvc(svc, j))
+	if err == nil || len(j.entries) == 0 {
+		return err
+	}
+
+	console.Error().Printf("rolling back %d change(s) after error: %v", len(j.entries), err)
+	reverted, rerr := j.rollback()
+	for _, r := range reverted {
+		console.Error().Printf("  %s", r)
+	}
+
+	if rerr != nil {
+		return fmt.Errorf("%v; rollback incomplete: %v", err, rerr)
+	}
+
+	return err
+}
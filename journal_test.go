@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestWithRollbackRevertsInReverseOrder(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	all.EXPECT().Cluster().Return(mc).AnyTimes()
+
+	existing := api.Cluster{ClusterKey: "old", Name: "old", Checksum: api.Checksum{Checksum: "cs1"}}
+	modified := existing
+	modified.RequireTLS = true
+	modifiedResult := modified
+	modifiedResult.Checksum = api.Checksum{Checksum: "cs2"}
+
+	created := api.Cluster{ClusterKey: "new", Name: "new", Checksum: api.Checksum{Checksum: "cs3"}}
+	restored := existing
+	restored.Checksum = api.Checksum{Checksum: "cs2"}
+
+	gomock.InOrder(
+		mc.EXPECT().Create(api.Cluster{Name: "new"}).Return(created, nil),
+		mc.EXPECT().Get(api.ClusterKey("old")).Return(existing, nil),
+		mc.EXPECT().Modify(modified).Return(modifiedResult, nil),
+		mc.EXPECT().Modify(restored).Return(restored, nil),
+		mc.EXPECT().Delete(api.ClusterKey("new"), api.Checksum{Checksum: "cs3"}).Return(nil),
+	)
+
+	failure := errors.New("boom")
+	err := withRollback(&unifiedSvc{all, admin}, false, func(svc *unifiedSvc) error {
+		if _, err := svc.Cluster().Create(api.Cluster{Name: "new"}); err != nil {
+			return err
+		}
+		if _, err := svc.Cluster().Modify(modified); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, err, failure)
+}
+
+func TestWithRollbackDisabled(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	all := service.NewMockAll(ctrl)
+	admin := service.NewMockAdmin(ctrl)
+	mc := service.NewMockCluster(ctrl)
+	all.EXPECT().Cluster().Return(mc)
+
+	created := api.Cluster{ClusterKey: "new", Name: "new"}
+	mc.EXPECT().Create(api.Cluster{Name: "new"}).Return(created, nil)
+
+	failure := errors.New("boom")
+	err := withRollback(&unifiedSvc{all, admin}, true, func(svc *unifiedSvc) error {
+		if _, err := svc.Cluster().Create(api.Cluster{Name: "new"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, err, failure)
+}
@@ -0,0 +1,408 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/codec"
+)
+
+// lintProblem is a problem found in an object or zone document.
+type lintProblem struct {
+	File    string `json:"file,omitempty"`
+	Object  string `json:"object,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p lintProblem) String() string {
+	parts := []string{}
+	for _, s := range []string{p.File, p.Object, p.Field} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	parts = append(parts, p.Message)
+	return strings.Join(parts, ": ")
+}
+
+// linter accumulates problems found in objects without consulting the API.
+type linter struct {
+	file     string
+	problems []lintProblem
+}
+
+func (l *linter) addf(object, field, format string, args ...interface{}) {
+	l.problems = append(l.problems, lintProblem{l.file, object, field, fmt.Sprintf(format, args...)})
+}
+
+// lintZero returns the zero value of the given object type, or nil if the
+// type cannot be linted.
+func lintZero(ot objecttype.ObjectType) interface{} {
+	switch ot {
+	case objecttype.Zone:
+		return api.Zone{}
+	case objecttype.Proxy:
+		return api.Proxy{}
+	case objecttype.Listener:
+		return api.Listener{}
+	case objecttype.Domain:
+		return api.Domain{}
+	case objecttype.SharedRules:
+		return api.SharedRules{}
+	case objecttype.Route:
+		return api.Route{}
+	case objecttype.Cluster:
+		return api.Cluster{}
+	case objecttype.User:
+		return api.User{}
+	}
+	return nil
+}
+
+// lintText decodes txt into a value of the same type as zero, reporting
+// decoding errors and unknown fields, and returns the decoded value.
+func (l *linter) lintText(object string, cdc codec.Codec, txt string, zero interface{}) (interface{}, bool) {
+	t := reflect.TypeOf(zero)
+	ptr := reflect.New(t)
+	if t == reflect.TypeOf(zoneObjects{}) {
+		ptr = reflect.ValueOf(newZoneObjects())
+	}
+
+	if err := codec.DecodeFromString(cdc, txt, ptr.Interface()); err != nil {
+		l.addf(object, "", "could not decode: %v", err)
+		return nil, false
+	}
+
+	var generic interface{}
+	if err := codec.DecodeFromString(cdc, txt, &generic); err == nil {
+		l.unknownFields(object, "", generic, t)
+	}
+
+	return ptr.Elem().Interface(), true
+}
+
+// jsonFields returns the types of the fields of a struct type, by JSON name,
+// including fields promoted from embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		name := getAssignmentName(sf)
+		if name == "-" {
+			continue
+		}
+
+		if sf.Anonymous && sf.Tag.Get("json") == "" && sf.Type.Kind() == reflect.Struct {
+			for n, ft := range jsonFields(sf.Type) {
+				fields[n] = ft
+			}
+			continue
+		}
+
+		fields[name] = sf.Type
+	}
+	return fields
+}
+
+// unknownFields reports keys in a value decoded without a type which do not
+// correspond to fields of the given type.
+func (l *linter) unknownFields(object, path string, v interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m := genericMap(v)
+		if m == nil {
+			return
+		}
+
+		fields := jsonFields(t)
+		keys := []string{}
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if ft, ok := fields[k]; ok {
+				l.unknownFields(object, joinPath(path, k), m[k], ft)
+			} else {
+				l.addf(object, joinPath(path, k), "unknown field")
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if s, ok := v.([]interface{}); ok {
+			for i, e := range s {
+				l.unknownFields(object, fmt.Sprintf("%s[%d]", path, i), e, t.Elem())
+			}
+		}
+
+	case reflect.Map:
+		for k, e := range genericMap(v) {
+			l.unknownFields(object, joinPath(path, k), e, t.Elem())
+		}
+	}
+}
+
+// genericMap returns v as a map with string keys, or nil if it is not a map.
+func genericMap(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for k, e := range m {
+			result[fmt.Sprint(k)] = e
+		}
+		return result
+	}
+	return nil
+}
+
+func objectName(ot objecttype.ObjectType, name string) string {
+	if name == "" {
+		return ot.Name
+	}
+	return ot.Name + " " + name
+}
+
+func routeName(r api.Route) string {
+	return string(r.DomainKey) + r.Path
+}
+
+// lintObject checks an object for missing required fields and constraint
+// weights which sum to zero. If standalone is true, the object is expected to
+// refer to its Zone.
+func (l *linter) lintObject(obj interface{}, standalone bool) {
+	required := func(object, field string, missing bool) {
+		if missing {
+			l.addf(object, field, "missing required field")
+		}
+	}
+
+	switch o := obj.(type) {
+	case api.Zone:
+		object := objectName(objecttype.Zone, o.Name)
+		required(object, "name", o.Name == "")
+
+	case api.Cluster:
+		object := objectName(objecttype.Cluster, o.Name)
+		required(object, "name", o.Name == "")
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+		for i, inst := range o.Instances {
+			required(object, fmt.Sprintf("instances[%d].host", i), inst.Host == "")
+			required(object, fmt.Sprintf("instances[%d].port", i), inst.Port == 0)
+		}
+
+	case api.Domain:
+		object := objectName(objecttype.Domain, o.Addr())
+		required(object, "name", o.Name == "")
+		required(object, "port", o.Port == 0)
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+
+	case api.Proxy:
+		object := objectName(objecttype.Proxy, o.Name)
+		required(object, "name", o.Name == "")
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+
+	case api.Listener:
+		object := objectName(objecttype.Listener, o.Name)
+		required(object, "name", o.Name == "")
+		required(object, "port", o.Port == 0)
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+
+	case api.Route:
+		object := objectName(objecttype.Route, routeName(o))
+		required(object, "domain_key", o.DomainKey == "")
+		required(object, "shared_rules_key", o.SharedRulesKey == "")
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+		if !strings.HasPrefix(o.Path, "/") {
+			l.addf(object, "path", "must begin with /")
+		}
+		l.lintRules(object, o.Rules)
+
+	case api.SharedRules:
+		object := objectName(objecttype.SharedRules, o.Name)
+		required(object, "name", o.Name == "")
+		required(object, "zone_key", standalone && o.ZoneKey == "")
+		required(object, "default.light", len(o.Default.Light) == 0)
+		l.lintAllConstraints(object, "default", o.Default)
+		l.lintRules(object, o.Rules)
+
+	case api.User:
+		required(objectName(objecttype.User, o.LoginEmail), "login_email", o.LoginEmail == "")
+	}
+}
+
+func (l *linter) lintRules(object string, rs api.Rules) {
+	for i, r := range rs {
+		l.lintAllConstraints(object, fmt.Sprintf("rules[%d].constraints", i), r.Constraints)
+	}
+}
+
+func (l *linter) lintAllConstraints(object, path string, ac api.AllConstraints) {
+	for _, group := range []struct {
+		name string
+		ccs  api.ClusterConstraints
+	}{{"light", ac.Light}, {"dark", ac.Dark}, {"tap", ac.Tap}} {
+		if len(group.ccs) == 0 {
+			continue
+		}
+
+		field := joinPath(path, group.name)
+		sum := uint32(0)
+		for i, cc := range group.ccs {
+			if cc.ClusterKey == "" {
+				l.addf(object, fmt.Sprintf("%s[%d].cluster_key", field, i), "missing required field")
+			}
+			sum += cc.Weight
+		}
+
+		if sum == 0 {
+			l.addf(object, field, "constraint weights sum to zero")
+		}
+	}
+}
+
+// lintZone checks each object in a zone document, and that the references
+// between them, by name, are satisfied.
+func (l *linter) lintZone(zo *zoneObjects) {
+	l.lintObject(zo.Zone, false)
+
+	clusters := map[string]bool{}
+	for _, c := range zo.Clusters {
+		l.lintObject(c, false)
+		if clusters[c.Name] {
+			l.addf(objectName(objecttype.Cluster, c.Name), "name", "duplicate cluster")
+		}
+		clusters[c.Name] = true
+	}
+
+	domains := map[string]bool{}
+	for _, d := range zo.Domains {
+		l.lintObject(d, false)
+		if domains[d.Addr()] {
+			l.addf(objectName(objecttype.Domain, d.Addr()), "", "duplicate domain")
+		}
+		domains[d.Addr()] = true
+	}
+
+	listeners := map[string]bool{}
+	for _, lis := range zo.Listeners {
+		l.lintObject(lis, false)
+		object := objectName(objecttype.Listener, lis.Name)
+		if listeners[lis.Name] {
+			l.addf(object, "name", "duplicate listener")
+		}
+		listeners[lis.Name] = true
+		for i, dk := range lis.DomainKeys {
+			if !domains[string(dk)] {
+				l.addf(object, fmt.Sprintf("domain_keys[%d]", i), "unknown domain %q", dk)
+			}
+		}
+	}
+
+	for _, p := range zo.Proxies {
+		l.lintObject(p, false)
+		for i, dk := range p.DomainKeys {
+			if !domains[string(dk)] {
+				l.addf(
+					objectName(objecttype.Proxy, p.Name),
+					fmt.Sprintf("domain_keys[%d]", i),
+					"unknown domain %q",
+					dk,
+				)
+			}
+		}
+		for i, lk := range p.ListenerKeys {
+			if !listeners[string(lk)] {
+				l.addf(
+					objectName(objecttype.Proxy, p.Name),
+					fmt.Sprintf("listener_keys[%d]", i),
+					"unknown listener %q",
+					lk,
+				)
+			}
+		}
+	}
+
+	checkClusters := func(object, path string, ac api.AllConstraints) {
+		for _, group := range []struct {
+			name string
+			ccs  api.ClusterConstraints
+		}{{"light", ac.Light}, {"dark", ac.Dark}, {"tap", ac.Tap}} {
+			for i, cc := range group.ccs {
+				if cc.ClusterKey != "" && !clusters[string(cc.ClusterKey)] {
+					l.addf(
+						object,
+						fmt.Sprintf("%s[%d].cluster_key", joinPath(path, group.name), i),
+						"unknown cluster %q",
+						cc.ClusterKey,
+					)
+				}
+			}
+		}
+	}
+	checkRuleClusters := func(object string, rs api.Rules) {
+		for i, r := range rs {
+			checkClusters(object, fmt.Sprintf("rules[%d].constraints", i), r.Constraints)
+		}
+	}
+
+	sharedRules := map[string]bool{}
+	for _, sr := range zo.SharedRules {
+		l.lintObject(sr, false)
+		object := objectName(objecttype.SharedRules, sr.Name)
+		if sharedRules[sr.Name] {
+			l.addf(object, "name", "duplicate shared_rules")
+		}
+		sharedRules[sr.Name] = true
+		checkClusters(object, "default", sr.Default)
+		checkRuleClusters(object, sr.Rules)
+	}
+
+	routes := map[string]bool{}
+	for _, r := range zo.Routes {
+		l.lintObject(r, false)
+		object := objectName(objecttype.Route, routeName(r))
+		if r.DomainKey != "" && !domains[string(r.DomainKey)] {
+			l.addf(object, "domain_key", "unknown domain %q", r.DomainKey)
+		}
+		if r.SharedRulesKey != "" && !sharedRules[string(r.SharedRulesKey)] {
+			l.addf(object, "shared_rules_key", "unknown shared_rules %q", r.SharedRulesKey)
+		}
+		if routes[routeName(r)] {
+			l.addf(object, "", "duplicate route")
+		}
+		routes[routeName(r)] = true
+		checkRuleClusters(object, r.Rules)
+	}
+}
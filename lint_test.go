@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/test/assert"
+)
+
+func problemStrings(ps []lintProblem) []string {
+	strs := []string{}
+	for _, p := range ps {
+		strs = append(strs, p.String())
+	}
+	return strs
+}
+
+func TestLintObject(t *testing.T) {
+	txt := `{"name": "c", "zone_key": "z", "require_tls": true, "bogus": 1, "instances": [{"host": "h", "port": 0, "extra": "x"}]}`
+
+	problems := lint("c.json", "cluster", codec.NewJson(), txt, api.Cluster{})
+	assert.DeepEqual(t, problemStrings(problems), []string{
+		"c.json: cluster: bogus: unknown field",
+		"c.json: cluster: instances[0].extra: unknown field",
+		"c.json: cluster c: instances[0].port: missing required field",
+	})
+}
+
+func TestLintObjectDecodeError(t *testing.T) {
+	problems := lint("", "cluster", codec.NewJson(), `{"name":`, api.Cluster{})
+	assert.Equal(t, len(problems), 1)
+	assert.Equal(t, problems[0].Object, "cluster")
+}
+
+func TestLintZone(t *testing.T) {
+	zo := newZoneObjects()
+	zo.Zone = api.Zone{Name: "z"}
+	zo.Clusters = api.Clusters{{Name: "c"}}
+	zo.Domains = api.Domains{{Name: "d", Port: 80}}
+	zo.Proxies = api.Proxies{{Name: "p", DomainKeys: []api.DomainKey{"d:80", "e:80"}}}
+	zo.SharedRules = api.SharedRulesSlice{
+		{
+			Name: "sr",
+			Default: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 0}, {ClusterKey: "x", Weight: 0}},
+			},
+		},
+	}
+	zo.Routes = api.Routes{
+		{DomainKey: "d:80", Path: "/", SharedRulesKey: "sr"},
+		{DomainKey: "d:80", Path: "/", SharedRulesKey: "sr"},
+		{DomainKey: "e:80", Path: "api", SharedRulesKey: "nope"},
+	}
+
+	txt, err := codec.EncodeToString(codec.NewJson(), zo)
+	assert.Nil(t, err)
+
+	problems := lint("", "document", codec.NewJson(), txt, zoneObjects{})
+	assert.DeepEqual(t, problemStrings(problems), []string{
+		`proxy p: domain_keys[1]: unknown domain "e:80"`,
+		`shared_rules sr: default.light: constraint weights sum to zero`,
+		`shared_rules sr: default.light[1].cluster_key: unknown cluster "x"`,
+		`route d:80/: duplicate route`,
+		`route e:80api: path: must begin with /`,
+		`route e:80api: domain_key: unknown domain "e:80"`,
+		`route e:80api: shared_rules_key: unknown shared_rules "nope"`,
+	})
+}
+
+func TestLintZoneListeners(t *testing.T) {
+	zo := newZoneObjects()
+	zo.Zone = api.Zone{Name: "z"}
+	zo.Domains = api.Domains{{Name: "d", Port: 80}}
+	zo.Listeners = api.Listeners{
+		{Name: "l", Port: 80, DomainKeys: []api.DomainKey{"d:80", "e:80"}},
+		{Name: "l", Port: 80},
+	}
+	zo.Proxies = api.Proxies{{Name: "p", ListenerKeys: []api.ListenerKey{"l", "m"}}}
+
+	txt, err := codec.EncodeToString(codec.NewJson(), zo)
+	assert.Nil(t, err)
+
+	problems := lint("", "document", codec.NewJson(), txt, zoneObjects{})
+	assert.DeepEqual(t, problemStrings(problems), []string{
+		`listener l: domain_keys[1]: unknown domain "e:80"`,
+		`listener l: name: duplicate listener`,
+		`proxy p: listener_keys[1]: unknown listener "m"`,
+	})
+}
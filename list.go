@@ -49,6 +49,9 @@ var headerStrings = map[string]map[string]string{
 	objecttype.Cluster.Name: {
 		"summary": "Cluster Key\tInstances\tZone\tName",
 	},
+	objecttype.Listener.Name: {
+		"summary": "Listener Key\tIP:Port\tProtocol\tDomains\tZone\tName",
+	},
 	objecttype.SharedRules.Name: {
 		"summary": "SharedRulesKey\tZoneKey\tName",
 	},
@@ -65,6 +68,9 @@ var formatStrings = map[string]map[string]string{
 	objecttype.Cluster.Name: {
 		"summary": "{{.ClusterKey}}\t{{len .Instances}}\t" + zoneName + "\t{{.Name}}",
 	},
+	objecttype.Listener.Name: {
+		"summary": "{{.ListenerKey}}\t{{.IP}}:{{.Port}}\t{{.Protocol}}\t{{len .DomainKeys}}\t" + zoneName + "\t{{.Name}}",
+	},
 	objecttype.SharedRules.Name: {
 		"summary": "{{.SharedRulesKey}}\t" + zoneName + "\t{{.Name}}",
 	},
@@ -178,7 +184,10 @@ func (gc *listRunner) run(cmd *command.Cmd, args []string) error {
 		return nil
 	}
 
-	objs, err := svc.FilteredIndex(gc.cfg.sliceSep, argsToAttrs(args))
+	attrs := argsToAttrs(args)
+	gc.cfg.scopeFilterAttrs(svc.IndexZeroFilter(), attrs)
+
+	objs, err := svc.FilteredIndex(gc.cfg.sliceSep, attrs)
 	if err != nil {
 		return err
 	}
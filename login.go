@@ -33,9 +33,13 @@ import (
 	"github.com/turbinelabs/nonstdlib/log/console"
 )
 
-// TokenCachePath returns the path that the auth token should be cached at.
-func TokenCachePath() string {
-	return filepath.Join(os.Getenv("HOME"), ".tbnctl-auth-cache")
+// TokenCachePath returns the path that the auth token for the named context
+// should be cached at. If no context is active, the name is empty.
+func TokenCachePath(contextName string) string {
+	if contextName == "" {
+		return filepath.Join(os.Getenv("HOME"), ".tbnctl-auth-cache")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".tbnctl-auth-cache."+contextName)
 }
 
 func populateDefaults(tc tokencache.TokenCache) tokencache.TokenCache {
@@ -57,7 +61,7 @@ type loginRunner struct {
 	cfg *loginCfg
 }
 
-func login(tc tokencache.TokenCache, password string) error {
+func login(tc tokencache.TokenCache, password, path string) error {
 	cfg, err := tokencache.ToOAuthConfig(tc)
 	if err != nil {
 		return err
@@ -70,7 +74,7 @@ func login(tc tokencache.TokenCache, password string) error {
 	}
 
 	tc.SetToken(tokencache.WrapOAuth2Token(tkn))
-	if err := tc.Save(TokenCachePath()); err != nil {
+	if err := tc.Save(path); err != nil {
 		return fmt.Errorf("unable to save new token: %v", err)
 	}
 
@@ -96,15 +100,20 @@ func readline() (string, error) {
 }
 
 func (gc *loginRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
-	tokenCache, err := tokencache.NewFromFile(TokenCachePath())
+	path := TokenCachePath(gc.cfg.contextName)
+	tokenCache, err := tokencache.NewFromFile(path)
 	if err != nil {
 		return cmd.Errorf(
 			"Unable to process token cache (%v): %v",
-			TokenCachePath(),
+			path,
 			err,
 		)
 	}
 
+	if tokenCache.Username == "" {
+		tokenCache.Username = gc.cfg.context[contextUsername]
+	}
+
 	if gc.cfg.user != "" {
 		tokenCache.Username = gc.cfg.user
 	} else {
@@ -139,7 +148,7 @@ func (gc *loginRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 		return cmd.Error("password must not be empty")
 	}
 
-	err = login(populateDefaults(tokenCache), password)
+	err = login(populateDefaults(tokenCache), password, path)
 	if err != nil {
 		return gc.cfg.PrettyCmdErr(cmd, err)
 	}
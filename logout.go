@@ -22,20 +22,23 @@ import (
 	"github.com/turbinelabs/nonstdlib/log/console"
 )
 
-type logoutRunner struct{}
+type logoutRunner struct {
+	cfg *globalConfigT
+}
 
 func (gc *logoutRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
-	tokenCache, err := tokencache.NewFromFile(TokenCachePath())
+	path := TokenCachePath(gc.cfg.contextName)
+	tokenCache, err := tokencache.NewFromFile(path)
 	if err != nil {
 		return cmd.Errorf(
 			"Unable to process token cache (%v): %v",
-			TokenCachePath(),
+			path,
 			err,
 		)
 	}
 
 	tokenCache.SetToken(nil)
-	if err := tokenCache.Save(TokenCachePath()); err != nil {
+	if err := tokenCache.Save(path); err != nil {
 		return cmd.Errorf("Unable to invalidate cached auth token")
 	}
 
@@ -45,7 +48,7 @@ func (gc *logoutRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
 }
 
 func cmdLogout(cfg globalConfigT) *command.Cmd {
-	runner := &logoutRunner{}
+	runner := &logoutRunner{&cfg}
 
 	cmd := &command.Cmd{
 		Name:    "logout",
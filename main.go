@@ -18,7 +18,10 @@ package main
 
 import (
 	goflag "flag"
+	"fmt"
+	"os"
 
+	"github.com/turbinelabs/api"
 	apiclient "github.com/turbinelabs/api/client"
 	apiflag "github.com/turbinelabs/api/client/flags"
 	"github.com/turbinelabs/api/client/tokencache"
@@ -42,9 +45,19 @@ var cmds = []func(globalConfigT) *command.Cmd{
 	cmdInitZone,
 	cmdExportZone,
 	cmdImportZone,
+	cmdCloneZone,
+	cmdApply,
+	cmdDiffZone,
+	cmdSnapshot,
+	cmdRender,
+	cmdServeFake,
+	cmdRelease,
+	cmdInstances,
+	cmdValidate,
 	cmdTokens,
 	cmdLogin,
 	cmdLogout,
+	cmdContext,
 }
 
 type globalConfigT struct {
@@ -52,12 +65,32 @@ type globalConfigT struct {
 	apiClient  *unifiedSvc
 	codecFlags codec.FromFlags
 	codec      codec.Codec
+
+	// dryRun is shared by all commands, which receive copies of the
+	// globalConfigT before flags are parsed.
+	dryRun *bool
+
+	// contextName is the name of the active context, if any, and context
+	// holds its settings. contextErr records a failure to load or apply the
+	// context, which is reported when the API is used.
+	contextName string
+	context     tbnctlContext
+	contextErr  error
+
+	// zone is the value of the global --zone flag, shared like dryRun.
+	// zoneKey is the key of the Zone it names, resolved by Prepare.
+	zone    *string
+	zoneKey api.ZoneKey
 }
 
 // Prepare handles getting everything validated, instantiated, and set on the
 // globalConfigT. It returns an error if any configuration is invalid or fails
 // to produce the expected component.
 func (gc *globalConfigT) Prepare(cmd *command.Cmd) command.CmdErr {
+	if gc.contextErr != nil {
+		return cmd.BadInput(gc.contextErr)
+	}
+
 	if err := gc.Validate(); err != nil {
 		return cmd.BadInput(err)
 	}
@@ -66,9 +99,29 @@ func (gc *globalConfigT) Prepare(cmd *command.Cmd) command.CmdErr {
 		return cmd.Error(err)
 	}
 
+	if err := gc.resolveZone(); err != nil {
+		return cmd.Error(err)
+	}
+
 	return command.NoError()
 }
 
+// prepareCodec validates and instantiates only the codec, for commands which
+// do not use the API.
+func (gc *globalConfigT) prepareCodec(cmd *command.Cmd) command.CmdErr {
+	if err := gc.codecFlags.Validate(); err != nil {
+		return cmd.BadInput(err)
+	}
+	gc.codec = gc.codecFlags.Make()
+
+	return command.NoError()
+}
+
+// dryRunEnabled returns true if the global --dry-run flag is set.
+func (gc *globalConfigT) dryRunEnabled() bool {
+	return gc.dryRun != nil && *gc.dryRun
+}
+
 // Validate calls Validate on the nested flag-configured components and returns
 // an error if any of them fail to validate.
 func (gc globalConfigT) Validate() error {
@@ -99,13 +152,20 @@ func (gc *globalConfigT) Make() error {
 	gc.apiClient = &unifiedSvc{svc, svca}
 	gc.codec = gc.codecFlags.Make()
 
+	if gc.dryRunEnabled() {
+		gc.apiClient = newDryRunSvc(gc.apiClient, &dryRunRecorder{codec: gc.codec, out: os.Stdout})
+	}
+
 	return nil
 }
 
 func main() {
 	globalConfig := globalConfigT{}
+	globalConfig.contextName, globalConfig.context, globalConfig.contextErr =
+		loadContext(os.Args[1:])
 
-	gflags := tbnflag.Wrap(&goflag.FlagSet{})
+	fs := &goflag.FlagSet{}
+	gflags := tbnflag.Wrap(fs)
 	apiFlags := gflags.Scope("api", "API")
 	globalConfig.apiFlags = apiflag.NewClientFromFlagsWithSharedAPIConfig(
 		clientApp,
@@ -113,14 +173,36 @@ func main() {
 		apiflag.NewAPIConfigFromFlags(
 			apiFlags,
 			apiflag.APIConfigMayUseAuthToken(
-				tokencache.NewStaticPath(TokenCachePath()),
+				tokencache.NewStaticPath(TokenCachePath(globalConfig.contextName)),
 			),
 		),
 	)
 	globalConfig.codecFlags = codec.NewFromFlags(gflags)
 
+	globalConfig.dryRun = new(bool)
+	gflags.BoolVar(
+		globalConfig.dryRun,
+		"dry-run",
+		false,
+		"If true, print the create, modify, and delete operations that would be performed against the API instead of performing them.",
+	)
+
+	globalConfig.zone = new(string)
+	gflags.StringVar(globalConfig.zone, "zone", "", zoneFlagDesc)
+
+	gflags.StringVar(
+		new(string),
+		contextFlag,
+		"",
+		"The name of the context whose settings are used as defaults for other flags. See tbnctl help context.",
+	)
+
 	console.Init(gflags)
 
+	if err := globalConfig.context.applyTo(fs); err != nil {
+		globalConfig.contextErr = fmt.Errorf("context %s: %s", globalConfig.contextName, err)
+	}
+
 	subs := []*command.Cmd{}
 	for _, mkCmd := range cmds {
 		subs = append(subs, mkCmd(globalConfig))
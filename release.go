@@ -0,0 +1,334 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/cli/command"
+)
+
+const (
+	releaseStart  = "start"
+	releaseStep   = "step"
+	releaseFinish = "finish"
+	releaseAbort  = "abort"
+	releaseStatus = "status"
+	releaseAuto   = "auto"
+)
+
+const releaseDesc = `Release a new version of a Cluster by incrementally shifting
+traffic to it in the Default Light ClusterConstraints of a SharedRules.
+Versions are identified by constraint metadata. The action is one of:
+
+    start   begin a release, shifting traffic to the first step. Requires
+            --from and --to.
+    step    shift traffic to the next step.
+    finish  replace the "from" constraint with the "to" constraint.
+    abort   restore the Default Light constraints as they were before the
+            release started.
+    status  show the current split.
+    auto    step through the release automatically, waiting --interval
+            between steps, and finish it. Before each step, the gate given by
+            --gate-exec or --gate-url is checked; if it fails, the release is
+            aborted. If no release is in progress, one is started, as with
+            start. An interrupted auto release may be resumed by running auto
+            again.
+
+For example:
+
+    tbnctl release start api-rules --from version=1 --to version=2
+    tbnctl release step api-rules
+    tbnctl release finish api-rules
+
+The "to" constraint has the metadata of the "from" constraint, with the values
+given by --to replacing or adding to it. While a release is in progress, the
+weights of other Light constraints are scaled so that their share of traffic
+is unchanged.
+
+Progress is recorded in the SharedRules properties, so a release may be
+continued by later invocations. Each change is made against the checksum of
+the SharedRules as read, so concurrent modifications cause the action to fail
+rather than be overwritten. The current split is printed after each action.`
+
+func cmdRelease(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "release",
+		Summary:     "shift SharedRules traffic between versions of a Cluster",
+		Usage:       "[OPTIONS] <start|step|finish|abort|status|auto> <shared-rules>",
+		Description: releaseDesc,
+	}
+
+	r := &releaseRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.from,
+		"from",
+		"",
+		"Metadata, of the form key=value[,key=value]*, identifying the constraint traffic is shifted from. Required by start.",
+	)
+
+	cmd.Flags.StringVar(
+		&r.to,
+		"to",
+		"",
+		"Metadata, of the form key=value[,key=value]*, identifying the constraint traffic is shifted to. Required by start.",
+	)
+
+	cmd.Flags.StringVar(
+		&r.cluster,
+		"cluster",
+		"",
+		"The name or key of the Cluster being released, if --from matches constraints for more than one Cluster.",
+	)
+
+	cmd.Flags.StringVar(
+		&r.steps,
+		"steps",
+		formatReleaseSteps(defaultReleaseSteps),
+		"A comma-delimited list of increasing percentages of traffic to shift at each step. Used by start.",
+	)
+
+	cmd.Flags.DurationVar(
+		&r.interval,
+		"interval",
+		5*time.Minute,
+		"The time to wait between steps. Used by auto.",
+	)
+
+	cmd.Flags.StringVar(
+		&r.gateExec,
+		"gate-exec",
+		"",
+		"A command, run with sh -c before each step, which must exit successfully for the release to continue. Used by auto.",
+	)
+
+	cmd.Flags.StringVar(
+		&r.gateURL,
+		"gate-url",
+		"",
+		"A URL, fetched before each step, which must return a 2xx status for the release to continue. Used by auto.",
+	)
+
+	cmd.Flags.DurationVar(
+		&r.gateTimeout,
+		"gate-timeout",
+		30*time.Second,
+		"The timeout for fetching --gate-url.",
+	)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type releaseRunner struct {
+	cfg     globalConfigT
+	from    string
+	to      string
+	cluster string
+	steps   string
+
+	interval    time.Duration
+	gateExec    string
+	gateURL     string
+	gateTimeout time.Duration
+}
+
+// releaseSplit describes the traffic split of a SharedRules.
+type releaseSplit struct {
+	SharedRules string                 `json:"shared_rules"`
+	InProgress  bool                   `json:"in_progress"`
+	From        string                 `json:"from,omitempty"`
+	To          string                 `json:"to,omitempty"`
+	Percent     int                    `json:"percent,omitempty"`
+	Steps       []int                  `json:"steps,omitempty"`
+	Light       api.ClusterConstraints `json:"light"`
+}
+
+func newReleaseSplit(sr api.SharedRules, r *release) releaseSplit {
+	split := releaseSplit{SharedRules: sr.Name, Light: sr.Default.Light}
+	if r != nil {
+		split.InProgress = true
+		split.From = formatMetadataSelector(r.From)
+		split.To = formatMetadataSelector(r.To)
+		split.Percent = r.Percent
+		split.Steps = r.Steps
+	}
+	return split
+}
+
+func (r *releaseRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *releaseRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) != 2 {
+		return cmd.BadInput("requires exactly two arguments")
+	}
+	action, name := args[0], args[1]
+
+	svc := r.cfg.apiClient
+
+	key, err := r.cfg.resolveKeyOrName(sharedRulesAdapter{svc.SharedRules()}, name)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	if action == releaseAuto {
+		return r.auto(cmd, svc, api.SharedRulesKey(key))
+	}
+
+	sr, err := svc.SharedRules().Get(api.SharedRulesKey(key))
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	rel, err := readRelease(sr)
+	if err != nil {
+		return cmd.Error(err)
+	}
+
+	if action != releaseStart && action != releaseStatus && rel == nil {
+		return cmd.BadInputf("no release is in progress for %s", sr.Name)
+	}
+
+	switch action {
+	case releaseStart:
+		if rel, err = r.start(svc, &sr); err != nil {
+			return cmd.BadInput(err)
+		}
+
+	case releaseStep:
+		if err := rel.verify(sr); err != nil {
+			return cmd.Errorf("%s; use abort to restore the original constraints", err)
+		}
+		next, ok := rel.nextStep()
+		if !ok {
+			return cmd.BadInputf("release is at %d%%; use finish to complete it", rel.Percent)
+		}
+		if err := rel.apply(&sr, next); err != nil {
+			return cmd.Error(err)
+		}
+
+	case releaseFinish:
+		if err := rel.verify(sr); err != nil {
+			return cmd.Errorf("%s; use abort to restore the original constraints", err)
+		}
+		rel.finish(&sr)
+		rel = nil
+
+	case releaseAbort:
+		rel.abort(&sr)
+		rel = nil
+
+	case releaseStatus:
+		r.cfg.PrintResult(newReleaseSplit(sr, rel))
+		return command.NoError()
+
+	default:
+		return cmd.BadInputf("unknown release action %q", action)
+	}
+
+	sr, err = svc.SharedRules().Modify(sr)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	r.cfg.PrintResult(newReleaseSplit(sr, rel))
+
+	return command.NoError()
+}
+
+func (r *releaseRunner) auto(cmd *command.Cmd, svc *unifiedSvc, key api.SharedRulesKey) command.CmdErr {
+	var gate releaseGate
+	switch {
+	case r.gateExec != "" && r.gateURL != "":
+		return cmd.BadInput("only one of --gate-exec and --gate-url may be given")
+	case r.gateExec != "":
+		gate = execGate{r.gateExec}
+	case r.gateURL != "":
+		gate = httpGate{r.gateURL, &http.Client{Timeout: r.gateTimeout}}
+	default:
+		return cmd.BadInput("one of --gate-exec or --gate-url is required")
+	}
+
+	var start func(*api.SharedRules) (*release, error)
+	if r.from != "" || r.to != "" {
+		start = func(sr *api.SharedRules) (*release, error) {
+			return r.start(svc, sr)
+		}
+	}
+
+	a := &autoRelease{
+		svc:      svc,
+		gate:     gate,
+		interval: r.interval,
+		sleep:    time.Sleep,
+		report: func(sr api.SharedRules, rel *release) {
+			r.cfg.PrintResult(newReleaseSplit(sr, rel))
+		},
+	}
+
+	if err := a.run(key, start); err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
+
+func (r *releaseRunner) start(svc *unifiedSvc, sr *api.SharedRules) (*release, error) {
+	if r.from == "" || r.to == "" {
+		return nil, fmt.Errorf("--from and --to are required")
+	}
+
+	from, err := parseMetadataSelector(r.from)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseMetadataSelector(r.to)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := parseReleaseSteps(r.steps)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterKey api.ClusterKey
+	if r.cluster != "" {
+		cs, err := svc.Cluster().Index(service.ClusterFilter{Name: r.cluster, ZoneKey: sr.ZoneKey})
+		if err != nil {
+			return nil, err
+		}
+		clusterKey = api.ClusterKey(r.cluster)
+		if len(cs) == 1 {
+			clusterKey = cs[0].ClusterKey
+		}
+	}
+
+	return startRelease(sr, clusterKey, from, to, steps)
+}
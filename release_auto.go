@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+)
+
+// releaseGate is checked before each step of an automated release. A non-nil
+// error causes the release to be reverted.
+type releaseGate interface {
+	Check() error
+}
+
+// execGate passes if the command, run with "sh -c", exits successfully.
+type execGate struct {
+	command string
+}
+
+func (g execGate) Check() error {
+	out, err := exec.Command("sh", "-c", g.command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%q failed: %v: %s", g.command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// httpGate passes if a GET of the URL returns a 2xx status.
+type httpGate struct {
+	url    string
+	client *http.Client
+}
+
+func (g httpGate) Check() error {
+	resp, err := g.client.Get(g.url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("GET %s returned %s", g.url, resp.Status)
+	}
+	return nil
+}
+
+// autoRelease steps a release through each of its steps, waiting interval
+// between steps and checking gate before each. If the gate fails, the
+// original Default Light constraints are restored. All state is kept in the
+// SharedRules Properties, so an interrupted autoRelease may be resumed by
+// running another.
+type autoRelease struct {
+	svc      service.All
+	gate     releaseGate
+	interval time.Duration
+	sleep    func(time.Duration)
+
+	// report is called with the SharedRules after each change, and the
+	// release in progress, if any.
+	report func(api.SharedRules, *release)
+}
+
+// run performs the release of the SharedRules with the given key. If no
+// release is in progress, one is begun by calling start, which may be nil if a
+// release is required to be in progress.
+func (a *autoRelease) run(key api.SharedRulesKey, start func(*api.SharedRules) (*release, error)) error {
+	sr, err := a.svc.SharedRules().Get(key)
+	if err != nil {
+		return err
+	}
+
+	rel, err := readRelease(sr)
+	if err != nil {
+		return err
+	}
+
+	if rel == nil {
+		if start == nil {
+			return fmt.Errorf("no release is in progress for %s", sr.Name)
+		}
+
+		if err := a.gate.Check(); err != nil {
+			return fmt.Errorf("gate failed, release not started: %v", err)
+		}
+
+		if rel, err = start(&sr); err != nil {
+			return err
+		}
+
+		if sr, err = a.svc.SharedRules().Modify(sr); err != nil {
+			return err
+		}
+		a.report(sr, rel)
+	}
+
+	for {
+		a.sleep(a.interval)
+
+		sr, err = a.svc.SharedRules().Get(sr.SharedRulesKey)
+		if err != nil {
+			return err
+		}
+
+		if rel, err = readRelease(sr); err != nil {
+			return err
+		} else if rel == nil {
+			return fmt.Errorf("release of %s was finished or aborted elsewhere", sr.Name)
+		}
+
+		if err := rel.verify(sr); err != nil {
+			return err
+		}
+
+		if gerr := a.gate.Check(); gerr != nil {
+			rel.abort(&sr)
+			if sr, err = a.svc.SharedRules().Modify(sr); err != nil {
+				return fmt.Errorf("gate failed: %v; could not revert: %v", gerr, err)
+			}
+			a.report(sr, nil)
+			return fmt.Errorf("gate failed, reverted to original constraints: %v", gerr)
+		}
+
+		next, ok := rel.nextStep()
+		if ok {
+			if err := rel.apply(&sr, next); err != nil {
+				return err
+			}
+		} else {
+			rel.finish(&sr)
+			rel = nil
+		}
+
+		if sr, err = a.svc.SharedRules().Modify(sr); err != nil {
+			return err
+		}
+		a.report(sr, rel)
+
+		if rel == nil {
+			return nil
+		}
+	}
+}
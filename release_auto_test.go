@@ -0,0 +1,158 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+type releaseGateFunc func() error
+
+func (f releaseGateFunc) Check() error { return f() }
+
+// fakeSharedRulesAll is a service.All holding a single SharedRules.
+type fakeSharedRulesAll struct {
+	service.All
+	*fakeSharedRules
+}
+
+func (f fakeSharedRulesAll) SharedRules() service.SharedRules { return f.fakeSharedRules }
+
+// fakeSharedRules is a service.SharedRules holding a single SharedRules,
+// which enforces checksums on Modify.
+type fakeSharedRules struct {
+	service.SharedRules
+
+	sr       api.SharedRules
+	modifies int
+}
+
+func newFakeSharedRulesAll(sr api.SharedRules) fakeSharedRulesAll {
+	sr.SharedRulesKey = "srk"
+	sr.Checksum = api.Checksum{Checksum: "0"}
+	return fakeSharedRulesAll{fakeSharedRules: &fakeSharedRules{sr: sr}}
+}
+
+func (f *fakeSharedRules) Index(filters ...service.SharedRulesFilter) (api.SharedRulesSlice, error) {
+	return api.SharedRulesSlice{f.sr}, nil
+}
+
+func (f *fakeSharedRules) Get(k api.SharedRulesKey) (api.SharedRules, error) {
+	if k != f.sr.SharedRulesKey {
+		return api.SharedRules{}, fmt.Errorf("no such key %q", k)
+	}
+	return f.sr, nil
+}
+
+func (f *fakeSharedRules) Modify(sr api.SharedRules) (api.SharedRules, error) {
+	if sr.Checksum != f.sr.Checksum {
+		return api.SharedRules{}, errors.New("checksum mismatch")
+	}
+	f.modifies++
+	sr.Checksum = api.Checksum{Checksum: fmt.Sprintf("%d", f.modifies)}
+	f.sr = sr
+	return sr, nil
+}
+
+func mkAutoRelease(svc service.All, gate releaseGate) (*autoRelease, *[]int) {
+	percents := &[]int{}
+	return &autoRelease{
+		svc:      svc,
+		gate:     gate,
+		interval: time.Minute,
+		sleep:    func(time.Duration) {},
+		report: func(_ api.SharedRules, r *release) {
+			if r == nil {
+				*percents = append(*percents, 0)
+			} else {
+				*percents = append(*percents, r.Percent)
+			}
+		},
+	}, percents
+}
+
+func startAutoRelease(sr *api.SharedRules) (*release, error) {
+	return startRelease(
+		sr,
+		"",
+		api.Metadata{{Key: "version", Value: "1"}},
+		api.Metadata{{Key: "version", Value: "2"}},
+		[]int{10, 50, 100},
+	)
+}
+
+func TestAutoReleaseCompletes(t *testing.T) {
+	svc := newFakeSharedRulesAll(mkReleaseSharedRules())
+	a, percents := mkAutoRelease(svc, releaseGateFunc(func() error { return nil }))
+
+	assert.Nil(t, a.run("srk", startAutoRelease))
+	assert.DeepEqual(t, *percents, []int{10, 50, 100, 0})
+
+	rel, err := readRelease(svc.sr)
+	assert.Nil(t, err)
+	assert.Nil(t, rel)
+	assert.Equal(t, len(svc.sr.Default.Light), 2)
+	assert.Equal(t, svc.sr.Default.Light[0].Metadata[0].Value, "2")
+	assert.Equal(t, svc.sr.Default.Light[0].Weight, uint32(3))
+}
+
+func TestAutoReleaseRevertsOnGateFailure(t *testing.T) {
+	svc := newFakeSharedRulesAll(mkReleaseSharedRules())
+	checks := 0
+	a, percents := mkAutoRelease(svc, releaseGateFunc(func() error {
+		checks++
+		if checks == 3 {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}))
+
+	err := a.run("srk", startAutoRelease)
+	assert.ErrorContains(t, err, "unhealthy")
+	assert.DeepEqual(t, *percents, []int{10, 50, 0})
+
+	want := mkReleaseSharedRules()
+	assert.DeepEqual(t, svc.sr.Default, want.Default)
+	assert.DeepEqual(t, svc.sr.Properties, want.Properties)
+}
+
+func TestAutoReleaseResumes(t *testing.T) {
+	sr := mkReleaseSharedRules()
+	_, err := startAutoRelease(&sr)
+	assert.Nil(t, err)
+
+	svc := newFakeSharedRulesAll(sr)
+	a, percents := mkAutoRelease(svc, releaseGateFunc(func() error { return nil }))
+
+	assert.Nil(t, a.run("srk", nil))
+	assert.DeepEqual(t, *percents, []int{50, 100, 0})
+}
+
+func TestAutoReleaseRequiresRelease(t *testing.T) {
+	svc := newFakeSharedRulesAll(mkReleaseSharedRules())
+	a, _ := mkAutoRelease(svc, releaseGateFunc(func() error { return nil }))
+
+	assert.ErrorContains(t, a.run("srk", nil), "no release is in progress")
+	assert.Equal(t, svc.modifies, 0)
+}
@@ -0,0 +1,383 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	tbnstrings "github.com/turbinelabs/nonstdlib/strings"
+)
+
+// SharedRules Properties used to record the progress of a release.
+const (
+	releasePropPrefix   = "tbnctl.release."
+	releasePropCluster  = releasePropPrefix + "cluster"
+	releasePropFrom     = releasePropPrefix + "from"
+	releasePropTo       = releasePropPrefix + "to"
+	releasePropSteps    = releasePropPrefix + "steps"
+	releasePropPercent  = releasePropPrefix + "percent"
+	releasePropOriginal = releasePropPrefix + "original"
+)
+
+var defaultReleaseSteps = []int{1, 10, 50, 100}
+
+// release is a shift of traffic from one version of a Cluster to another,
+// within the Default Light ClusterConstraints of a SharedRules. Versions are
+// identified by constraint Metadata. While a release is in progress, the
+// weight of the original "from" constraint is split between it and a "to"
+// constraint according to Percent, and the weights of any other Light
+// constraints are scaled to preserve their share of traffic.
+type release struct {
+	ClusterKey api.ClusterKey
+	From       api.Metadata
+	To         api.Metadata
+	Steps      []int
+	Percent    int
+
+	// Original holds the Default Light constraints before the release started.
+	Original api.ClusterConstraints
+}
+
+// parseMetadataSelector parses a comma-delimited list of key=value pairs.
+func parseMetadataSelector(str string) (api.Metadata, error) {
+	md := api.Metadata{}
+	for _, pair := range strings.Split(str, ",") {
+		k, v := tbnstrings.SplitFirstEqual(pair)
+		k = strings.TrimSpace(k)
+		if k == "" || v == "" {
+			return nil, fmt.Errorf("malformed metadata %q: must be of the form key=value[,key=value]*", str)
+		}
+		md = append(md, api.Metadatum{Key: k, Value: strings.TrimSpace(v)})
+	}
+	return md, nil
+}
+
+func formatMetadataSelector(md api.Metadata) string {
+	strs := make([]string, len(md))
+	for i, m := range md {
+		strs[i] = m.Key + "=" + m.Value
+	}
+	return strings.Join(strs, ",")
+}
+
+// parseReleaseSteps parses a comma-delimited list of increasing percentages.
+// If the last step is not 100, 100 is appended.
+func parseReleaseSteps(str string) ([]int, error) {
+	steps := []int{}
+	for _, s := range strings.Split(str, ",") {
+		s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+		p, err := strconv.Atoi(s)
+		if err != nil || p < 1 || p > 100 {
+			return nil, fmt.Errorf("malformed step %q: must be a percentage between 1 and 100", s)
+		}
+		if len(steps) > 0 && p <= steps[len(steps)-1] {
+			return nil, fmt.Errorf("steps must be increasing: %q", str)
+		}
+		steps = append(steps, p)
+	}
+
+	if steps[len(steps)-1] != 100 {
+		steps = append(steps, 100)
+	}
+
+	return steps, nil
+}
+
+func formatReleaseSteps(steps []int) string {
+	strs := make([]string, len(steps))
+	for i, s := range steps {
+		strs[i] = strconv.Itoa(s)
+	}
+	return strings.Join(strs, ",")
+}
+
+// hasMetadata returns true if md contains every key/value pair in sel.
+func hasMetadata(md, sel api.Metadata) bool {
+	for _, s := range sel {
+		found := false
+		for _, m := range md {
+			if m.Key == s.Key && m.Value == s.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// withMetadata returns a copy of md with the key/value pairs in overrides set.
+func withMetadata(md, overrides api.Metadata) api.Metadata {
+	result := append(api.Metadata{}, md...)
+	for _, o := range overrides {
+		found := false
+		for i := range result {
+			if result[i].Key == o.Key {
+				result[i].Value = o.Value
+				found = true
+			}
+		}
+		if !found {
+			result = append(result, o)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// readRelease returns the release recorded in the SharedRules Properties, or
+// nil if no release is in progress.
+func readRelease(sr api.SharedRules) (*release, error) {
+	props := map[string]string{}
+	for _, p := range sr.Properties {
+		if strings.HasPrefix(p.Key, releasePropPrefix) {
+			props[p.Key] = p.Value
+		}
+	}
+
+	if len(props) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	r := &release{ClusterKey: api.ClusterKey(props[releasePropCluster])}
+	malformed := func(prop string, err error) error {
+		return fmt.Errorf("malformed release property %s: %v", prop, err)
+	}
+
+	if r.From, err = parseMetadataSelector(props[releasePropFrom]); err != nil {
+		return nil, malformed(releasePropFrom, err)
+	}
+	if r.To, err = parseMetadataSelector(props[releasePropTo]); err != nil {
+		return nil, malformed(releasePropTo, err)
+	}
+	if r.Steps, err = parseReleaseSteps(props[releasePropSteps]); err != nil {
+		return nil, malformed(releasePropSteps, err)
+	}
+	if r.Percent, err = strconv.Atoi(props[releasePropPercent]); err != nil {
+		return nil, malformed(releasePropPercent, err)
+	}
+	if err = json.Unmarshal([]byte(props[releasePropOriginal]), &r.Original); err != nil {
+		return nil, malformed(releasePropOriginal, err)
+	}
+
+	return r, nil
+}
+
+// write records the release in the SharedRules Properties, replacing any
+// previously recorded release.
+func (r *release) write(sr *api.SharedRules) error {
+	original, err := json.Marshal(r.Original)
+	if err != nil {
+		return err
+	}
+
+	clearRelease(sr)
+	sr.Properties = append(
+		sr.Properties,
+		api.Metadatum{Key: releasePropCluster, Value: string(r.ClusterKey)},
+		api.Metadatum{Key: releasePropFrom, Value: formatMetadataSelector(r.From)},
+		api.Metadatum{Key: releasePropTo, Value: formatMetadataSelector(r.To)},
+		api.Metadatum{Key: releasePropSteps, Value: formatReleaseSteps(r.Steps)},
+		api.Metadatum{Key: releasePropPercent, Value: strconv.Itoa(r.Percent)},
+		api.Metadatum{Key: releasePropOriginal, Value: string(original)},
+	)
+	return nil
+}
+
+// clearRelease removes any release recorded in the SharedRules Properties.
+func clearRelease(sr *api.SharedRules) {
+	props := api.Metadata{}
+	for _, p := range sr.Properties {
+		if !strings.HasPrefix(p.Key, releasePropPrefix) {
+			props = append(props, p)
+		}
+	}
+	sr.Properties = props
+}
+
+func (r *release) isFrom(cc api.ClusterConstraint) bool {
+	return cc.ClusterKey == r.ClusterKey && hasMetadata(cc.Metadata, r.From)
+}
+
+func (r *release) isTo(cc api.ClusterConstraint) bool {
+	return cc.ClusterKey == r.ClusterKey && hasMetadata(cc.Metadata, r.To)
+}
+
+// startRelease begins a release on the given SharedRules, shifting traffic to
+// the first step. The "from" constraint is the single Default Light constraint
+// with the given metadata and, if clusterKey is non-empty, ClusterKey.
+func startRelease(
+	sr *api.SharedRules,
+	clusterKey api.ClusterKey,
+	from api.Metadata,
+	to api.Metadata,
+	steps []int,
+) (*release, error) {
+	if r, err := readRelease(*sr); err != nil {
+		return nil, err
+	} else if r != nil {
+		return nil, fmt.Errorf(
+			"a release from %s to %s is already in progress",
+			formatMetadataSelector(r.From),
+			formatMetadataSelector(r.To),
+		)
+	}
+
+	candidates := api.ClusterConstraints{}
+	for _, cc := range sr.Default.Light {
+		if (clusterKey == "" || cc.ClusterKey == clusterKey) && hasMetadata(cc.Metadata, from) {
+			candidates = append(candidates, cc)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no Default Light constraint matches %s", formatMetadataSelector(from))
+	case 1:
+	default:
+		return nil, fmt.Errorf(
+			"%d Default Light constraints match %s; use --cluster or more specific metadata",
+			len(candidates),
+			formatMetadataSelector(from),
+		)
+	}
+
+	r := &release{
+		ClusterKey: candidates[0].ClusterKey,
+		From:       from,
+		To:         to,
+		Steps:      steps,
+		Original:   append(api.ClusterConstraints{}, sr.Default.Light...),
+	}
+
+	for _, cc := range sr.Default.Light {
+		if r.isTo(cc) {
+			return nil, fmt.Errorf(
+				"a Default Light constraint already matches %s",
+				formatMetadataSelector(to),
+			)
+		}
+	}
+
+	if err := r.apply(sr, steps[0]); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// nextStep returns the first step greater than the current percentage.
+func (r *release) nextStep() (int, bool) {
+	for _, s := range r.Steps {
+		if s > r.Percent {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// light computes the Default Light constraints for the given percentage. The
+// ConstraintKey of an existing "to" constraint in current is preserved.
+func (r *release) light(current api.ClusterConstraints, percent int) api.ClusterConstraints {
+	var toKey api.ConstraintKey
+	for _, cc := range current {
+		if r.isTo(cc) {
+			toKey = cc.ConstraintKey
+		}
+	}
+
+	result := api.ClusterConstraints{}
+	for _, cc := range r.Original {
+		if !r.isFrom(cc) {
+			cc.Weight *= 100
+			result = append(result, cc)
+			continue
+		}
+
+		weight := cc.Weight
+		if percent < 100 {
+			cc.Weight = weight * uint32(100-percent)
+			result = append(result, cc)
+		}
+		if percent > 0 {
+			result = append(result, api.ClusterConstraint{
+				ConstraintKey: toKey,
+				ClusterKey:    cc.ClusterKey,
+				Metadata:      withMetadata(cc.Metadata, r.To),
+				Properties:    cc.Properties,
+				Weight:        weight * uint32(percent),
+			})
+		}
+	}
+
+	return result
+}
+
+// verify returns an error if the Default Light constraints of the SharedRules
+// no longer match the current step of the release, which indicates that they
+// have been modified by someone else.
+func (r *release) verify(sr api.SharedRules) error {
+	want := r.light(sr.Default.Light, r.Percent)
+	got := sr.Default.Light
+
+	if len(got) != len(want) {
+		return fmt.Errorf("Default Light constraints were modified outside of this release")
+	}
+	for i := range want {
+		w, g := want[i], got[i]
+		if w.ClusterKey != g.ClusterKey || w.Weight != g.Weight || !hasMetadata(g.Metadata, w.Metadata) {
+			return fmt.Errorf("Default Light constraints were modified outside of this release")
+		}
+	}
+
+	return nil
+}
+
+// apply shifts the given percentage of traffic to the "to" constraint and
+// records the release in the SharedRules Properties.
+func (r *release) apply(sr *api.SharedRules, percent int) error {
+	sr.Default.Light = r.light(sr.Default.Light, percent)
+	r.Percent = percent
+	return r.write(sr)
+}
+
+// finish replaces the "from" constraint with the "to" constraint at the
+// original weight, restores the weights of other constraints, and removes the
+// release from the SharedRules Properties.
+func (r *release) finish(sr *api.SharedRules) {
+	light := r.light(sr.Default.Light, 100)
+	for i := range light {
+		light[i].Weight /= 100
+	}
+	sr.Default.Light = light
+	clearRelease(sr)
+}
+
+// abort restores the original Default Light constraints and removes the
+// release from the SharedRules Properties.
+func (r *release) abort(sr *api.SharedRules) {
+	sr.Default.Light = append(api.ClusterConstraints{}, r.Original...)
+	clearRelease(sr)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/test/assert"
+)
+
+func mkReleaseSharedRules() api.SharedRules {
+	return api.SharedRules{
+		Name: "srs",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{
+				{
+					ConstraintKey: "c1",
+					ClusterKey:    "api",
+					Metadata:      api.Metadata{{Key: "version", Value: "1"}},
+					Weight:        3,
+				},
+				{ConstraintKey: "c2", ClusterKey: "other", Weight: 1},
+			},
+		},
+		Properties: api.Metadata{{Key: "owner", Value: "me"}},
+	}
+}
+
+func TestParseReleaseSteps(t *testing.T) {
+	steps, err := parseReleaseSteps("5,25%, 50")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, steps, []int{5, 25, 50, 100})
+
+	_, err = parseReleaseSteps("10,5")
+	assert.NonNil(t, err)
+
+	_, err = parseReleaseSteps("0")
+	assert.NonNil(t, err)
+}
+
+func TestReleaseLifecycle(t *testing.T) {
+	sr := mkReleaseSharedRules()
+	from := api.Metadata{{Key: "version", Value: "1"}}
+	to := api.Metadata{{Key: "version", Value: "2"}}
+
+	r, err := startRelease(&sr, "", from, to, []int{10, 100})
+	assert.Nil(t, err)
+	assert.Equal(t, r.Percent, 10)
+	assert.Equal(t, len(sr.Default.Light), 3)
+	assert.Equal(t, sr.Default.Light[0].Weight, uint32(270))
+	assert.Equal(t, sr.Default.Light[1].Weight, uint32(30))
+	assert.DeepEqual(t, sr.Default.Light[1].Metadata, to)
+	assert.Equal(t, sr.Default.Light[2].Weight, uint32(100))
+
+	_, err = startRelease(&sr, "", from, to, []int{10, 100})
+	assert.NonNil(t, err)
+
+	read, err := readRelease(sr)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, read, r)
+	assert.Nil(t, read.verify(sr))
+
+	next, ok := read.nextStep()
+	assert.True(t, ok)
+	assert.Equal(t, next, 100)
+	assert.Nil(t, read.apply(&sr, next))
+	assert.Equal(t, len(sr.Default.Light), 2)
+
+	_, ok = read.nextStep()
+	assert.False(t, ok)
+
+	read.finish(&sr)
+	assert.DeepEqual(t, sr.Properties, api.Metadata{{Key: "owner", Value: "me"}})
+	assert.Equal(t, len(sr.Default.Light), 2)
+	assert.DeepEqual(t, sr.Default.Light[0].Metadata, to)
+	assert.Equal(t, sr.Default.Light[0].Weight, uint32(3))
+	assert.Equal(t, sr.Default.Light[1].Weight, uint32(1))
+}
+
+func TestReleaseAbortAndVerify(t *testing.T) {
+	sr := mkReleaseSharedRules()
+	from := api.Metadata{{Key: "version", Value: "1"}}
+	to := api.Metadata{{Key: "version", Value: "2"}}
+
+	r, err := startRelease(&sr, "", from, to, defaultReleaseSteps)
+	assert.Nil(t, err)
+
+	sr.Default.Light[0].Weight = 1
+	assert.NonNil(t, r.verify(sr))
+
+	r.abort(&sr)
+	assert.DeepEqual(t, sr, mkReleaseSharedRules())
+}
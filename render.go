@@ -0,0 +1,118 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnos "github.com/turbinelabs/nonstdlib/os"
+)
+
+const renderDesc = `Render a Zone document written as a Go text/template,
+without contacting the API. Values are taken from --values and --set, as for
+apply and import-zone. The rendered document is decoded, to check that it is a
+valid Zone document, and printed.
+
+Referring to a value which is not set is an error. Errors in the template are
+reported with the line number at which they occur.`
+
+func cmdRender(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "render",
+		Summary:     "render a templated Zone document",
+		Usage:       "[OPTIONS]",
+		Description: renderDesc,
+	}
+
+	r := &renderRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.file,
+		"f",
+		"",
+		"The file containing the Zone template. If not specified, the template is read from STDIN.",
+	)
+
+	r.templates = newZoneTemplateFlags(&cmd.Flags)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type renderRunner struct {
+	cfg       globalConfigT
+	file      string
+	templates *zoneTemplateFlags
+}
+
+func (r *renderRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.prepareCodec(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *renderRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) != 0 {
+		return cmd.BadInput("takes no arguments")
+	}
+
+	var (
+		txt string
+		err error
+	)
+
+	if r.file != "" {
+		bytes, err := ioutil.ReadFile(r.file)
+		if err != nil {
+			return cmd.Errorf("could not read %s: %s", r.file, err)
+		}
+		txt = string(bytes)
+	} else {
+		txt, err = tbnos.ReadIfNonEmpty(os.Stdin)
+		if err != nil {
+			return cmd.Errorf("could not process STDIN: %s", err)
+		}
+	}
+
+	if txt == "" {
+		return cmd.BadInput("no zone template provided")
+	}
+
+	values, err := r.templates.values()
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	txt, err = renderZoneTemplate(templateName(r.file), txt, values)
+	if err != nil {
+		return cmd.BadInput(err)
+	}
+
+	zo := newZoneObjects()
+	if err := codec.DecodeFromString(r.cfg.codec, txt, zo); err != nil {
+		return cmd.BadInputf("could not decode rendered zone document: %s", err)
+	}
+
+	r.cfg.PrintResult(zo)
+
+	return command.NoError()
+}
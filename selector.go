@@ -0,0 +1,213 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	tbnstrings "github.com/turbinelabs/nonstdlib/strings"
+)
+
+const (
+	selectorDesc = `{{bold "Object Selectors"}}
+
+Wherever an object key is expected, an object selector may be given instead,
+in the form <object type>/<selector>. The selector is either a single
+field_name=field_value filter, as accepted by list, or a name:
+
+    cluster/name=api
+    cluster/api
+    domain/api.example.com:443
+    route/api.example.com:443/users
+
+Domains are named by host and optional port, and Routes by their Domain
+followed by their path. The selector must match exactly one object; if --zone
+is set, only objects in that Zone are considered.`
+
+	selectorSliceSep = ","
+)
+
+// isObjSelector returns true if the string is an object selector for the
+// given type, rather than an object key.
+func isObjSelector(ot objecttype.ObjectType, s string) bool {
+	return strings.HasPrefix(s, ot.Name+"/")
+}
+
+// filterHasField returns true if the filter struct has a field with the given
+// assignment name.
+func filterHasField(filter interface{}, name string) bool {
+	ft := reflect.TypeOf(filter)
+	for i := 0; i < ft.NumField(); i++ {
+		if getAssignmentName(ft.Field(i)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDomainSelector returns filter attributes for a host with an optional
+// port.
+func parseDomainSelector(s string) (map[string]string, error) {
+	host, port := tbnstrings.Split2(s, ":")
+	if host == "" {
+		return nil, fmt.Errorf("domain selector %q has no host", s)
+	}
+
+	attrs := map[string]string{"name": host}
+	if port != "" {
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("domain selector %q has invalid port %q", s, port)
+		}
+		attrs["port"] = port
+	}
+
+	return attrs, nil
+}
+
+// resolveKey returns the object key for the given key or object selector. Keys
+// are returned unchanged.
+func (gc *globalConfigT) resolveKey(svc typelessIface, keyOrSelector string) (string, error) {
+	ot := svc.Type()
+	if !isObjSelector(ot, keyOrSelector) {
+		return keyOrSelector, nil
+	}
+	sel := strings.TrimPrefix(keyOrSelector, ot.Name+"/")
+
+	// a Route's path may contain '=', so only the Domain part is checked
+	attrSel, _ := tbnstrings.Split2(sel, "/")
+	if ot != objecttype.Route {
+		attrSel = sel
+	}
+
+	var attrs map[string]string
+	switch {
+	case strings.Contains(attrSel, "="):
+		k, v := tbnstrings.SplitFirstEqual(sel)
+		attrs = map[string]string{k: v}
+
+	case ot == objecttype.Domain:
+		var err error
+		if attrs, err = parseDomainSelector(sel); err != nil {
+			return "", err
+		}
+
+	case ot == objecttype.Route:
+		domainSel, path := tbnstrings.Split2(sel, "/")
+		domainKey, err := gc.resolveKey(
+			newTypelessIface(gc.apiClient, objecttype.Domain),
+			objecttype.Domain.Name+"/"+domainSel,
+		)
+		if err != nil {
+			return "", err
+		}
+		attrs = map[string]string{"domain_key": domainKey, "path": "/" + path}
+
+	default:
+		attrs = map[string]string{"name": sel}
+	}
+
+	filter := svc.IndexZeroFilter()
+	for k := range attrs {
+		if !filterHasField(filter, k) {
+			return "", fmt.Errorf("%s: %s cannot be selected by %s", keyOrSelector, ot.Name, k)
+		}
+	}
+	gc.scopeFilterAttrs(filter, attrs)
+
+	objs, err := svc.FilteredIndex(selectorSliceSep, attrs)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(objs) {
+	case 0:
+		return "", noMatchError{keyOrSelector, ot}
+	case 1:
+		return svc.Key(objs[0]), nil
+	}
+
+	return "", gc.ambiguousSelectorError(svc, keyOrSelector, objs)
+}
+
+// resolveKeyOrName returns the object key for the given key, name or object
+// selector. A string which is not a selector is treated as a name if any
+// object has that name, and as a key otherwise.
+func (gc *globalConfigT) resolveKeyOrName(svc typelessIface, s string) (string, error) {
+	if isObjSelector(svc.Type(), s) {
+		return gc.resolveKey(svc, s)
+	}
+
+	key, err := gc.resolveKey(svc, svc.Type().Name+"/name="+s)
+	if _, ok := err.(noMatchError); ok {
+		return s, nil
+	}
+	return key, err
+}
+
+// noMatchError is returned when a selector matches no objects.
+type noMatchError struct {
+	selector string
+	ot       objecttype.ObjectType
+}
+
+func (e noMatchError) Error() string {
+	return fmt.Sprintf("%s: no %s matches", e.selector, e.ot.Name)
+}
+
+// ambiguousSelectorError returns an error listing the keys and Zones of the
+// objects matched by a selector.
+func (gc *globalConfigT) ambiguousSelectorError(
+	svc typelessIface,
+	selector string,
+	objs []interface{},
+) error {
+	zoneNames := map[api.ZoneKey]string{}
+	if zs, err := gc.apiClient.Zone().Index(service.ZoneFilter{}); err == nil {
+		for _, z := range zs {
+			zoneNames[z.ZoneKey] = z.Name
+		}
+	}
+
+	candidates := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		candidate := svc.Key(obj)
+		if f := reflect.ValueOf(obj).FieldByName("ZoneKey"); f.IsValid() {
+			zk := api.ZoneKey(f.String())
+			if name, ok := zoneNames[zk]; ok {
+				candidate = fmt.Sprintf("%s (zone %s)", candidate, name)
+			} else {
+				candidate = fmt.Sprintf("%s (zone %s)", candidate, zk)
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+
+	return fmt.Errorf(
+		"%s is ambiguous; it matches %d objects:\n  %s\nuse --zone or an object key to choose one",
+		selector,
+		len(objs),
+		strings.Join(candidates, "\n  "),
+	)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/tbnctl/fakeapi"
+	"github.com/turbinelabs/test/assert"
+)
+
+type selectorTestObjs struct {
+	gc      *globalConfigT
+	zones   []api.Zone
+	cluster api.Cluster
+	only    api.Cluster
+	domain  api.Domain
+	route   api.Route
+}
+
+func mkSelectorTestObjs(t *testing.T) selectorTestObjs {
+	store := fakeapi.New()
+	svc := store.All()
+
+	objs := selectorTestObjs{
+		gc: &globalConfigT{apiClient: &unifiedSvc{svc, store.Admin()}},
+	}
+
+	for _, name := range []string{"prod", "staging"} {
+		z, err := svc.Zone().Create(api.Zone{Name: name})
+		assert.Nil(t, err)
+		objs.zones = append(objs.zones, z)
+
+		c, err := svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: "api"})
+		assert.Nil(t, err)
+		only, err := svc.Cluster().Create(api.Cluster{ZoneKey: z.ZoneKey, Name: name + "-only"})
+		assert.Nil(t, err)
+
+		d, err := svc.Domain().Create(
+			api.Domain{ZoneKey: z.ZoneKey, Name: name + ".example.com", Port: 443},
+		)
+		assert.Nil(t, err)
+
+		sr, err := svc.SharedRules().Create(api.SharedRules{ZoneKey: z.ZoneKey, Name: "sr"})
+		assert.Nil(t, err)
+
+		r, err := svc.Route().Create(api.Route{
+			ZoneKey:        z.ZoneKey,
+			DomainKey:      d.DomainKey,
+			Path:           "/users",
+			SharedRulesKey: sr.SharedRulesKey,
+		})
+		assert.Nil(t, err)
+
+		if name == "prod" {
+			objs.cluster, objs.only, objs.domain, objs.route = c, only, d, r
+		}
+	}
+
+	return objs
+}
+
+func (o selectorTestObjs) resolve(ot objecttype.ObjectType, s string) (string, error) {
+	return o.gc.resolveKey(newTypelessIface(o.gc.apiClient, ot), s)
+}
+
+func TestResolveKeyPassesKeysThrough(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+
+	key, err := objs.resolve(objecttype.Cluster, "some-key")
+	assert.Nil(t, err)
+	assert.Equal(t, key, "some-key")
+
+	// a selector for another type is a key
+	key, err = objs.resolve(objecttype.Cluster, "domain/x")
+	assert.Nil(t, err)
+	assert.Equal(t, key, "domain/x")
+}
+
+func TestResolveKeySelectors(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+
+	for _, tc := range []struct {
+		ot   objecttype.ObjectType
+		sel  string
+		want string
+	}{
+		{objecttype.Cluster, "cluster/name=prod-only", string(objs.only.ClusterKey)},
+		{objecttype.Cluster, "cluster/prod-only", string(objs.only.ClusterKey)},
+		{objecttype.Domain, "domain/prod.example.com:443", string(objs.domain.DomainKey)},
+		{objecttype.Domain, "domain/prod.example.com", string(objs.domain.DomainKey)},
+		{objecttype.Route, "route/prod.example.com:443/users", string(objs.route.RouteKey)},
+		{objecttype.Zone, "zone/staging", string(objs.zones[1].ZoneKey)},
+	} {
+		key, err := objs.resolve(tc.ot, tc.sel)
+		assert.Nil(t, err)
+		assert.Equal(t, key, tc.want)
+	}
+}
+
+func TestResolveKeyAmbiguous(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+
+	_, err := objs.resolve(objecttype.Cluster, "cluster/name=api")
+	assert.ErrorContains(t, err, "cluster/name=api is ambiguous; it matches 2 objects")
+	assert.ErrorContains(t, err, string(objs.cluster.ClusterKey)+" (zone prod)")
+	assert.ErrorContains(t, err, "(zone staging)")
+
+	_, err = objs.resolve(objecttype.Route, "route/path=/users")
+	assert.ErrorContains(t, err, "route/path=/users is ambiguous; it matches 2 objects")
+}
+
+func TestResolveKeyScopedToZone(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+	objs.gc.zoneKey = objs.zones[0].ZoneKey
+
+	key, err := objs.resolve(objecttype.Cluster, "cluster/api")
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.cluster.ClusterKey))
+
+	key, err = objs.resolve(objecttype.Route, "route/path=/users")
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.route.RouteKey))
+
+	_, err = objs.resolve(objecttype.Cluster, "cluster/staging-only")
+	assert.ErrorContains(t, err, "cluster/staging-only: no cluster matches")
+}
+
+func TestResolveKeyErrors(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+
+	_, err := objs.resolve(objecttype.Cluster, "cluster/nme=api")
+	assert.ErrorContains(t, err, "cluster/nme=api: cluster cannot be selected by nme")
+
+	_, err = objs.resolve(objecttype.Domain, "domain/prod.example.com:https")
+	assert.ErrorContains(t, err, `has invalid port "https"`)
+
+	_, err = objs.resolve(objecttype.Route, "route/nope.example.com:443/users")
+	assert.ErrorContains(t, err, "domain/nope.example.com:443: no domain matches")
+}
+
+func TestResolveKeyOrName(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+	svc := newTypelessIface(objs.gc.apiClient, objecttype.Cluster)
+
+	key, err := objs.gc.resolveKeyOrName(svc, "prod-only")
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.only.ClusterKey))
+
+	key, err = objs.gc.resolveKeyOrName(svc, string(objs.cluster.ClusterKey))
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.cluster.ClusterKey))
+
+	key, err = objs.gc.resolveKeyOrName(svc, "cluster/prod-only")
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.only.ClusterKey))
+
+	_, err = objs.gc.resolveKeyOrName(svc, "api")
+	assert.ErrorContains(t, err, "cluster/name=api is ambiguous")
+
+	objs.gc.zoneKey = objs.zones[0].ZoneKey
+	key, err = objs.gc.resolveKeyOrName(svc, "api")
+	assert.Nil(t, err)
+	assert.Equal(t, key, string(objs.cluster.ClusterKey))
+}
+
+func TestOtFromStringsSelector(t *testing.T) {
+	args := []string{"cluster/name=api", "x"}
+	ot, err := keyedOtFromStrings(&args)
+	assert.Nil(t, err)
+	assert.Equal(t, ot, objecttype.Cluster)
+	assert.DeepEqual(t, args, []string{"cluster/name=api", "x"})
+
+	args = []string{"cluster", "key"}
+	ot, err = keyedOtFromStrings(&args)
+	assert.Nil(t, err)
+	assert.Equal(t, ot, objecttype.Cluster)
+	assert.DeepEqual(t, args, []string{"key"})
+
+	args = []string{"nope/name=api"}
+	_, err = keyedOtFromStrings(&args)
+	assert.ErrorContains(t, err, "nope/name=api was not a valid object type")
+
+	args = []string{"cluster", "key"}
+	ot, err = otFromStrings(&args)
+	assert.Nil(t, err)
+	assert.Equal(t, ot, objecttype.Cluster)
+	assert.DeepEqual(t, args, []string{"key"})
+
+	args = []string{"cluster/name=api"}
+	_, err = otFromStrings(&args)
+	assert.ErrorContains(t, err, "cluster/name=api is an object selector")
+	assert.DeepEqual(t, args, []string{"cluster/name=api"})
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	"github.com/turbinelabs/nonstdlib/log/console"
+	"github.com/turbinelabs/tbnctl/fakeapi"
+)
+
+const serveFakeDesc = `Serve an in-memory fake of the Turbine Labs API, for offline
+development and testing. Every object type is supported, with the same filter
+semantics, generated keys, and checksum checks as the API. API keys are
+accepted but not checked. Nothing is persisted; all objects are lost when the
+server exits.
+
+The fake may be seeded with one or more Zone documents in the format produced
+by export-zone, each given with -f. Each document is imported as with
+import-zone, using the Zone name in the document.
+
+Point tbnctl at the fake with the --api.* flags, e.g.:
+
+    tbnctl serve-fake -f prod.json &
+    tbnctl --api.host=127.0.0.1 --api.port=8080 --api.ssl=false list cluster`
+
+func cmdServeFake(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "serve-fake",
+		Summary:     "serve an in-memory fake of the Turbine Labs API",
+		Usage:       "[OPTIONS]",
+		Description: serveFakeDesc,
+	}
+
+	r := &serveFakeRunner{cfg: globalConfig, files: tbnflag.NewStrings()}
+
+	cmd.Flags.StringVar(
+		&r.listen,
+		"listen",
+		"127.0.0.1:8080",
+		"The address on which to serve the fake API.",
+	)
+	cmd.Flags.Var(
+		&r.files,
+		"f",
+		"A Zone document with which to seed the fake API. May be repeated.",
+	)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type serveFakeRunner struct {
+	cfg    globalConfigT
+	listen string
+	files  tbnflag.Strings
+}
+
+func (r *serveFakeRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.prepareCodec(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *serveFakeRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) != 0 {
+		return cmd.BadInput("takes no arguments")
+	}
+
+	store := fakeapi.New()
+	if err := r.seed(store); err != nil {
+		return cmd.Error(err)
+	}
+
+	l, err := net.Listen("tcp", r.listen)
+	if err != nil {
+		return cmd.Errorf("could not listen on %s: %s", r.listen, err)
+	}
+
+	console.Info().Printf("serving fake API on %s", l.Addr())
+	if err := http.Serve(l, store.Handler()); err != nil {
+		return cmd.Error(err)
+	}
+
+	return command.NoError()
+}
+
+// seed imports each Zone document into the store.
+func (r *serveFakeRunner) seed(store *fakeapi.Store) error {
+	for _, file := range r.files.Strings {
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %s", file, err)
+		}
+
+		zo := newZoneObjects()
+		if err := codec.DecodeFromString(r.cfg.codec, string(bytes), zo); err != nil {
+			return fmt.Errorf("could not decode %s: %s", file, err)
+		}
+		if zo.Zone.Name == "" {
+			return fmt.Errorf("%s: zone name must be specified in the document", file)
+		}
+
+		if _, err := storeZone(store.All(), zo.Zone.Name, zo); err != nil {
+			return fmt.Errorf("could not import %s: %s", file, err)
+		}
+		console.Info().Printf("seeded zone %s from %s", zo.Zone.Name, file)
+	}
+
+	return nil
+}
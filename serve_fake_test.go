@@ -0,0 +1,71 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	"github.com/turbinelabs/tbnctl/fakeapi"
+	"github.com/turbinelabs/test/assert"
+)
+
+const seedDoc = `{
+  "zone": {"name": "z"},
+  "clusters": [{"name": "c"}],
+  "domains": [{"name": "d", "port": 80}],
+  "listeners": [{"name": "l", "port": 80, "domain_keys": ["d:80"]}],
+  "proxies": [{"name": "p", "domain_keys": ["d:80"], "listener_keys": ["l"]}],
+  "shared_rules": [
+    {"name": "sr", "default": {"light": [{"cluster_key": "c", "weight": 1}]}}
+  ],
+  "routes": [{"domain_key": "d:80", "path": "/", "shared_rules_key": "sr"}]
+}`
+
+func TestServeFakeSeedRoundTrips(t *testing.T) {
+	f, err := ioutil.TempFile("", "tbnctl-seed")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(seedDoc)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	r := &serveFakeRunner{files: tbnflag.Strings{Strings: []string{f.Name()}}}
+	r.cfg.codec = codec.NewJson()
+
+	store := fakeapi.New()
+	assert.Nil(t, r.seed(store))
+
+	zo, err := exportZone(store.All(), "z")
+	assert.Nil(t, err)
+	assert.Equal(t, zo.Zone.Name, "z")
+	assert.Equal(t, zo.Clusters[0].Name, "c")
+	assert.DeepEqual(t, zo.Listeners[0].DomainKeys, []api.DomainKey{"d:80"})
+	assert.DeepEqual(t, zo.Proxies[0].DomainKeys, []api.DomainKey{"d:80"})
+	assert.DeepEqual(t, zo.Proxies[0].ListenerKeys, []api.ListenerKey{"l"})
+	assert.Equal(t, zo.SharedRules[0].Default.Light[0].ClusterKey, api.ClusterKey("c"))
+	assert.Equal(t, zo.Routes[0].RouteKey, api.RouteKey("d:80/"))
+
+	// applying the exported document makes no changes
+	changes, err := applyZone(&unifiedSvc{store.All(), store.Admin()}, zo, true)
+	assert.Nil(t, err)
+	assert.Equal(t, len(changes), 0)
+}
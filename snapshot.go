@@ -0,0 +1,293 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+)
+
+const (
+	snapshotCreate  = "create"
+	snapshotRestore = "restore"
+
+	// snapshotVersion is the version of the snapshot format written by
+	// snapshot create. Restore rejects snapshots with a later version.
+	snapshotVersion = 1
+
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+const snapshotDesc = `Create or restore a snapshot of a Zone. The action is one of:
+
+    create   write a snapshot of the named Zone to a file.
+    restore  restore a snapshot into the Zone it was taken from, or into
+             the Zone with the given name.
+
+Unlike export-zone, a snapshot records every object in the Zone exactly as
+stored, including Cluster Instances, Listeners, object keys and checksums,
+along with the time it was taken and a format version.
+
+By default, create writes to a file named after the Zone and the time, in the
+current directory; use -o to choose another file, or -o=- for STDOUT. The
+snapshot is encoded using the configured format, and restore must be run with
+the same format.
+
+Restore matches objects in the snapshot to those in the target Zone by name,
+as apply does, creating the Zone if necessary. Objects which differ are
+modified back to their snapshotted state, and missing objects are re-created
+(with new keys). Objects created since the snapshot was taken are left in
+place unless --prune is given, in which case they are deleted. The changes
+made are printed.`
+
+func cmdSnapshot(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "snapshot",
+		Summary:     "create or restore a snapshot of a Zone",
+		Usage:       "[OPTIONS] create <zone-name>|<zone-key> | restore <file> [zone-name]",
+		Description: snapshotDesc,
+	}
+
+	r := &snapshotRunner{cfg: globalConfig}
+
+	cmd.Flags.StringVar(
+		&r.output,
+		"o",
+		"",
+		"For create, the file to write the snapshot to, or - for STDOUT.",
+	)
+
+	cmd.Flags.BoolVar(
+		&r.prune,
+		"prune",
+		false,
+		"For restore, if true, delete objects in the Zone which are not in the snapshot. Off by default, so that restore never deletes anything unless asked.",
+	)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type snapshotRunner struct {
+	cfg    globalConfigT
+	output string
+	prune  bool
+}
+
+// snapshot holds every object in a Zone, as stored in the API.
+type snapshot struct {
+	Version     int                  `json:"version"`
+	Created     time.Time            `json:"created"`
+	Zone        api.Zone             `json:"zone"`
+	Clusters    api.Clusters         `json:"clusters"`
+	Domains     api.Domains          `json:"domains"`
+	Listeners   api.Listeners        `json:"listeners"`
+	Proxies     api.Proxies          `json:"proxies"`
+	SharedRules api.SharedRulesSlice `json:"shared_rules"`
+	Routes      api.Routes           `json:"routes"`
+}
+
+// takeSnapshot returns a snapshot of the Zone with a ZoneKey or Name matching
+// the given string.
+func takeSnapshot(svc service.All, keyOrName string, now time.Time) (*snapshot, error) {
+	z, err := findZone(svc, keyOrName)
+	if err != nil {
+		return nil, err
+	}
+
+	zk := z.ZoneKey
+	s := &snapshot{Version: snapshotVersion, Created: now.UTC(), Zone: z}
+
+	if s.Clusters, err = svc.Cluster().Index(service.ClusterFilter{ZoneKey: zk}); err != nil {
+		return nil, err
+	}
+
+	if s.Domains, err = svc.Domain().Index(service.DomainFilter{ZoneKey: zk}); err != nil {
+		return nil, err
+	}
+
+	if s.Listeners, err = svc.Listener().Index(service.ListenerFilter{ZoneKey: zk}); err != nil {
+		return nil, err
+	}
+
+	if s.Proxies, err = svc.Proxy().Index(service.ProxyFilter{ZoneKey: zk}); err != nil {
+		return nil, err
+	}
+
+	srs, err := svc.SharedRules().Index(service.SharedRulesFilter{ZoneKey: zk})
+	if err != nil {
+		return nil, err
+	}
+	s.SharedRules = srs
+
+	if s.Routes, err = svc.Route().Index(service.RouteFilter{ZoneKey: zk}); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// zoneObjects returns the snapshot as a name-keyed zoneObjects, suitable for
+// applyZone. Unlike the output of exportZone, Cluster Instances are retained,
+// and Listeners are always included, so that applying the result restores
+// them.
+func (s *snapshot) zoneObjects() *zoneObjects {
+	zo := newZoneObjects()
+	zo.Zone = zo.exportZone(s.Zone)
+	zo.Clusters = api.Clusters{}
+	zo.Domains = api.Domains{}
+	zo.Listeners = api.Listeners{}
+	zo.Proxies = api.Proxies{}
+	zo.SharedRules = api.SharedRulesSlice{}
+	zo.Routes = api.Routes{}
+
+	for _, c := range s.Clusters {
+		ec := zo.exportCluster(c)
+		ec.Instances = c.Instances
+		if ec.Instances == nil {
+			ec.Instances = api.Instances{}
+		}
+		zo.Clusters = append(zo.Clusters, ec)
+	}
+
+	for _, d := range s.Domains {
+		zo.Domains = append(zo.Domains, zo.exportDomain(d))
+	}
+
+	for _, l := range s.Listeners {
+		zo.Listeners = append(zo.Listeners, zo.exportListener(l))
+	}
+
+	for _, p := range s.Proxies {
+		ep := zo.exportProxy(p)
+		if ep.ListenerKeys == nil {
+			ep.ListenerKeys = []api.ListenerKey{}
+		}
+		zo.Proxies = append(zo.Proxies, ep)
+	}
+
+	// exportSharedRules and exportRoute modify constraints in place, so copy
+	// the snapshot first.
+	for _, sr := range copySharedRules(s.SharedRules) {
+		zo.SharedRules = append(zo.SharedRules, zo.exportSharedRules(sr))
+	}
+
+	for _, r := range copyRoutes(s.Routes) {
+		zo.Routes = append(zo.Routes, zo.exportRoute(r))
+	}
+
+	return zo
+}
+
+func (r *snapshotRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.Prepare(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *snapshotRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	if len(args) < 2 {
+		return cmd.BadInput("requires an action and a zone or file")
+	}
+
+	switch args[0] {
+	case snapshotCreate:
+		if len(args) != 2 {
+			return cmd.BadInput("create takes exactly one zone")
+		}
+		return r.create(cmd, args[1])
+
+	case snapshotRestore:
+		if len(args) > 3 {
+			return cmd.BadInput("restore takes a file and an optional zone name")
+		}
+		name := ""
+		if len(args) == 3 {
+			name = args[2]
+		}
+		return r.restore(cmd, args[1], name)
+	}
+
+	return cmd.BadInputf("unknown snapshot action %q", args[0])
+}
+
+func (r *snapshotRunner) create(cmd *command.Cmd, zone string) command.CmdErr {
+	s, err := takeSnapshot(r.cfg.apiClient, zone, time.Now())
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	if r.output == "-" {
+		r.cfg.PrintResult(s)
+		return command.NoError()
+	}
+
+	file := r.output
+	if file == "" {
+		file = fmt.Sprintf("%s-%s.snapshot", s.Zone.Name, s.Created.Format(snapshotTimeFormat))
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.cfg.codec.Encode(s, buf); err != nil {
+		return cmd.Error(err)
+	}
+
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0600); err != nil {
+		return cmd.Errorf("could not write %s: %s", file, err)
+	}
+
+	fmt.Println(file)
+	return command.NoError()
+}
+
+func (r *snapshotRunner) restore(cmd *command.Cmd, file, name string) command.CmdErr {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cmd.Errorf("could not read %s: %s", file, err)
+	}
+
+	s := &snapshot{}
+	if err := codec.DecodeFromString(r.cfg.codec, string(bytes), s); err != nil {
+		return cmd.BadInputf("could not decode snapshot %s: %s", file, err)
+	}
+
+	if s.Version < 1 || s.Version > snapshotVersion {
+		return cmd.BadInputf("unsupported snapshot version %d", s.Version)
+	}
+
+	zo := s.zoneObjects()
+	if name != "" {
+		zo.Zone.Name = name
+	}
+
+	changes, err := applyZone(r.cfg.apiClient, zo, r.prune)
+	r.cfg.PrintResult(changes)
+	if err != nil {
+		return r.cfg.PrettyCmdErr(cmd, err)
+	}
+
+	return command.NoError()
+}
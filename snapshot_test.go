@@ -0,0 +1,67 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestSnapshotZoneObjects(t *testing.T) {
+	s := &snapshot{
+		Version:  snapshotVersion,
+		Zone:     api.Zone{ZoneKey: "zk", Name: "z", Checksum: api.Checksum{Checksum: "z1"}},
+		Clusters: api.Clusters{{ClusterKey: "ck", ZoneKey: "zk", Name: "c", Instances: api.Instances{{Host: "h", Port: 80}}}},
+		Domains:  api.Domains{{DomainKey: "dk", ZoneKey: "zk", Name: "d", Port: 80}},
+		Listeners: api.Listeners{
+			{ListenerKey: "lk", ZoneKey: "zk", Name: "l", Port: 80, DomainKeys: []api.DomainKey{"dk"}},
+		},
+		Proxies: api.Proxies{{ProxyKey: "pk", ZoneKey: "zk", Name: "p", ListenerKeys: []api.ListenerKey{"lk"}}},
+		SharedRules: api.SharedRulesSlice{
+			{
+				SharedRulesKey: "srk",
+				ZoneKey:        "zk",
+				Name:           "sr",
+				Default: api.AllConstraints{
+					Light: api.ClusterConstraints{{ConstraintKey: "cc", ClusterKey: "ck", Weight: 1}},
+				},
+			},
+		},
+		Routes: api.Routes{{RouteKey: "rk", ZoneKey: "zk", DomainKey: "dk", SharedRulesKey: "srk", Path: "/"}},
+	}
+
+	zo := s.zoneObjects()
+	assert.Equal(t, zo.Zone.Name, "z")
+	assert.DeepEqual(t, zo.Clusters[0].Instances, api.Instances{{Host: "h", Port: 80}})
+	assert.DeepEqual(t, zo.Listeners[0].DomainKeys, []api.DomainKey{"d:80"})
+	assert.DeepEqual(t, zo.Proxies[0].ListenerKeys, []api.ListenerKey{"l"})
+	assert.DeepEqual(t, zo.Proxies[0].DomainKeys, []api.DomainKey{})
+	assert.Equal(t, zo.SharedRules[0].Default.Light[0].ClusterKey, api.ClusterKey("c"))
+	assert.Equal(t, zo.Routes[0].DomainKey, api.DomainKey("d:80"))
+	assert.Equal(t, zo.Routes[0].SharedRulesKey, api.SharedRulesKey("sr"))
+
+	// the snapshot itself is unchanged
+	assert.Equal(t, s.SharedRules[0].Default.Light[0].ClusterKey, api.ClusterKey("ck"))
+	assert.Equal(t, s.SharedRules[0].Default.Light[0].ConstraintKey, api.ConstraintKey("cc"))
+}
+
+func TestSnapshotRestoreDoesNotPruneByDefault(t *testing.T) {
+	cmd := cmdSnapshot(globalConfigT{})
+	assert.Equal(t, cmd.Flags.Lookup("prune").DefValue, "false")
+}
@@ -52,13 +52,16 @@ type typelessIface interface {
 
 	ObjFromString(string, codec.Codec) (interface{}, error)
 	Checksum(interface{}) api.Checksum
+	Key(interface{}) string
+	WithKey(interface{}, string) interface{}
+	WithChecksum(interface{}, api.Checksum) interface{}
 	Zero() interface{}
 
 	Create(interface{}) (interface{}, error)
 	Get(string) (interface{}, error)
 	Modify(interface{}) (interface{}, error)
 	Delete(string, api.Checksum) error
-	DeepDelete(string, api.Checksum, *unifiedSvc) error
+	DeepDeleter(string, *unifiedSvc) (*deleter, error)
 	Index() ([]interface{}, error)
 	FilteredIndex(string, map[string]string) ([]interface{}, error)
 	IndexZeroFilter() interface{}
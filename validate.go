@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+	tbnos "github.com/turbinelabs/nonstdlib/os"
+)
+
+const validateDesc = `Validate objects or zone documents without contacting the
+Turbine Labs API. If an object type is given, each input is decoded as an
+object of that type; otherwise each is decoded as a zone document, in the
+format produced by export-zone.
+
+The following problems are reported:
+
+    - input which cannot be decoded
+    - unknown fields
+    - missing required fields
+    - constraints whose weights sum to zero
+    - in zone documents, duplicate Clusters, Domains, Listeners, SharedRules
+      or Routes, and references to Clusters, Domains, Listeners or SharedRules
+      which are not in the document
+
+Input is read from the files given with -f, or from STDIN. The exit status is
+zero if no problems are found, and non-zero otherwise.
+
+object type is one of: `
+
+func cmdValidate(globalConfig globalConfigT) *command.Cmd {
+	cmd := &command.Cmd{
+		Name:        "validate",
+		Summary:     "validate objects or zone documents offline",
+		Usage:       "[OPTIONS] [object type]",
+		Description: validateDesc + objTypeNames(),
+	}
+
+	r := &validateRunner{cfg: globalConfig, files: tbnflag.NewStrings()}
+
+	cmd.Flags.Var(
+		&r.files,
+		"f",
+		"The files to validate. May be comma-delimited or given more than once. If not specified, input is read from STDIN.",
+	)
+
+	cmd.Flags.BoolVar(
+		&r.json,
+		"json",
+		false,
+		"If true, print problems as JSON, regardless of the configured format.",
+	)
+
+	cmd.Runner = r
+	return cmd
+}
+
+type validateRunner struct {
+	cfg   globalConfigT
+	files tbnflag.Strings
+	json  bool
+}
+
+// validateResult is the JSON output of validate.
+type validateResult struct {
+	Valid    bool          `json:"valid"`
+	Problems []lintProblem `json:"problems"`
+}
+
+func (r *validateRunner) Run(cmd *command.Cmd, args []string) command.CmdErr {
+	if err := r.cfg.prepareCodec(cmd); err != command.NoError() {
+		return err
+	}
+
+	return r.run(cmd, args)
+}
+
+func (r *validateRunner) run(cmd *command.Cmd, args []string) command.CmdErr {
+	var (
+		zero   interface{} = zoneObjects{}
+		object             = "document"
+	)
+	if len(args) > 0 {
+		ot, err := otFromStrings(&args)
+		if err != nil {
+			return cmd.BadInput(err)
+		}
+		if zero = lintZero(ot); zero == nil {
+			return cmd.BadInputf("cannot validate %s", ot.Name)
+		}
+		object = ot.Name
+	}
+	if len(args) > 0 {
+		return cmd.BadInput("takes at most one argument")
+	}
+
+	inputs := map[string]string{}
+	names := r.files.Strings
+	if len(names) == 0 {
+		txt, err := tbnos.ReadIfNonEmpty(os.Stdin)
+		if err != nil {
+			return cmd.Errorf("could not process STDIN: %s", err)
+		}
+		if txt == "" {
+			return cmd.BadInput("no input provided")
+		}
+		names = []string{""}
+		inputs[""] = txt
+	} else {
+		for _, name := range names {
+			bytes, err := ioutil.ReadFile(name)
+			if err != nil {
+				return cmd.Errorf("could not read %s: %s", name, err)
+			}
+			inputs[name] = string(bytes)
+		}
+	}
+
+	problems := []lintProblem{}
+	for _, name := range names {
+		problems = append(problems, lint(name, object, r.cfg.codec, inputs[name], zero)...)
+	}
+
+	if r.json {
+		r.cfg.codec = codec.NewJson()
+		r.cfg.PrintResult(validateResult{len(problems) == 0, problems})
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return cmd.Errorf("found %d problem(s)", len(problems))
+	}
+
+	return command.NoError()
+}
+
+// lint decodes txt as a value of the same type as zero, which is either an
+// API object or a zoneObjects, and returns any problems found. Problems found
+// while decoding are attributed to the given object.
+func lint(file, object string, cdc codec.Codec, txt string, zero interface{}) []lintProblem {
+	l := &linter{file: file}
+
+	obj, ok := l.lintText(object, cdc, txt, zero)
+	if ok {
+		if zo, isZone := obj.(zoneObjects); isZone {
+			l.lintZone(&zo)
+		} else {
+			l.lintObject(obj, true)
+		}
+	}
+
+	return l.problems
+}
@@ -0,0 +1,662 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/nonstdlib/log/console"
+)
+
+const (
+	changeCreate = "create"
+	changeModify = "modify"
+	changeDelete = "delete"
+	changeKeep   = "keep"
+)
+
+const (
+	conflictKeep      = "keep"
+	conflictOverwrite = "overwrite"
+	conflictFail      = "fail"
+)
+
+// zoneChange describes a single mutation made while applying a zone document.
+type zoneChange struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+}
+
+// staleObject is an object present in the API but absent from the applied
+// zone document.
+type staleObject struct {
+	ot       objecttype.ObjectType
+	name     string
+	key      string
+	checksum api.Checksum
+}
+
+// zoneApplier reconciles a name-keyed zone document against the objects
+// currently stored in the API.
+type zoneApplier struct {
+	svc   *unifiedSvc
+	prune bool
+
+	// onConflict determines what happens to an existing object which differs
+	// from the zone document. It is empty when applying, which always
+	// overwrites, and one of the conflict constants when merging.
+	onConflict string
+
+	// want is the desired state. As objects are stored its key maps are
+	// populated with name to key mappings, as in importZone.
+	want *zoneObjects
+
+	// have is used to export live objects for comparison with the desired
+	// state. Its key maps contain key to name mappings, as in exportZone.
+	have *zoneObjects
+
+	zoneKey api.ZoneKey
+	stale   []staleObject
+	changes []zoneChange
+}
+
+// applyZone makes the Zone in the API with the same name as the given
+// zoneObjects match it, creating the Zone if necessary. Objects are matched by
+// name (or address and path for Domains and Routes), and are created or
+// modified in dependency order. If prune is true, objects in the Zone that are
+// not present in the zoneObjects are deleted in reverse dependency order. The
+// changes made are returned even if an error occurs.
+func applyZone(svc *unifiedSvc, want *zoneObjects, prune bool) ([]zoneChange, error) {
+	a := &zoneApplier{svc: svc, prune: prune, want: want, have: newZoneObjects()}
+	return a.apply()
+}
+
+// mergeZone adds the objects in the given zoneObjects to the Zone in the API
+// with the same name, creating the Zone if necessary. References are resolved
+// against objects already in the Zone, matched as in applyZone, and missing
+// objects are created. An existing object which differs from the zoneObjects
+// is kept, overwritten, or causes an error, according to onConflict. Objects
+// in the Zone but not in the zoneObjects are left alone. The changes made,
+// including conflicting objects which were kept, are returned even if an
+// error occurs.
+func mergeZone(svc *unifiedSvc, want *zoneObjects, onConflict string) ([]zoneChange, error) {
+	a := &zoneApplier{svc: svc, onConflict: onConflict, want: want, have: newZoneObjects()}
+	return a.apply()
+}
+
+func (a *zoneApplier) apply() ([]zoneChange, error) {
+	for _, step := range []func() error{
+		a.applyZone,
+		a.applyClusters,
+		a.applyDomains,
+		a.applyListeners,
+		a.applyProxies,
+		a.applySharedRules,
+		a.applyRoutes,
+		a.pruneStale,
+	} {
+		if err := step(); err != nil {
+			return a.changes, err
+		}
+	}
+
+	return a.changes, nil
+}
+
+// clearConstraintKeys removes ConstraintKeys, which are assigned by the API
+// and are meaningless when comparing name-keyed objects.
+func clearConstraintKeys(ac *api.AllConstraints) {
+	for _, ccs := range []api.ClusterConstraints{ac.Light, ac.Dark, ac.Tap} {
+		for i := range ccs {
+			ccs[i].ConstraintKey = ""
+		}
+	}
+}
+
+// clearRuleKeys removes RuleKeys and ConstraintKeys, which are assigned by
+// the API and are meaningless when comparing name-keyed objects.
+func clearRuleKeys(rs api.Rules) {
+	for i := range rs {
+		rs[i].RuleKey = ""
+		clearConstraintKeys(&rs[i].Constraints)
+	}
+}
+
+func (a *zoneApplier) record(action string, ot objecttype.ObjectType, name string) {
+	console.Info().Printf("%s %s %s", action, ot.Name, name)
+	a.changes = append(a.changes, zoneChange{action, ot.Name, name})
+}
+
+func (a *zoneApplier) addStale(ot objecttype.ObjectType, name, key string, cs api.Checksum) {
+	a.stale = append(a.stale, staleObject{ot, name, key, cs})
+}
+
+func (a *zoneApplier) create(
+	ot objecttype.ObjectType,
+	name string,
+	obj interface{},
+) (interface{}, error) {
+	obj, err := newTypelessIface(a.svc, ot).Create(obj)
+	if err != nil {
+		return nil, err
+	}
+	a.record(changeCreate, ot, name)
+	return obj, nil
+}
+
+func (a *zoneApplier) modify(
+	ot objecttype.ObjectType,
+	name string,
+	obj interface{},
+) (interface{}, error) {
+	obj, err := newTypelessIface(a.svc, ot).Modify(obj)
+	if err != nil {
+		return nil, err
+	}
+	a.record(changeModify, ot, name)
+	return obj, nil
+}
+
+// update is called with an object which exists in the API, cur, but which
+// differs from the zone document, obj. According to the conflict policy, it
+// modifies the object, keeps the existing object, or fails. The resulting
+// object is returned.
+func (a *zoneApplier) update(
+	ot objecttype.ObjectType,
+	name string,
+	obj interface{},
+	cur interface{},
+) (interface{}, error) {
+	switch a.onConflict {
+	case conflictKeep:
+		a.record(changeKeep, ot, name)
+		return cur, nil
+	case conflictFail:
+		return nil, fmt.Errorf("%s %s differs from the zone document", ot.Name, name)
+	}
+	return a.modify(ot, name, obj)
+}
+
+func (a *zoneApplier) applyZone() error {
+	name := a.want.Zone.Name
+	zs, err := a.svc.Zone().Index(service.ZoneFilter{Name: name})
+	if err != nil {
+		return err
+	}
+
+	var z api.Zone
+	if len(zs) > 0 {
+		z = zs[0]
+	} else {
+		obj, err := a.create(objecttype.Zone, name, api.Zone{Name: name})
+		if err != nil {
+			return err
+		}
+		z = obj.(api.Zone)
+	}
+
+	a.zoneKey = z.ZoneKey
+	a.want.Zone = z
+	a.have.Zone = a.have.exportZone(z)
+	return nil
+}
+
+func (a *zoneApplier) applyClusters() error {
+	live, err := a.svc.Cluster().Index(service.ClusterFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]api.Cluster{}
+	for _, c := range live {
+		byName[c.Name] = c
+		a.have.exportCluster(c)
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.Clusters {
+		c := a.want.Clusters[i]
+		c.ZoneKey = a.zoneKey
+		seen[c.Name] = true
+
+		if cur, ok := byName[c.Name]; ok {
+			c.ClusterKey = cur.ClusterKey
+			c.Checksum = cur.Checksum
+			if c.Instances == nil || len(c.Instances)+len(cur.Instances) == 0 {
+				// export-zone omits instances, so keep whatever is running
+				c.Instances = cur.Instances
+			}
+			if !c.Equals(cur) {
+				obj, err := a.update(objecttype.Cluster, c.Name, c, cur)
+				if err != nil {
+					return err
+				}
+				c = obj.(api.Cluster)
+			}
+		} else {
+			c.ClusterKey = ""
+			c.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.Cluster, c.Name, c)
+			if err != nil {
+				return err
+			}
+			c = obj.(api.Cluster)
+		}
+
+		a.want.clusterKeyMap[api.ClusterKey(c.Name)] = c.ClusterKey
+		a.want.Clusters[i] = c
+	}
+
+	for _, c := range live {
+		if !seen[c.Name] {
+			a.addStale(objecttype.Cluster, c.Name, string(c.ClusterKey), c.Checksum)
+		}
+	}
+
+	return nil
+}
+
+func (a *zoneApplier) applyDomains() error {
+	live, err := a.svc.Domain().Index(service.DomainFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	byAddr := map[string]api.Domain{}
+	for _, d := range live {
+		byAddr[d.Addr()] = d
+		a.have.exportDomain(d)
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.Domains {
+		d := a.want.Domains[i]
+		d.ZoneKey = a.zoneKey
+		addr := d.Addr()
+		seen[addr] = true
+
+		if cur, ok := byAddr[addr]; ok {
+			d.DomainKey = cur.DomainKey
+			d.Checksum = cur.Checksum
+			if !d.Equals(cur) {
+				obj, err := a.update(objecttype.Domain, addr, d, cur)
+				if err != nil {
+					return err
+				}
+				d = obj.(api.Domain)
+			}
+		} else {
+			d.DomainKey = ""
+			d.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.Domain, addr, d)
+			if err != nil {
+				return err
+			}
+			d = obj.(api.Domain)
+		}
+
+		a.want.domainKeyMap[api.DomainKey(addr)] = d.DomainKey
+		a.want.Domains[i] = d
+	}
+
+	for _, d := range live {
+		if !seen[d.Addr()] {
+			a.addStale(objecttype.Domain, d.Addr(), string(d.DomainKey), d.Checksum)
+		}
+	}
+
+	return nil
+}
+
+// applyListeners reconciles the Listeners of the zone. Zone documents
+// exported before Listeners were included have none; in that case the
+// Listeners in the API are left alone rather than treated as stale.
+func (a *zoneApplier) applyListeners() error {
+	live, err := a.svc.Listener().Index(service.ListenerFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]api.Listener{}
+	for _, l := range live {
+		byName[l.Name] = l
+		a.have.exportListener(l)
+	}
+
+	if a.want.Listeners == nil {
+		for _, l := range live {
+			a.want.listenerKeyMap[api.ListenerKey(l.Name)] = l.ListenerKey
+		}
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.Listeners {
+		l := a.want.Listeners[i]
+		seen[l.Name] = true
+
+		cmp := l
+		cmp.ZoneKey = a.have.Zone.ZoneKey
+		cmp.ListenerKey = api.ListenerKey(l.Name)
+		cmp.Checksum = api.Checksum{}
+
+		l.ZoneKey = a.zoneKey
+		dks := make([]api.DomainKey, len(l.DomainKeys), len(l.DomainKeys))
+		for j, dk := range l.DomainKeys {
+			key, ok := a.want.domainKeyMap[dk]
+			if !ok {
+				return fmt.Errorf("listener %s refers to unknown domain %s", l.Name, dk)
+			}
+			dks[j] = key
+		}
+		l.DomainKeys = dks
+
+		if cur, ok := byName[l.Name]; ok {
+			l.ListenerKey = cur.ListenerKey
+			l.Checksum = cur.Checksum
+			if !cmp.Equals(a.have.exportListener(cur)) {
+				obj, err := a.update(objecttype.Listener, l.Name, l, cur)
+				if err != nil {
+					return err
+				}
+				l = obj.(api.Listener)
+			}
+		} else {
+			l.ListenerKey = ""
+			l.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.Listener, l.Name, l)
+			if err != nil {
+				return err
+			}
+			l = obj.(api.Listener)
+		}
+
+		a.want.listenerKeyMap[api.ListenerKey(l.Name)] = l.ListenerKey
+		a.want.Listeners[i] = l
+	}
+
+	for _, l := range live {
+		if !seen[l.Name] {
+			a.addStale(objecttype.Listener, l.Name, string(l.ListenerKey), l.Checksum)
+		}
+	}
+
+	return nil
+}
+
+func (a *zoneApplier) applyProxies() error {
+	live, err := a.svc.Proxy().Index(service.ProxyFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]api.Proxy{}
+	for _, p := range live {
+		byName[p.Name] = p
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.Proxies {
+		p := a.want.Proxies[i]
+		seen[p.Name] = true
+
+		cmp := p
+		cmp.ZoneKey = a.have.Zone.ZoneKey
+		cmp.ProxyKey = api.ProxyKey(p.Name)
+		cmp.Checksum = api.Checksum{}
+
+		p.ZoneKey = a.zoneKey
+		dks := make([]api.DomainKey, len(p.DomainKeys), len(p.DomainKeys))
+		for j, dk := range p.DomainKeys {
+			key, ok := a.want.domainKeyMap[dk]
+			if !ok {
+				return fmt.Errorf("proxy %s refers to unknown domain %s", p.Name, dk)
+			}
+			dks[j] = key
+		}
+		p.DomainKeys = dks
+
+		if p.ListenerKeys != nil {
+			lks := make([]api.ListenerKey, len(p.ListenerKeys), len(p.ListenerKeys))
+			for j, lk := range p.ListenerKeys {
+				key, ok := a.want.listenerKeyMap[lk]
+				if !ok {
+					return fmt.Errorf("proxy %s refers to unknown listener %s", p.Name, lk)
+				}
+				lks[j] = key
+			}
+			p.ListenerKeys = lks
+		}
+
+		if cur, ok := byName[p.Name]; ok {
+			p.ProxyKey = cur.ProxyKey
+			p.Checksum = cur.Checksum
+			exported := a.have.exportProxy(cur)
+			if p.ListenerKeys == nil || len(p.ListenerKeys)+len(cur.ListenerKeys) == 0 {
+				// older zone documents omit listeners, so keep the current ones
+				p.ListenerKeys = cur.ListenerKeys
+				cmp.ListenerKeys = exported.ListenerKeys
+			}
+			if !cmp.Equals(exported) {
+				obj, err := a.update(objecttype.Proxy, p.Name, p, cur)
+				if err != nil {
+					return err
+				}
+				p = obj.(api.Proxy)
+			}
+		} else {
+			p.ProxyKey = ""
+			p.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.Proxy, p.Name, p)
+			if err != nil {
+				return err
+			}
+			p = obj.(api.Proxy)
+		}
+
+		a.want.Proxies[i] = p
+	}
+
+	for _, p := range live {
+		if !seen[p.Name] {
+			a.addStale(objecttype.Proxy, p.Name, string(p.ProxyKey), p.Checksum)
+		}
+	}
+
+	return nil
+}
+
+func (a *zoneApplier) applySharedRules() error {
+	live, err := a.svc.SharedRules().Index(service.SharedRulesFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	// exporting modifies constraints in place, so export copies
+	byName := map[string]api.SharedRules{}
+	copies := map[string]api.SharedRules{}
+	for i, sr := range copySharedRules(live) {
+		byName[sr.Name] = live[i]
+		copies[sr.Name] = sr
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.SharedRules {
+		sr := a.want.SharedRules[i]
+		seen[sr.Name] = true
+
+		clearConstraintKeys(&sr.Default)
+		clearRuleKeys(sr.Rules)
+		cmp := sr
+		cmp.ZoneKey = a.have.Zone.ZoneKey
+		cmp.SharedRulesKey = api.SharedRulesKey(sr.Name)
+		cmp.Checksum = api.Checksum{}
+
+		cur, exists := byName[sr.Name]
+		// compare before denameifying, which modifies constraints in place
+		changed := !exists || !cmp.Equals(a.have.exportSharedRules(copies[sr.Name]))
+
+		sr.ZoneKey = a.zoneKey
+		a.want.denameifyAllConstraints(&sr.Default)
+		a.want.denameifyRules(sr.Rules)
+
+		if exists {
+			sr.SharedRulesKey = cur.SharedRulesKey
+			sr.Checksum = cur.Checksum
+			if changed {
+				obj, err := a.update(objecttype.SharedRules, sr.Name, sr, cur)
+				if err != nil {
+					return err
+				}
+				sr = obj.(api.SharedRules)
+			}
+		} else {
+			sr.SharedRulesKey = ""
+			sr.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.SharedRules, sr.Name, sr)
+			if err != nil {
+				return err
+			}
+			sr = obj.(api.SharedRules)
+		}
+
+		a.want.sharedRulesKeyMap[api.SharedRulesKey(sr.Name)] = sr.SharedRulesKey
+		a.want.SharedRules[i] = sr
+	}
+
+	for _, sr := range live {
+		if !seen[sr.Name] {
+			a.addStale(objecttype.SharedRules, sr.Name, string(sr.SharedRulesKey), sr.Checksum)
+		}
+	}
+
+	return nil
+}
+
+func (a *zoneApplier) applyRoutes() error {
+	live, err := a.svc.Route().Index(service.RouteFilter{ZoneKey: a.zoneKey})
+	if err != nil {
+		return err
+	}
+
+	type liveRoute struct {
+		route    api.Route
+		exported api.Route
+	}
+
+	addrs := make([]string, 0, len(live))
+	byAddr := map[string]liveRoute{}
+	// exporting modifies constraints in place, so export copies
+	copies := copyRoutes(live)
+	for i, r := range live {
+		exported := a.have.exportRoute(copies[i])
+		addr := string(exported.RouteKey)
+		addrs = append(addrs, addr)
+		byAddr[addr] = liveRoute{r, exported}
+	}
+
+	seen := map[string]bool{}
+	for i := range a.want.Routes {
+		r := a.want.Routes[i]
+		addr := fmt.Sprintf("%s%s", r.DomainKey, r.Path)
+		seen[addr] = true
+
+		clearRuleKeys(r.Rules)
+		cmp := r
+		cmp.ZoneKey = a.have.Zone.ZoneKey
+		cmp.RouteKey = api.RouteKey(addr)
+		cmp.Checksum = api.Checksum{}
+
+		cur, exists := byAddr[addr]
+		// compare before denameifying, which modifies constraints in place
+		changed := !exists || !cmp.Equals(cur.exported)
+
+		dk, ok := a.want.domainKeyMap[r.DomainKey]
+		if !ok {
+			return fmt.Errorf("route %s refers to unknown domain %s", addr, r.DomainKey)
+		}
+		srk, ok := a.want.sharedRulesKeyMap[r.SharedRulesKey]
+		if !ok {
+			return fmt.Errorf("route %s refers to unknown shared_rules %s", addr, r.SharedRulesKey)
+		}
+
+		r.ZoneKey = a.zoneKey
+		r.DomainKey = dk
+		r.SharedRulesKey = srk
+		a.want.denameifyRules(r.Rules)
+
+		if exists {
+			r.RouteKey = cur.route.RouteKey
+			r.Checksum = cur.route.Checksum
+			if changed {
+				obj, err := a.update(objecttype.Route, addr, r, cur.route)
+				if err != nil {
+					return err
+				}
+				r = obj.(api.Route)
+			}
+		} else {
+			r.RouteKey = ""
+			r.Checksum = api.Checksum{}
+			obj, err := a.create(objecttype.Route, addr, r)
+			if err != nil {
+				return err
+			}
+			r = obj.(api.Route)
+		}
+
+		a.want.Routes[i] = r
+	}
+
+	for _, addr := range addrs {
+		if !seen[addr] {
+			r := byAddr[addr].route
+			a.addStale(objecttype.Route, addr, string(r.RouteKey), r.Checksum)
+		}
+	}
+
+	return nil
+}
+
+// pruneStale deletes objects not present in the zone document, in reverse
+// dependency order, if pruning is enabled.
+func (a *zoneApplier) pruneStale() error {
+	if !a.prune {
+		if len(a.stale) > 0 && a.onConflict == "" {
+			console.Info().Printf(
+				"%d object(s) in zone %s not present in document; use --prune to delete them",
+				len(a.stale),
+				a.want.Zone.Name,
+			)
+		}
+		return nil
+	}
+
+	for i := len(a.stale) - 1; i >= 0; i-- {
+		s := a.stale[i]
+		if err := newTypelessIface(a.svc, s.ot).Delete(s.key, s.checksum); err != nil {
+			return err
+		}
+		a.record(changeDelete, s.ot, s.name)
+	}
+
+	return nil
+}
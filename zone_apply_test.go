@@ -0,0 +1,436 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/test/assert"
+)
+
+type applyTestMocks struct {
+	svc *unifiedSvc
+	mz  *service.MockZone
+	mc  *service.MockCluster
+	md  *service.MockDomain
+	ml  *service.MockListener
+	mp  *service.MockProxy
+	msr *service.MockSharedRules
+	mr  *service.MockRoute
+}
+
+func newApplyTestMocks(ctrl *gomock.Controller) applyTestMocks {
+	all := service.NewMockAll(ctrl)
+	m := applyTestMocks{
+		svc: &unifiedSvc{all, service.NewMockAdmin(ctrl)},
+		mz:  service.NewMockZone(ctrl),
+		mc:  service.NewMockCluster(ctrl),
+		md:  service.NewMockDomain(ctrl),
+		ml:  service.NewMockListener(ctrl),
+		mp:  service.NewMockProxy(ctrl),
+		msr: service.NewMockSharedRules(ctrl),
+		mr:  service.NewMockRoute(ctrl),
+	}
+	all.EXPECT().Zone().Return(m.mz).AnyTimes()
+	all.EXPECT().Cluster().Return(m.mc).AnyTimes()
+	all.EXPECT().Domain().Return(m.md).AnyTimes()
+	all.EXPECT().Listener().Return(m.ml).AnyTimes()
+	all.EXPECT().Proxy().Return(m.mp).AnyTimes()
+	all.EXPECT().SharedRules().Return(m.msr).AnyTimes()
+	all.EXPECT().Route().Return(m.mr).AnyTimes()
+	return m
+}
+
+// expectLive sets up the Index calls made for the given live objects in Zone
+// "z", whose key is "zk".
+func (m applyTestMocks) expectLive(
+	cs api.Clusters,
+	ds api.Domains,
+	srs api.SharedRulesSlice,
+	rs api.Routes,
+) {
+	m.mz.EXPECT().Index(service.ZoneFilter{Name: "z"}).Return(api.Zones{{ZoneKey: "zk", Name: "z"}}, nil)
+	m.mc.EXPECT().Index(service.ClusterFilter{ZoneKey: "zk"}).Return(cs, nil)
+	m.md.EXPECT().Index(service.DomainFilter{ZoneKey: "zk"}).Return(ds, nil)
+	m.ml.EXPECT().Index(service.ListenerFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.mp.EXPECT().Index(service.ProxyFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.msr.EXPECT().Index(service.SharedRulesFilter{ZoneKey: "zk"}).Return(srs, nil)
+	m.mr.EXPECT().Index(service.RouteFilter{ZoneKey: "zk"}).Return(rs, nil)
+}
+
+func liveCluster() api.Cluster {
+	return api.Cluster{ClusterKey: "ck", ZoneKey: "zk", Name: "c", Checksum: api.Checksum{Checksum: "c1"}}
+}
+
+func liveDomain() api.Domain {
+	return api.Domain{DomainKey: "dk", ZoneKey: "zk", Name: "d", Port: 80, Checksum: api.Checksum{Checksum: "d1"}}
+}
+
+func liveSharedRules() api.SharedRules {
+	return api.SharedRules{
+		SharedRulesKey: "srk",
+		ZoneKey:        "zk",
+		Name:           "sr",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{{ConstraintKey: "cck", ClusterKey: "ck", Weight: 1}},
+		},
+		Checksum: api.Checksum{Checksum: "sr1"},
+	}
+}
+
+func liveRoute() api.Route {
+	return api.Route{
+		RouteKey:       "rk",
+		ZoneKey:        "zk",
+		DomainKey:      "dk",
+		SharedRulesKey: "srk",
+		Path:           "/",
+		Checksum:       api.Checksum{Checksum: "r1"},
+	}
+}
+
+// applyTestDocument returns a zone document matching the live objects.
+func applyTestDocument() *zoneObjects {
+	zo := newZoneObjects()
+	zo.Zone = api.Zone{Name: "z"}
+	zo.Clusters = api.Clusters{{ClusterKey: "c", Name: "c"}}
+	zo.Domains = api.Domains{{DomainKey: "d:80", Name: "d", Port: 80}}
+	zo.SharedRules = api.SharedRulesSlice{
+		{
+			SharedRulesKey: "sr",
+			Name:           "sr",
+			Default: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 1}},
+			},
+		},
+	}
+	zo.Routes = api.Routes{
+		{RouteKey: "d:80/", DomainKey: "d:80", SharedRulesKey: "sr", Path: "/"},
+	}
+	return zo
+}
+
+func TestApplyZoneCreates(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newApplyTestMocks(ctrl)
+	gomock.InOrder(
+		m.mz.EXPECT().Index(service.ZoneFilter{Name: "z"}).Return(nil, nil),
+		m.mz.EXPECT().Create(api.Zone{Name: "z"}).Return(api.Zone{ZoneKey: "zk", Name: "z"}, nil),
+	)
+	m.mc.EXPECT().Index(service.ClusterFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.md.EXPECT().Index(service.DomainFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.ml.EXPECT().Index(service.ListenerFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.mp.EXPECT().Index(service.ProxyFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.msr.EXPECT().Index(service.SharedRulesFilter{ZoneKey: "zk"}).Return(nil, nil)
+	m.mr.EXPECT().Index(service.RouteFilter{ZoneKey: "zk"}).Return(nil, nil)
+
+	c := liveCluster()
+	d := liveDomain()
+	sr := liveSharedRules()
+	r := liveRoute()
+
+	m.mc.EXPECT().Create(api.Cluster{ZoneKey: "zk", Name: "c"}).Return(c, nil)
+	m.md.EXPECT().Create(api.Domain{ZoneKey: "zk", Name: "d", Port: 80}).Return(d, nil)
+	m.msr.EXPECT().Create(api.SharedRules{
+		ZoneKey: "zk",
+		Name:    "sr",
+		Default: api.AllConstraints{
+			Light: api.ClusterConstraints{{ClusterKey: "ck", Weight: 1}},
+		},
+	}).Return(sr, nil)
+	m.mr.EXPECT().Create(api.Route{
+		ZoneKey:        "zk",
+		DomainKey:      "dk",
+		SharedRulesKey: "srk",
+		Path:           "/",
+	}).Return(r, nil)
+
+	changes, err := applyZone(m.svc, applyTestDocument(), false)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{
+		{changeCreate, "zone", "z"},
+		{changeCreate, "cluster", "c"},
+		{changeCreate, "domain", "d:80"},
+		{changeCreate, "shared_rules", "sr"},
+		{changeCreate, "route", "d:80/"},
+	})
+}
+
+func TestApplyZoneNoChanges(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newApplyTestMocks(ctrl)
+	m.expectLive(
+		api.Clusters{liveCluster()},
+		api.Domains{liveDomain()},
+		api.SharedRulesSlice{liveSharedRules()},
+		api.Routes{liveRoute()},
+	)
+
+	changes, err := applyZone(m.svc, applyTestDocument(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, len(changes), 0)
+}
+
+func TestApplyZoneModifiesWithCurrentChecksum(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newApplyTestMocks(ctrl)
+	m.expectLive(
+		api.Clusters{liveCluster()},
+		api.Domains{liveDomain()},
+		api.SharedRulesSlice{liveSharedRules()},
+		api.Routes{liveRoute()},
+	)
+
+	modified := liveCluster()
+	modified.RequireTLS = true
+	m.mc.EXPECT().Modify(modified).Return(modified, nil)
+
+	zo := applyTestDocument()
+	zo.Clusters[0].RequireTLS = true
+
+	changes, err := applyZone(m.svc, zo, false)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{{changeModify, "cluster", "c"}})
+}
+
+func TestApplyZonePrunes(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newApplyTestMocks(ctrl)
+	stale := api.Cluster{ClusterKey: "sk", ZoneKey: "zk", Name: "stale", Checksum: api.Checksum{Checksum: "s1"}}
+	m.expectLive(
+		api.Clusters{liveCluster(), stale},
+		api.Domains{liveDomain()},
+		api.SharedRulesSlice{liveSharedRules()},
+		api.Routes{liveRoute()},
+	)
+
+	zo := applyTestDocument()
+	zo.Routes = nil
+
+	// routes are deleted before the clusters they may depend on
+	gomock.InOrder(
+		m.mr.EXPECT().Delete(api.RouteKey("rk"), api.Checksum{Checksum: "r1"}).Return(nil),
+		m.mc.EXPECT().Delete(api.ClusterKey("sk"), api.Checksum{Checksum: "s1"}).Return(nil),
+	)
+
+	changes, err := applyZone(m.svc, zo, true)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{
+		{changeDelete, "route", "d:80/"},
+		{changeDelete, "cluster", "stale"},
+	})
+}
+
+func TestApplyZoneWithoutPruneKeepsStale(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newApplyTestMocks(ctrl)
+	m.expectLive(
+		api.Clusters{liveCluster()},
+		api.Domains{liveDomain()},
+		api.SharedRulesSlice{liveSharedRules()},
+		api.Routes{liveRoute()},
+	)
+
+	zo := applyTestDocument()
+	zo.Routes = nil
+
+	changes, err := applyZone(m.svc, zo, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(changes), 0)
+}
+
+func TestApplyZoneRouteUnknownReferences(t *testing.T) {
+	for _, tc := range []struct {
+		mutate func(*api.Route)
+		want   string
+	}{
+		{
+			func(r *api.Route) { r.DomainKey = "nope:80" },
+			"route nope:80/ refers to unknown domain nope:80",
+		},
+		{
+			func(r *api.Route) { r.SharedRulesKey = "nope" },
+			"route d:80/ refers to unknown shared_rules nope",
+		},
+	} {
+		ctrl := gomock.NewController(assert.Tracing(t))
+
+		m := newApplyTestMocks(ctrl)
+		m.expectLive(
+			api.Clusters{liveCluster()},
+			api.Domains{liveDomain()},
+			api.SharedRulesSlice{liveSharedRules()},
+			api.Routes{liveRoute()},
+		)
+
+		zo := applyTestDocument()
+		tc.mutate(&zo.Routes[0])
+
+		_, err := applyZone(m.svc, zo, false)
+		assert.ErrorContains(t, err, tc.want)
+
+		ctrl.Finish()
+	}
+}
+
+// newMergeTestMocks returns mocks for a Zone "z" containing only a Cluster
+// "a" which does not require TLS.
+func newMergeTestMocks(ctrl *gomock.Controller) applyTestMocks {
+	m := newApplyTestMocks(ctrl)
+	m.mz.EXPECT().Index(service.ZoneFilter{Name: "z"}).Return(api.Zones{{ZoneKey: "zk", Name: "z"}}, nil)
+	m.mc.EXPECT().Index(service.ClusterFilter{ZoneKey: "zk"}).Return(
+		api.Clusters{
+			{ClusterKey: "ak", ZoneKey: "zk", Name: "a", Checksum: api.Checksum{Checksum: "a1"}},
+		},
+		nil,
+	)
+	m.md.EXPECT().Index(service.DomainFilter{ZoneKey: "zk"}).Return(nil, nil).AnyTimes()
+	m.ml.EXPECT().Index(service.ListenerFilter{ZoneKey: "zk"}).Return(nil, nil).AnyTimes()
+	m.mp.EXPECT().Index(service.ProxyFilter{ZoneKey: "zk"}).Return(nil, nil).AnyTimes()
+	m.msr.EXPECT().Index(service.SharedRulesFilter{ZoneKey: "zk"}).Return(nil, nil).AnyTimes()
+	m.mr.EXPECT().Index(service.RouteFilter{ZoneKey: "zk"}).Return(nil, nil).AnyTimes()
+	return m
+}
+
+func mergeTestDocument() *zoneObjects {
+	zo := newZoneObjects()
+	zo.Zone = api.Zone{Name: "z"}
+	zo.Clusters = api.Clusters{
+		{ClusterKey: "a", Name: "a", RequireTLS: true},
+		{ClusterKey: "b", Name: "b"},
+	}
+	return zo
+}
+
+func TestMergeZoneKeep(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newMergeTestMocks(ctrl)
+	m.mc.EXPECT().Create(api.Cluster{ZoneKey: "zk", Name: "b"}).Return(
+		api.Cluster{ClusterKey: "bk", ZoneKey: "zk", Name: "b"},
+		nil,
+	)
+
+	zo := mergeTestDocument()
+	changes, err := mergeZone(m.svc, zo, conflictKeep)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{
+		{changeKeep, "cluster", "a"},
+		{changeCreate, "cluster", "b"},
+	})
+	assert.False(t, zo.Clusters[0].RequireTLS)
+	assert.Equal(t, zo.clusterKeyMap["a"], api.ClusterKey("ak"))
+	assert.Equal(t, zo.clusterKeyMap["b"], api.ClusterKey("bk"))
+}
+
+func TestMergeZoneOverwrite(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newMergeTestMocks(ctrl)
+	modified := api.Cluster{
+		ClusterKey: "ak",
+		ZoneKey:    "zk",
+		Name:       "a",
+		RequireTLS: true,
+		Checksum:   api.Checksum{Checksum: "a1"},
+	}
+	m.mc.EXPECT().Modify(modified).Return(modified, nil)
+	m.mc.EXPECT().Create(api.Cluster{ZoneKey: "zk", Name: "b"}).Return(
+		api.Cluster{ClusterKey: "bk", ZoneKey: "zk", Name: "b"},
+		nil,
+	)
+
+	changes, err := mergeZone(m.svc, mergeTestDocument(), conflictOverwrite)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{
+		{changeModify, "cluster", "a"},
+		{changeCreate, "cluster", "b"},
+	})
+}
+
+func TestMergeZoneFail(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	m := newMergeTestMocks(ctrl)
+
+	changes, err := mergeZone(m.svc, mergeTestDocument(), conflictFail)
+	assert.ErrorContains(t, err, "cluster a differs from the zone document")
+	assert.Equal(t, len(changes), 0)
+}
+
+func TestMergeZoneKeepReturnsUnmodifiedConstraints(t *testing.T) {
+	ctrl := gomock.NewController(assert.Tracing(t))
+	defer ctrl.Finish()
+
+	liveRouteWithRules := func() api.Route {
+		r := liveRoute()
+		r.Rules = api.Rules{
+			{
+				RuleKey: "rlk",
+				Constraints: api.AllConstraints{
+					Light: api.ClusterConstraints{{ConstraintKey: "rck", ClusterKey: "ck", Weight: 1}},
+				},
+			},
+		}
+		return r
+	}
+
+	m := newApplyTestMocks(ctrl)
+	m.expectLive(
+		api.Clusters{liveCluster()},
+		api.Domains{liveDomain()},
+		api.SharedRulesSlice{liveSharedRules()},
+		api.Routes{liveRouteWithRules()},
+	)
+
+	zo := applyTestDocument()
+	zo.SharedRules[0].Default.Light[0].Weight = 2
+	zo.Routes[0].Rules = api.Rules{
+		{
+			RuleKey: "rlk",
+			Constraints: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 2}},
+			},
+		},
+	}
+
+	changes, err := mergeZone(m.svc, zo, conflictKeep)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, changes, []zoneChange{
+		{changeKeep, "shared_rules", "sr"},
+		{changeKeep, "route", "d:80/"},
+	})
+	assert.DeepEqual(t, zo.SharedRules[0], liveSharedRules())
+	assert.DeepEqual(t, zo.Routes[0], liveRouteWithRules())
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// fieldDiff describes a difference in a single field of an object. Path is
+// the dotted path to the field, using the object's JSON attribute names. From
+// or To are nil if the field is absent on that side.
+type fieldDiff struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// objectDiff describes the difference between two versions of an object.
+// Fields are only set for changed objects.
+type objectDiff struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Change string      `json:"change"`
+	Fields []fieldDiff `json:"fields,omitempty"`
+}
+
+func (od objectDiff) String() string {
+	var prefix string
+	switch od.Change {
+	case diffAdded:
+		prefix = "+"
+	case diffRemoved:
+		prefix = "-"
+	default:
+		prefix = "~"
+	}
+
+	str := fmt.Sprintf("%s %s %s\n", prefix, od.Type, od.Name)
+	for _, fd := range od.Fields {
+		str += fmt.Sprintf("    %s: %s => %s\n", fd.Path, diffValueStr(fd.From), diffValueStr(fd.To))
+	}
+	return str
+}
+
+func diffValueStr(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// normalize strips a name-keyed zoneObjects of everything that would create
+// noise when compared with another zone: ZoneKeys, Checksums, Cluster
+// Instances, and the keys of rules and constraints. Object keys are replaced
+// with the names used to match objects between zones.
+func (zo *zoneObjects) normalize() {
+	zo.Zone.ZoneKey = ""
+	zo.Zone.Checksum = api.Checksum{}
+
+	for i := range zo.Clusters {
+		c := &zo.Clusters[i]
+		c.ZoneKey = ""
+		c.ClusterKey = api.ClusterKey(c.Name)
+		c.Instances = nil
+		c.Checksum = api.Checksum{}
+	}
+
+	for i := range zo.Domains {
+		d := &zo.Domains[i]
+		d.ZoneKey = ""
+		d.DomainKey = api.DomainKey(d.Addr())
+		d.Checksum = api.Checksum{}
+	}
+
+	for i := range zo.Listeners {
+		l := &zo.Listeners[i]
+		l.ZoneKey = ""
+		l.ListenerKey = api.ListenerKey(l.Name)
+		l.Checksum = api.Checksum{}
+	}
+
+	for i := range zo.Proxies {
+		p := &zo.Proxies[i]
+		p.ZoneKey = ""
+		p.ProxyKey = api.ProxyKey(p.Name)
+		p.Checksum = api.Checksum{}
+	}
+
+	for i := range zo.SharedRules {
+		sr := &zo.SharedRules[i]
+		sr.ZoneKey = ""
+		sr.SharedRulesKey = api.SharedRulesKey(sr.Name)
+		sr.Checksum = api.Checksum{}
+		clearConstraintKeys(&sr.Default)
+		clearRuleKeys(sr.Rules)
+	}
+
+	for i := range zo.Routes {
+		r := &zo.Routes[i]
+		r.ZoneKey = ""
+		r.RouteKey = api.RouteKey(fmt.Sprintf("%s%s", r.DomainKey, r.Path))
+		r.Checksum = api.Checksum{}
+		clearRuleKeys(r.Rules)
+	}
+}
+
+// namedObjects returns the objects of the given normalized zoneObjects, keyed
+// by the names used to match them between zones, for each object type in
+// dependency order.
+func (zo *zoneObjects) namedObjects() []namedObjectSet {
+	clusters := map[string]interface{}{}
+	for _, c := range zo.Clusters {
+		clusters[string(c.ClusterKey)] = c
+	}
+
+	domains := map[string]interface{}{}
+	for _, d := range zo.Domains {
+		domains[string(d.DomainKey)] = d
+	}
+
+	listeners := map[string]interface{}{}
+	for _, l := range zo.Listeners {
+		listeners[string(l.ListenerKey)] = l
+	}
+
+	proxies := map[string]interface{}{}
+	for _, p := range zo.Proxies {
+		proxies[string(p.ProxyKey)] = p
+	}
+
+	srs := map[string]interface{}{}
+	for _, sr := range zo.SharedRules {
+		srs[string(sr.SharedRulesKey)] = sr
+	}
+
+	routes := map[string]interface{}{}
+	for _, r := range zo.Routes {
+		routes[string(r.RouteKey)] = r
+	}
+
+	return []namedObjectSet{
+		{objecttype.Cluster, clusters},
+		{objecttype.Domain, domains},
+		{objecttype.Listener, listeners},
+		{objecttype.Proxy, proxies},
+		{objecttype.SharedRules, srs},
+		{objecttype.Route, routes},
+	}
+}
+
+type namedObjectSet struct {
+	ot      objecttype.ObjectType
+	objects map[string]interface{}
+}
+
+// diffZones returns the object-by-object differences between two normalized
+// zoneObjects, ordered by object type and then by name.
+func diffZones(from, to *zoneObjects) ([]objectDiff, error) {
+	fromSets := from.namedObjects()
+	toSets := to.namedObjects()
+
+	result := []objectDiff{}
+	for i := range fromSets {
+		ot := fromSets[i].ot
+		fromObjs := fromSets[i].objects
+		toObjs := toSets[i].objects
+
+		names := []string{}
+		for name := range fromObjs {
+			names = append(names, name)
+		}
+		for name := range toObjs {
+			if _, ok := fromObjs[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			f, inFrom := fromObjs[name]
+			t, inTo := toObjs[name]
+
+			switch {
+			case !inFrom:
+				result = append(result, objectDiff{Type: ot.Name, Name: name, Change: diffAdded})
+			case !inTo:
+				result = append(result, objectDiff{Type: ot.Name, Name: name, Change: diffRemoved})
+			default:
+				fields, err := diffObjects(f, t)
+				if err != nil {
+					return nil, err
+				}
+				if len(fields) > 0 {
+					result = append(
+						result,
+						objectDiff{Type: ot.Name, Name: name, Change: diffChanged, Fields: fields},
+					)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// diffObjects returns the field-level differences between two objects, based
+// on their JSON representations.
+func diffObjects(from, to interface{}) ([]fieldDiff, error) {
+	f, err := toGeneric(from)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := toGeneric(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValues("", f, t), nil
+}
+
+func toGeneric(obj interface{}) (interface{}, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func joinPath(path, elem string) string {
+	if path == "" {
+		return elem
+	}
+	if strings.HasPrefix(elem, "[") {
+		return path + elem
+	}
+	return path + "." + elem
+}
+
+// diffValues recursively compares two values decoded from JSON. Maps are
+// compared key by key, and slices index by index.
+func diffValues(path string, from, to interface{}) []fieldDiff {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := []string{}
+		for k := range f {
+			keys = append(keys, k)
+		}
+		for k := range t {
+			if _, ok := f[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		result := []fieldDiff{}
+		for _, k := range keys {
+			result = append(result, diffValues(joinPath(path, k), f[k], t[k])...)
+		}
+		return result
+
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+
+		result := []fieldDiff{}
+		for i := 0; i < len(f) || i < len(t); i++ {
+			var fv, tv interface{}
+			if i < len(f) {
+				fv = f[i]
+			}
+			if i < len(t) {
+				tv = t[i]
+			}
+			result = append(result, diffValues(joinPath(path, fmt.Sprintf("[%d]", i)), fv, tv)...)
+		}
+		return result
+	}
+
+	if reflect.DeepEqual(from, to) || (isEmptyValue(from) && isEmptyValue(to)) {
+		return nil
+	}
+
+	return []fieldDiff{{Path: path, From: from, To: to}}
+}
+
+// isEmptyValue returns true for values decoded from JSON that are equivalent
+// to an omitted value: null, empty arrays and empty objects.
+func isEmptyValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		return len(t) == 0
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/cli/command"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestDiffValues(t *testing.T) {
+	from := map[string]interface{}{
+		"name":    "foo",
+		"port":    80.0,
+		"aliases": []interface{}{"a", "b"},
+		"nested":  map[string]interface{}{"x": true},
+		"empty":   []interface{}{},
+	}
+	to := map[string]interface{}{
+		"name":    "foo",
+		"port":    443.0,
+		"aliases": []interface{}{"a"},
+		"nested":  map[string]interface{}{"x": false, "y": "new"},
+	}
+
+	assert.DeepEqual(t, diffValues("", from, to), []fieldDiff{
+		{Path: "aliases[1]", From: "b", To: nil},
+		{Path: "nested.x", From: true, To: false},
+		{Path: "nested.y", From: nil, To: "new"},
+		{Path: "port", From: 80.0, To: 443.0},
+	})
+}
+
+func TestDiffZonesIgnoresKeysAndChecksums(t *testing.T) {
+	from := newZoneObjects()
+	from.Zone = api.Zone{ZoneKey: "prod", Name: "prod"}
+	from.Clusters = api.Clusters{
+		{
+			ClusterKey: "c1",
+			ZoneKey:    "prod",
+			Name:       "foo",
+			Instances:  api.Instances{{Host: "1.2.3.4", Port: 80}},
+			Checksum:   api.Checksum{Checksum: "abc"},
+		},
+	}
+
+	to := newZoneObjects()
+	to.Zone = api.Zone{ZoneKey: "staging", Name: "staging"}
+	to.Clusters = api.Clusters{
+		{ClusterKey: "foo", ZoneKey: "staging", Name: "foo"},
+	}
+
+	from.normalize()
+	to.normalize()
+
+	diffs, err := diffZones(from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, len(diffs), 0)
+}
+
+func TestDiffZones(t *testing.T) {
+	from := newZoneObjects()
+	from.Clusters = api.Clusters{{Name: "foo"}, {Name: "bar"}}
+	from.Domains = api.Domains{{Name: "example.com", Port: 80}}
+
+	to := newZoneObjects()
+	to.Clusters = api.Clusters{{Name: "foo", RequireTLS: true}, {Name: "baz"}}
+	to.Domains = api.Domains{{Name: "example.com", Port: 80}}
+
+	from.normalize()
+	to.normalize()
+
+	diffs, err := diffZones(from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, len(diffs), 3)
+	assert.DeepEqual(t, diffs[0], objectDiff{Type: objecttype.Cluster.Name, Name: "bar", Change: diffRemoved})
+	assert.DeepEqual(t, diffs[1], objectDiff{Type: objecttype.Cluster.Name, Name: "baz", Change: diffAdded})
+	assert.Equal(t, diffs[2].Name, "foo")
+	assert.Equal(t, diffs[2].Change, diffChanged)
+	assert.Equal(t, len(diffs[2].Fields), 1)
+	assert.Equal(t, diffs[2].Fields[0].Path, "require_tls")
+	assert.Equal(t, diffs[2].Fields[0].To, true)
+}
+
+func TestDiffZoneJSONKeepsCountOffStdout(t *testing.T) {
+	objs := mkSelectorTestObjs(t)
+	r := &diffZoneRunner{cfg: *objs.gc, json: true}
+
+	cerr := r.run(&command.Cmd{}, []string{"prod", "staging"})
+	assert.Equal(t, cerr.Code, command.CmdErrCodeError)
+	assert.Equal(t, cerr.Message, "")
+
+	cerr = r.run(&command.Cmd{}, []string{"prod", "prod"})
+	assert.Equal(t, cerr, command.NoError())
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/turbinelabs/api"
+)
+
+// zoneFilter selects a consistent subset of an exported, name-keyed Zone.
+// Routes are selected by Domain address and path prefix. The SharedRules and
+// Clusters referenced by selected Routes, and the Domains they belong to, are
+// included automatically, as are any Domains and Clusters named explicitly.
+type zoneFilter struct {
+	domains      []string
+	clusters     []string
+	routesPrefix string
+}
+
+// enabled returns true if any selection criteria were specified.
+func (f zoneFilter) enabled() bool {
+	return len(f.domains) > 0 || len(f.clusters) > 0 || f.routesPrefix != ""
+}
+
+// selectsRoutes returns true if Routes are selected by the filter.
+func (f zoneFilter) selectsRoutes() bool {
+	return len(f.domains) > 0 || f.routesPrefix != ""
+}
+
+// apply returns a new zoneObjects containing the objects selected by the
+// filter. Proxies are never included, and Listeners are left unspecified, so
+// that applying the result does not alter either. It is an error to name a
+// Domain or Cluster which is not present in the Zone.
+func (f zoneFilter) apply(zo *zoneObjects) (*zoneObjects, error) {
+	if !f.enabled() {
+		return zo, nil
+	}
+
+	domainsByAddr := map[api.DomainKey]api.Domain{}
+	for _, d := range zo.Domains {
+		domainsByAddr[d.DomainKey] = d
+	}
+
+	clustersByName := map[api.ClusterKey]api.Cluster{}
+	for _, c := range zo.Clusters {
+		clustersByName[c.ClusterKey] = c
+	}
+
+	srsByName := map[api.SharedRulesKey]api.SharedRules{}
+	for _, sr := range zo.SharedRules {
+		srsByName[sr.SharedRulesKey] = sr
+	}
+
+	wantDomains := map[api.DomainKey]bool{}
+	for _, addr := range f.domains {
+		dk := api.DomainKey(addr)
+		if _, ok := domainsByAddr[dk]; !ok {
+			return nil, fmt.Errorf("no domain %s in zone %s", addr, zo.Zone.Name)
+		}
+		wantDomains[dk] = true
+	}
+
+	wantClusters := map[api.ClusterKey]bool{}
+	for _, name := range f.clusters {
+		ck := api.ClusterKey(name)
+		if _, ok := clustersByName[ck]; !ok {
+			return nil, fmt.Errorf("no cluster %s in zone %s", name, zo.Zone.Name)
+		}
+		wantClusters[ck] = true
+	}
+
+	wantSharedRules := map[api.SharedRulesKey]bool{}
+
+	out := newZoneObjects()
+	out.Zone = zo.Zone
+	out.Clusters = api.Clusters{}
+	out.Domains = api.Domains{}
+	out.Proxies = api.Proxies{}
+	out.Routes = api.Routes{}
+	out.SharedRules = api.SharedRulesSlice{}
+
+	if f.selectsRoutes() {
+		for _, r := range zo.Routes {
+			if len(f.domains) > 0 && !wantDomains[r.DomainKey] {
+				continue
+			}
+			if !strings.HasPrefix(r.Path, f.routesPrefix) {
+				continue
+			}
+
+			out.Routes = append(out.Routes, r)
+			wantDomains[r.DomainKey] = true
+			wantSharedRules[r.SharedRulesKey] = true
+			addRulesClusters(wantClusters, r.Rules)
+		}
+	}
+
+	for _, sr := range zo.SharedRules {
+		if wantSharedRules[sr.SharedRulesKey] {
+			out.SharedRules = append(out.SharedRules, sr)
+			addAllConstraintsClusters(wantClusters, sr.Default)
+			addRulesClusters(wantClusters, sr.Rules)
+		}
+	}
+
+	for _, c := range zo.Clusters {
+		if wantClusters[c.ClusterKey] {
+			out.Clusters = append(out.Clusters, c)
+		}
+	}
+
+	for _, d := range zo.Domains {
+		if wantDomains[d.DomainKey] {
+			out.Domains = append(out.Domains, d)
+		}
+	}
+
+	return out, nil
+}
+
+func addRulesClusters(cks map[api.ClusterKey]bool, rs api.Rules) {
+	for _, r := range rs {
+		addAllConstraintsClusters(cks, r.Constraints)
+	}
+}
+
+func addAllConstraintsClusters(cks map[api.ClusterKey]bool, ac api.AllConstraints) {
+	for _, ccs := range []api.ClusterConstraints{ac.Light, ac.Dark, ac.Tap} {
+		for _, cc := range ccs {
+			cks[cc.ClusterKey] = true
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/test/assert"
+)
+
+func filterTestZone() *zoneObjects {
+	zo := newZoneObjects()
+	zo.Zone = api.Zone{ZoneKey: "z", Name: "z"}
+	zo.Clusters = api.Clusters{
+		{ClusterKey: "api", Name: "api"},
+		{ClusterKey: "api-canary", Name: "api-canary"},
+		{ClusterKey: "web", Name: "web"},
+		{ClusterKey: "batch", Name: "batch"},
+	}
+	zo.Domains = api.Domains{
+		{DomainKey: "api.example.com:443", Name: "api.example.com", Port: 443},
+		{DomainKey: "www.example.com:443", Name: "www.example.com", Port: 443},
+	}
+	zo.Listeners = api.Listeners{{ListenerKey: "l", Name: "l", Port: 443}}
+	zo.Proxies = api.Proxies{{ProxyKey: "p", Name: "p"}}
+	zo.SharedRules = api.SharedRulesSlice{
+		{
+			SharedRulesKey: "api-rules",
+			Name:           "api-rules",
+			Default: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "api", Weight: 1}},
+			},
+		},
+		{
+			SharedRulesKey: "web-rules",
+			Name:           "web-rules",
+			Default: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "web", Weight: 1}},
+			},
+		},
+	}
+	zo.Routes = api.Routes{
+		{
+			RouteKey:       "api.example.com:443/api/users",
+			DomainKey:      "api.example.com:443",
+			SharedRulesKey: "api-rules",
+			Path:           "/api/users",
+			Rules: api.Rules{
+				{
+					Constraints: api.AllConstraints{
+						Light: api.ClusterConstraints{{ClusterKey: "api-canary", Weight: 1}},
+					},
+				},
+			},
+		},
+		{
+			RouteKey:       "api.example.com:443/health",
+			DomainKey:      "api.example.com:443",
+			SharedRulesKey: "api-rules",
+			Path:           "/health",
+		},
+		{
+			RouteKey:       "www.example.com:443/",
+			DomainKey:      "www.example.com:443",
+			SharedRulesKey: "web-rules",
+			Path:           "/",
+		},
+	}
+	return zo
+}
+
+func clusterNames(cs api.Clusters) []string {
+	names := []string{}
+	for _, c := range cs {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestZoneFilterDisabled(t *testing.T) {
+	zo := filterTestZone()
+	got, err := zoneFilter{}.apply(zo)
+	assert.Nil(t, err)
+	assert.SameInstance(t, got, zo)
+}
+
+func TestZoneFilterRoutesPrefix(t *testing.T) {
+	got, err := zoneFilter{routesPrefix: "/api", clusters: []string{"batch"}}.apply(filterTestZone())
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(got.Routes), 1)
+	assert.Equal(t, got.Routes[0].Path, "/api/users")
+	assert.Equal(t, len(got.SharedRules), 1)
+	assert.Equal(t, got.SharedRules[0].Name, "api-rules")
+	assert.HasSameElements(t, clusterNames(got.Clusters), []string{"api", "api-canary", "batch"})
+	assert.Equal(t, len(got.Domains), 1)
+	assert.Equal(t, got.Domains[0].Name, "api.example.com")
+	assert.Equal(t, len(got.Proxies), 0)
+	assert.Nil(t, got.Listeners)
+}
+
+func TestZoneFilterDomains(t *testing.T) {
+	got, err := zoneFilter{domains: []string{"www.example.com:443"}}.apply(filterTestZone())
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(got.Routes), 1)
+	assert.Equal(t, got.Routes[0].DomainKey, api.DomainKey("www.example.com:443"))
+	assert.Equal(t, len(got.SharedRules), 1)
+	assert.Equal(t, got.SharedRules[0].Name, "web-rules")
+	assert.DeepEqual(t, clusterNames(got.Clusters), []string{"web"})
+}
+
+func TestZoneFilterClustersOnly(t *testing.T) {
+	got, err := zoneFilter{clusters: []string{"batch"}}.apply(filterTestZone())
+	assert.Nil(t, err)
+
+	assert.DeepEqual(t, clusterNames(got.Clusters), []string{"batch"})
+	assert.Equal(t, len(got.Routes), 0)
+	assert.Equal(t, len(got.SharedRules), 0)
+	assert.Equal(t, len(got.Domains), 0)
+}
+
+func TestZoneFilterUnknown(t *testing.T) {
+	_, err := zoneFilter{domains: []string{"nope:80"}}.apply(filterTestZone())
+	assert.ErrorContains(t, err, "no domain nope:80")
+
+	_, err = zoneFilter{clusters: []string{"nope"}}.apply(filterTestZone())
+	assert.ErrorContains(t, err, "no cluster nope")
+}
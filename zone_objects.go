@@ -30,12 +30,14 @@ type zoneObjects struct {
 	Zone        api.Zone             `json:"zone"`
 	Clusters    api.Clusters         `json:"clusters"`
 	Domains     api.Domains          `json:"domains"`
+	Listeners   api.Listeners        `json:"listeners"`
 	Proxies     api.Proxies          `json:"proxies"`
 	Routes      api.Routes           `json:"routes"`
 	SharedRules api.SharedRulesSlice `json:"shared_rules"`
 
 	clusterKeyMap     map[api.ClusterKey]api.ClusterKey
 	domainKeyMap      map[api.DomainKey]api.DomainKey
+	listenerKeyMap    map[api.ListenerKey]api.ListenerKey
 	sharedRulesKeyMap map[api.SharedRulesKey]api.SharedRulesKey
 }
 
@@ -55,6 +57,7 @@ func (zo *zoneObjects) nameifyAllConstraints(ac *api.AllConstraints) {
 func (zo *zoneObjects) nameifyClusterConstraints(ccs api.ClusterConstraints) {
 	for i := range ccs {
 		ccs[i].ClusterKey = zo.clusterKeyMap[ccs[i].ClusterKey]
+		ccs[i].ConstraintKey = ""
 	}
 }
 
@@ -77,52 +80,190 @@ func (zo *zoneObjects) denameifyClusterConstraints(ccs api.ClusterConstraints) {
 	}
 }
 
+func copySharedRules(srs api.SharedRulesSlice) api.SharedRulesSlice {
+	result := make(api.SharedRulesSlice, len(srs))
+	for i, sr := range srs {
+		sr.Default = copyAllConstraints(sr.Default)
+		sr.Rules = copyRules(sr.Rules)
+		result[i] = sr
+	}
+	return result
+}
+
+func copyRoutes(rs api.Routes) api.Routes {
+	result := make(api.Routes, len(rs))
+	for i, r := range rs {
+		r.Rules = copyRules(r.Rules)
+		result[i] = r
+	}
+	return result
+}
+
+func copyRules(rs api.Rules) api.Rules {
+	if rs == nil {
+		return nil
+	}
+	result := make(api.Rules, len(rs))
+	for i, r := range rs {
+		r.Constraints = copyAllConstraints(r.Constraints)
+		result[i] = r
+	}
+	return result
+}
+
+func copyAllConstraints(ac api.AllConstraints) api.AllConstraints {
+	cp := func(ccs api.ClusterConstraints) api.ClusterConstraints {
+		if ccs == nil {
+			return nil
+		}
+		return append(api.ClusterConstraints{}, ccs...)
+	}
+	return api.AllConstraints{Light: cp(ac.Light), Dark: cp(ac.Dark), Tap: cp(ac.Tap)}
+}
+
+// exportZone returns a copy of the Zone with its key replaced by its name.
+func (zo *zoneObjects) exportZone(z api.Zone) api.Zone {
+	z.ZoneKey = api.ZoneKey(z.Name)
+	z.Checksum = api.Checksum{}
+	return z
+}
+
+// exportCluster returns a copy of the Cluster with its key replaced by its name
+// and its Instances removed. The key mapping is recorded for use by subsequent
+// exports of objects which refer to the Cluster.
+func (zo *zoneObjects) exportCluster(c api.Cluster) api.Cluster {
+	ck := api.ClusterKey(c.Name)
+	zo.clusterKeyMap[c.ClusterKey] = ck
+	c.ZoneKey = zo.Zone.ZoneKey
+	c.ClusterKey = ck
+	c.Instances = nil
+	c.Checksum = api.Checksum{}
+	return c
+}
+
+// exportDomain returns a copy of the Domain with its key replaced by its
+// address. The key mapping is recorded for use by subsequent exports of
+// objects which refer to the Domain.
+func (zo *zoneObjects) exportDomain(d api.Domain) api.Domain {
+	dk := api.DomainKey(d.Addr())
+	zo.domainKeyMap[d.DomainKey] = dk
+	d.ZoneKey = zo.Zone.ZoneKey
+	d.DomainKey = dk
+	d.Checksum = api.Checksum{}
+	return d
+}
+
+// exportListener returns a copy of the Listener with its key replaced by its
+// name, and its DomainKeys replaced by the corresponding Domain addresses. The
+// key mapping is recorded for use by subsequent exports of objects which refer
+// to the Listener.
+func (zo *zoneObjects) exportListener(l api.Listener) api.Listener {
+	lk := api.ListenerKey(l.Name)
+	zo.listenerKeyMap[l.ListenerKey] = lk
+	l.ZoneKey = zo.Zone.ZoneKey
+	l.ListenerKey = lk
+	dks := make([]api.DomainKey, len(l.DomainKeys), len(l.DomainKeys))
+	for i, dk := range l.DomainKeys {
+		dks[i] = zo.domainKeyMap[dk]
+	}
+	l.DomainKeys = dks
+	l.Checksum = api.Checksum{}
+	return l
+}
+
+// exportProxy returns a copy of the Proxy with its key replaced by its name,
+// its DomainKeys replaced by the corresponding Domain addresses, and its
+// ListenerKeys replaced by the corresponding Listener names.
+func (zo *zoneObjects) exportProxy(p api.Proxy) api.Proxy {
+	p.ZoneKey = zo.Zone.ZoneKey
+	dks := make([]api.DomainKey, len(p.DomainKeys), len(p.DomainKeys))
+	for i, dk := range p.DomainKeys {
+		dks[i] = zo.domainKeyMap[dk]
+	}
+	p.DomainKeys = dks
+	if p.ListenerKeys != nil {
+		lks := make([]api.ListenerKey, len(p.ListenerKeys), len(p.ListenerKeys))
+		for i, lk := range p.ListenerKeys {
+			lks[i] = zo.listenerKeyMap[lk]
+		}
+		p.ListenerKeys = lks
+	}
+	p.ProxyKey = api.ProxyKey(p.Name)
+	p.Checksum = api.Checksum{}
+	return p
+}
+
+// exportSharedRules returns a copy of the SharedRules with its key replaced by
+// its name, and ClusterKeys in its constraints replaced by Cluster names. The
+// key mapping is recorded for use by subsequent exports of objects which refer
+// to the SharedRules. Note that constraints are modified in place.
+func (zo *zoneObjects) exportSharedRules(sr api.SharedRules) api.SharedRules {
+	srk := api.SharedRulesKey(sr.Name)
+	zo.sharedRulesKeyMap[sr.SharedRulesKey] = srk
+	sr.ZoneKey = zo.Zone.ZoneKey
+	sr.SharedRulesKey = srk
+	sr.Checksum = api.Checksum{}
+	zo.nameifyAllConstraints(&sr.Default)
+	zo.nameifyRules(sr.Rules)
+	return sr
+}
+
+// exportRoute returns a copy of the Route with its key replaced by its
+// address and path, and with its DomainKey, SharedRulesKey and the ClusterKeys
+// in its constraints replaced by names. Note that constraints are modified in
+// place.
+func (zo *zoneObjects) exportRoute(r api.Route) api.Route {
+	r.ZoneKey = zo.Zone.ZoneKey
+	r.DomainKey = zo.domainKeyMap[r.DomainKey]
+	r.SharedRulesKey = zo.sharedRulesKeyMap[r.SharedRulesKey]
+	r.RouteKey = api.RouteKey(fmt.Sprintf("%s%s", r.DomainKey, r.Path))
+	r.Checksum = api.Checksum{}
+	zo.nameifyRules(r.Rules)
+	return r
+}
+
 func newZoneObjects() *zoneObjects {
 	return &zoneObjects{
 		clusterKeyMap:     map[api.ClusterKey]api.ClusterKey{},
 		domainKeyMap:      map[api.DomainKey]api.DomainKey{},
+		listenerKeyMap:    map[api.ListenerKey]api.ListenerKey{},
 		sharedRulesKeyMap: map[api.SharedRulesKey]api.SharedRulesKey{},
 	}
 }
 
-// exportZone exports the zone with a ZoneKey or Name matching the given string,
-// and with object keys replaced by human-readable names.
-func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
-	var z api.Zone
-
+// findZone returns the zone with a Name or ZoneKey matching the given string.
+// Names take precedence over keys.
+func findZone(svc service.All, keyOrName string) (api.Zone, error) {
 	zs, err := svc.Zone().Index(service.ZoneFilter{Name: keyOrName})
 	if err != nil {
-		return nil, err
+		return api.Zone{}, err
 	}
 
 	if len(zs) == 1 {
-		z = zs[0]
-	} else {
-		z, err = svc.Zone().Get(api.ZoneKey(keyOrName))
-		if err != nil {
-			return nil, err
-		}
+		return zs[0], nil
 	}
 
-	zk := z.ZoneKey
-	z.ZoneKey = api.ZoneKey(z.Name)
-	z.Checksum = api.Checksum{}
+	return svc.Zone().Get(api.ZoneKey(keyOrName))
+}
 
+// exportZone exports the zone with a ZoneKey or Name matching the given string,
+// and with object keys replaced by human-readable names.
+func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
+	z, err := findZone(svc, keyOrName)
+	if err != nil {
+		return nil, err
+	}
+
+	zk := z.ZoneKey
 	zo := newZoneObjects()
-	zo.Zone = z
+	zo.Zone = zo.exportZone(z)
 
 	cs, err := svc.Cluster().Index(service.ClusterFilter{ZoneKey: zk})
 	if err != nil {
 		return nil, err
 	}
 	for _, c := range cs {
-		ck := api.ClusterKey(c.Name)
-		zo.clusterKeyMap[c.ClusterKey] = ck
-		c.ZoneKey = zo.Zone.ZoneKey
-		c.ClusterKey = ck
-		c.Instances = nil
-		c.Checksum = api.Checksum{}
-		zo.Clusters = append(zo.Clusters, c)
+		zo.Clusters = append(zo.Clusters, zo.exportCluster(c))
 	}
 
 	ds, err := svc.Domain().Index(service.DomainFilter{ZoneKey: zk})
@@ -130,12 +271,15 @@ func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
 		return nil, err
 	}
 	for _, d := range ds {
-		dk := api.DomainKey(d.Addr())
-		zo.domainKeyMap[d.DomainKey] = dk
-		d.ZoneKey = zo.Zone.ZoneKey
-		d.DomainKey = dk
-		d.Checksum = api.Checksum{}
-		zo.Domains = append(zo.Domains, d)
+		zo.Domains = append(zo.Domains, zo.exportDomain(d))
+	}
+
+	ls, err := svc.Listener().Index(service.ListenerFilter{ZoneKey: zk})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ls {
+		zo.Listeners = append(zo.Listeners, zo.exportListener(l))
 	}
 
 	ps, err := svc.Proxy().Index(service.ProxyFilter{ZoneKey: zk})
@@ -143,15 +287,7 @@ func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
 		return nil, err
 	}
 	for _, p := range ps {
-		p.ZoneKey = zo.Zone.ZoneKey
-		dks := make([]api.DomainKey, len(p.DomainKeys), len(p.DomainKeys))
-		for i, dk := range p.DomainKeys {
-			dks[i] = zo.domainKeyMap[dk]
-		}
-		p.DomainKeys = dks
-		p.ProxyKey = api.ProxyKey(p.Name)
-		p.Checksum = api.Checksum{}
-		zo.Proxies = append(zo.Proxies, p)
+		zo.Proxies = append(zo.Proxies, zo.exportProxy(p))
 	}
 
 	srs, err := svc.SharedRules().Index(service.SharedRulesFilter{ZoneKey: zk})
@@ -159,14 +295,7 @@ func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
 		return nil, err
 	}
 	for _, sr := range srs {
-		srk := api.SharedRulesKey(sr.Name)
-		zo.sharedRulesKeyMap[sr.SharedRulesKey] = srk
-		sr.ZoneKey = zo.Zone.ZoneKey
-		sr.SharedRulesKey = srk
-		sr.Checksum = api.Checksum{}
-		zo.nameifyAllConstraints(&sr.Default)
-		zo.nameifyRules(sr.Rules)
-		zo.SharedRules = append(zo.SharedRules, sr)
+		zo.SharedRules = append(zo.SharedRules, zo.exportSharedRules(sr))
 	}
 
 	rs, err := svc.Route().Index(service.RouteFilter{ZoneKey: zk})
@@ -174,13 +303,7 @@ func exportZone(svc service.All, keyOrName string) (*zoneObjects, error) {
 		return nil, err
 	}
 	for _, r := range rs {
-		r.ZoneKey = zo.Zone.ZoneKey
-		r.DomainKey = zo.domainKeyMap[r.DomainKey]
-		r.SharedRulesKey = zo.sharedRulesKeyMap[r.SharedRulesKey]
-		r.RouteKey = api.RouteKey(fmt.Sprintf("%s%s", r.DomainKey, r.Path))
-		r.Checksum = api.Checksum{}
-		zo.nameifyRules(r.Rules)
-		zo.Routes = append(zo.Routes, r)
+		zo.Routes = append(zo.Routes, zo.exportRoute(r))
 	}
 
 	return zo, nil
@@ -195,6 +318,13 @@ func importZone(svc service.All, name string, cdc codec.Codec, txt string) (*zon
 		return nil, err
 	}
 
+	return storeZone(svc, name, zo)
+}
+
+// storeZone stores the name-keyed zoneObjects in the API as a new Zone with
+// the given name. Objects are stored in dependency order, to maintain
+// referential integrity.
+func storeZone(svc service.All, name string, zo *zoneObjects) (*zoneObjects, error) {
 	var err error
 	zo.Zone.Name = name
 	zo.Zone.ZoneKey = ""
@@ -227,6 +357,24 @@ func importZone(svc service.All, name string, cdc codec.Codec, txt string) (*zon
 		zo.Domains[i] = d
 	}
 
+	for i := range zo.Listeners {
+		l := zo.Listeners[i]
+		l.ZoneKey = zo.Zone.ZoneKey
+		l.ListenerKey = ""
+		length := len(l.DomainKeys)
+		dks := make([]api.DomainKey, length, length)
+		for i, dk := range l.DomainKeys {
+			dks[i] = zo.domainKeyMap[dk]
+		}
+		l.DomainKeys = dks
+		l, err = svc.Listener().Create(l)
+		if err != nil {
+			return nil, err
+		}
+		zo.listenerKeyMap[api.ListenerKey(l.Name)] = l.ListenerKey
+		zo.Listeners[i] = l
+	}
+
 	for i := range zo.Proxies {
 		p := zo.Proxies[i]
 		p.ZoneKey = zo.Zone.ZoneKey
@@ -237,6 +385,14 @@ func importZone(svc service.All, name string, cdc codec.Codec, txt string) (*zon
 			dks[i] = zo.domainKeyMap[dk]
 		}
 		p.DomainKeys = dks
+		if p.ListenerKeys != nil {
+			length = len(p.ListenerKeys)
+			lks := make([]api.ListenerKey, length, length)
+			for i, lk := range p.ListenerKeys {
+				lks[i] = zo.listenerKeyMap[lk]
+			}
+			p.ListenerKeys = lks
+		}
 		p, err = svc.Proxy().Create(p)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestExportListenerAndProxyUseNames(t *testing.T) {
+	zo := newZoneObjects()
+	zo.Zone = zo.exportZone(api.Zone{ZoneKey: "zk", Name: "z"})
+	zo.exportDomain(api.Domain{DomainKey: "dk", Name: "d", Port: 80})
+
+	l := zo.exportListener(api.Listener{
+		ListenerKey: "lk",
+		ZoneKey:     "zk",
+		Name:        "main",
+		Port:        80,
+		DomainKeys:  []api.DomainKey{"dk"},
+		Checksum:    api.Checksum{Checksum: "cs"},
+	})
+	assert.DeepEqual(t, l, api.Listener{
+		ListenerKey: "main",
+		ZoneKey:     "z",
+		Name:        "main",
+		Port:        80,
+		DomainKeys:  []api.DomainKey{"d:80"},
+	})
+
+	p := zo.exportProxy(api.Proxy{
+		ProxyKey:     "pk",
+		ZoneKey:      "zk",
+		Name:         "p",
+		DomainKeys:   []api.DomainKey{"dk"},
+		ListenerKeys: []api.ListenerKey{"lk"},
+	})
+	assert.DeepEqual(t, p.DomainKeys, []api.DomainKey{"d:80"})
+	assert.DeepEqual(t, p.ListenerKeys, []api.ListenerKey{"main"})
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbinelabs/api"
+)
+
+// hostRewrite replaces Domain names and aliases matching a glob-style pattern,
+// in which each '*' matches any sequence of characters. Each '*' in the
+// replacement is filled in with the text matched by the corresponding '*' in
+// the pattern.
+type hostRewrite struct {
+	from    string
+	to      string
+	pattern *regexp.Regexp
+}
+
+// parseHostRewrite parses a rule of the form <pattern>=<replacement>.
+func parseHostRewrite(s string) (hostRewrite, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return hostRewrite{}, fmt.Errorf("malformed domain rewrite %q: expected <pattern>=<replacement>", s)
+	}
+
+	from, to := parts[0], parts[1]
+	if strings.Count(to, "*") > strings.Count(from, "*") {
+		return hostRewrite{}, fmt.Errorf(
+			"malformed domain rewrite %q: replacement has more wildcards than pattern",
+			s,
+		)
+	}
+
+	globs := strings.Split(from, "*")
+	for i := range globs {
+		globs[i] = regexp.QuoteMeta(globs[i])
+	}
+	pattern := regexp.MustCompile("^" + strings.Join(globs, "(.*)") + "$")
+
+	return hostRewrite{from: from, to: to, pattern: pattern}, nil
+}
+
+// apply returns the rewritten host, and true if the host matched the pattern.
+func (hr hostRewrite) apply(host string) (string, bool) {
+	m := hr.pattern.FindStringSubmatch(host)
+	if m == nil {
+		return host, false
+	}
+
+	parts := strings.Split(hr.to, "*")
+	result := parts[0]
+	for i, part := range parts[1:] {
+		result += m[i+1] + part
+	}
+	return result, true
+}
+
+// parsePortRewrite parses a rule of the form <port>=<port>.
+func parsePortRewrite(s string) (int, int, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed port rewrite %q: expected <port>=<port>", s)
+	}
+
+	ports := make([]int, 2)
+	for i, p := range parts {
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return 0, 0, fmt.Errorf("malformed port rewrite %q: bad port %q", s, p)
+		}
+		ports[i] = port
+	}
+
+	return ports[0], ports[1], nil
+}
+
+// zoneRewriter renames the objects of an exported, name-keyed Zone, updating
+// every reference to a renamed object so that referential integrity is
+// maintained.
+type zoneRewriter struct {
+	hosts         []hostRewrite
+	ports         map[int]int
+	clusterSuffix string
+}
+
+// newZoneRewriter produces a zoneRewriter from domain rewrite rules, port
+// rewrite rules, and a suffix to be appended to Cluster names.
+func newZoneRewriter(hostStrs, portStrs []string, clusterSuffix string) (*zoneRewriter, error) {
+	zr := &zoneRewriter{ports: map[int]int{}, clusterSuffix: clusterSuffix}
+
+	for _, s := range hostStrs {
+		hr, err := parseHostRewrite(s)
+		if err != nil {
+			return nil, err
+		}
+		zr.hosts = append(zr.hosts, hr)
+	}
+
+	for _, s := range portStrs {
+		from, to, err := parsePortRewrite(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := zr.ports[from]; ok {
+			return nil, fmt.Errorf("duplicate port rewrite for port %d", from)
+		}
+		zr.ports[from] = to
+	}
+
+	return zr, nil
+}
+
+// host rewrites the host using the first matching domain rewrite rule.
+func (zr *zoneRewriter) host(host string) string {
+	for _, hr := range zr.hosts {
+		if rewritten, ok := hr.apply(host); ok {
+			return rewritten
+		}
+	}
+	return host
+}
+
+// port rewrites the port using the port rewrite rules.
+func (zr *zoneRewriter) port(port int) int {
+	if to, ok := zr.ports[port]; ok {
+		return to
+	}
+	return port
+}
+
+// rewrite applies the rewrite rules to the zoneObjects in place. Domain names,
+// aliases and ports are rewritten, as are Listener ports, and Cluster names
+// are given the configured suffix. References to Domains from Listeners,
+// Proxies and Routes, and references to Clusters from constraints, are updated
+// to match.
+func (zr *zoneRewriter) rewrite(zo *zoneObjects) error {
+	cks := map[api.ClusterKey]api.ClusterKey{}
+	for i := range zo.Clusters {
+		c := &zo.Clusters[i]
+		c.Name += zr.clusterSuffix
+		ck := api.ClusterKey(c.Name)
+		cks[c.ClusterKey] = ck
+		c.ClusterKey = ck
+	}
+
+	dks := map[api.DomainKey]api.DomainKey{}
+	seen := map[api.DomainKey]api.DomainKey{}
+	for i := range zo.Domains {
+		d := &zo.Domains[i]
+		d.Name = zr.host(d.Name)
+		d.Port = zr.port(d.Port)
+		for j, alias := range d.Aliases {
+			d.Aliases[j] = api.DomainAlias(zr.host(string(alias)))
+		}
+
+		dk := api.DomainKey(d.Addr())
+		if prev, ok := seen[dk]; ok {
+			return fmt.Errorf("domains %s and %s are both rewritten to %s", prev, d.DomainKey, dk)
+		}
+		seen[dk] = d.DomainKey
+		dks[d.DomainKey] = dk
+		d.DomainKey = dk
+	}
+
+	for i := range zo.Listeners {
+		l := &zo.Listeners[i]
+		l.Port = zr.port(l.Port)
+		rewriteDomainKeys(l.DomainKeys, dks)
+	}
+
+	for i := range zo.Proxies {
+		rewriteDomainKeys(zo.Proxies[i].DomainKeys, dks)
+	}
+
+	for i := range zo.SharedRules {
+		sr := &zo.SharedRules[i]
+		rewriteAllConstraints(&sr.Default, cks)
+		rewriteRules(sr.Rules, cks)
+	}
+
+	for i := range zo.Routes {
+		r := &zo.Routes[i]
+		if dk, ok := dks[r.DomainKey]; ok {
+			r.DomainKey = dk
+		}
+		r.RouteKey = api.RouteKey(fmt.Sprintf("%s%s", r.DomainKey, r.Path))
+		rewriteRules(r.Rules, cks)
+	}
+
+	return nil
+}
+
+func rewriteDomainKeys(dks []api.DomainKey, m map[api.DomainKey]api.DomainKey) {
+	for i, dk := range dks {
+		if rewritten, ok := m[dk]; ok {
+			dks[i] = rewritten
+		}
+	}
+}
+
+func rewriteRules(rs api.Rules, m map[api.ClusterKey]api.ClusterKey) {
+	for i := range rs {
+		rewriteAllConstraints(&rs[i].Constraints, m)
+	}
+}
+
+func rewriteAllConstraints(ac *api.AllConstraints, m map[api.ClusterKey]api.ClusterKey) {
+	rewriteClusterConstraints(ac.Light, m)
+	rewriteClusterConstraints(ac.Dark, m)
+	rewriteClusterConstraints(ac.Tap, m)
+}
+
+func rewriteClusterConstraints(ccs api.ClusterConstraints, m map[api.ClusterKey]api.ClusterKey) {
+	for i := range ccs {
+		if rewritten, ok := m[ccs[i].ClusterKey]; ok {
+			ccs[i].ClusterKey = rewritten
+		}
+	}
+}
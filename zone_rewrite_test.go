@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestParseHostRewrite(t *testing.T) {
+	hr, err := parseHostRewrite("*.example.com=*.staging.example.com")
+	assert.Nil(t, err)
+
+	host, ok := hr.apply("api.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, host, "api.staging.example.com")
+
+	host, ok = hr.apply("*.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, host, "*.staging.example.com")
+
+	host, ok = hr.apply("example.org")
+	assert.False(t, ok)
+	assert.Equal(t, host, "example.org")
+
+	for _, s := range []string{"nope", "=x", "x=", "a.com=*.a.com"} {
+		_, err = parseHostRewrite(s)
+		assert.NonNil(t, err)
+	}
+}
+
+func TestNewZoneRewriterBadPorts(t *testing.T) {
+	for _, s := range []string{"80", "80=x", "0=80", "80=70000"} {
+		_, err := newZoneRewriter(nil, []string{s}, "")
+		assert.NonNil(t, err)
+	}
+
+	_, err := newZoneRewriter(nil, []string{"80=8080", "80=8081"}, "")
+	assert.NonNil(t, err)
+}
+
+func TestZoneRewriterRewrite(t *testing.T) {
+	zo := newZoneObjects()
+	zo.Clusters = api.Clusters{{ClusterKey: "c", Name: "c"}}
+	zo.Domains = api.Domains{
+		{
+			DomainKey: "api.example.com:80",
+			Name:      "api.example.com",
+			Port:      80,
+			Aliases:   api.DomainAliases{"*.api.example.com", "example.org"},
+		},
+	}
+	zo.Listeners = api.Listeners{
+		{ListenerKey: "l", Name: "l", Port: 80, DomainKeys: []api.DomainKey{"api.example.com:80"}},
+	}
+	zo.Proxies = api.Proxies{
+		{ProxyKey: "p", Name: "p", DomainKeys: []api.DomainKey{"api.example.com:80"}},
+	}
+	zo.SharedRules = api.SharedRulesSlice{
+		{
+			SharedRulesKey: "sr",
+			Name:           "sr",
+			Default: api.AllConstraints{
+				Light: api.ClusterConstraints{{ClusterKey: "c", Weight: 1}},
+			},
+		},
+	}
+	zo.Routes = api.Routes{
+		{
+			RouteKey:       "api.example.com:80/",
+			DomainKey:      "api.example.com:80",
+			SharedRulesKey: "sr",
+			Path:           "/",
+			Rules: api.Rules{
+				{Constraints: api.AllConstraints{Dark: api.ClusterConstraints{{ClusterKey: "c"}}}},
+			},
+		},
+	}
+
+	zr, err := newZoneRewriter(
+		[]string{"*.example.com=*.staging.example.com"},
+		[]string{"80=8080"},
+		"-staging",
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, zr.rewrite(zo))
+
+	dk := api.DomainKey("api.staging.example.com:8080")
+	assert.Equal(t, zo.Clusters[0].Name, "c-staging")
+	assert.Equal(t, zo.Clusters[0].ClusterKey, api.ClusterKey("c-staging"))
+	assert.Equal(t, zo.Domains[0].DomainKey, dk)
+	assert.Equal(t, zo.Domains[0].Port, 8080)
+	assert.DeepEqual(
+		t,
+		zo.Domains[0].Aliases,
+		api.DomainAliases{"*.api.staging.example.com", "example.org"},
+	)
+	assert.Equal(t, zo.Listeners[0].Port, 8080)
+	assert.DeepEqual(t, zo.Listeners[0].DomainKeys, []api.DomainKey{dk})
+	assert.DeepEqual(t, zo.Proxies[0].DomainKeys, []api.DomainKey{dk})
+	assert.Equal(t, zo.SharedRules[0].Default.Light[0].ClusterKey, api.ClusterKey("c-staging"))
+	assert.Equal(t, zo.Routes[0].DomainKey, dk)
+	assert.Equal(t, zo.Routes[0].RouteKey, api.RouteKey("api.staging.example.com:8080/"))
+	assert.Equal(t, zo.Routes[0].Rules[0].Constraints.Dark[0].ClusterKey, api.ClusterKey("c-staging"))
+}
+
+func TestZoneRewriterRewriteCollision(t *testing.T) {
+	zo := newZoneObjects()
+	zo.Domains = api.Domains{
+		{DomainKey: "a.com:80", Name: "a.com", Port: 80},
+		{DomainKey: "b.com:80", Name: "b.com", Port: 80},
+	}
+
+	zr, err := newZoneRewriter([]string{"*.com=c.com"}, nil, "")
+	assert.Nil(t, err)
+	assert.ErrorContains(t, zr.rewrite(zo), "both rewritten to c.com:80")
+}
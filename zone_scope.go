@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+)
+
+const (
+	zoneFlagDesc = `The name or key of a Zone to which commands are scoped. If set, list
+only returns objects in the Zone, create pre-populates the zone_key of new
+objects, and edit and delete refuse to change objects in other Zones unless
+--force is given.`
+
+	zoneForceDesc = "If true, proceed even if the object belongs to a Zone other than the one given by --zone."
+
+	zoneKeyAttr = "zone_key"
+)
+
+// resolveZone looks up the Zone given by --zone, if any, by name or key as
+// exportZone does, and records its key.
+func (gc *globalConfigT) resolveZone() error {
+	if gc.zone == nil || *gc.zone == "" {
+		return nil
+	}
+
+	z, err := findZone(gc.apiClient, *gc.zone)
+	if err != nil {
+		return fmt.Errorf("could not find zone %s: %v", *gc.zone, err)
+	}
+	if (api.Zone{}.Equals(z)) {
+		return fmt.Errorf("zone %s does not exist", *gc.zone)
+	}
+
+	gc.zoneKey = z.ZoneKey
+	return nil
+}
+
+// scopeFilterAttrs adds the key of the Zone given by --zone to the filter
+// attributes, if the filter has a zone_key field and no zone_key was given.
+func (gc *globalConfigT) scopeFilterAttrs(filter interface{}, attrs map[string]string) {
+	if gc.zoneKey == "" || attrs[zoneKeyAttr] != "" {
+		return
+	}
+
+	if filterHasField(filter, zoneKeyAttr) {
+		attrs[zoneKeyAttr] = string(gc.zoneKey)
+	}
+}
+
+// scopeZero returns a copy of the object with its ZoneKey set to the key of
+// the Zone given by --zone, if any. Zones, and objects without a ZoneKey, are
+// returned unchanged.
+func (gc *globalConfigT) scopeZero(obj interface{}) interface{} {
+	if gc.zoneKey == "" {
+		return obj
+	}
+	if _, ok := obj.(api.Zone); ok {
+		return obj
+	}
+
+	v := reflect.New(reflect.TypeOf(obj)).Elem()
+	v.Set(reflect.ValueOf(obj))
+	f := v.FieldByName("ZoneKey")
+	if !f.IsValid() {
+		return obj
+	}
+	f.SetString(string(gc.zoneKey))
+	return v.Interface()
+}
+
+// checkZoneScope returns an error if --zone is set and the object belongs to
+// a different Zone, unless force is true. A Zone belongs to itself.
+func (gc *globalConfigT) checkZoneScope(
+	ot objecttype.ObjectType,
+	key string,
+	obj interface{},
+	force bool,
+) error {
+	if gc.zoneKey == "" || force {
+		return nil
+	}
+
+	f := reflect.ValueOf(obj).FieldByName("ZoneKey")
+	if !f.IsValid() || api.ZoneKey(f.String()) == gc.zoneKey {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%s %s belongs to zone %s, not %s; use --force to proceed anyway",
+		ot.Name,
+		key,
+		f.String(),
+		*gc.zone,
+	)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/api"
+	"github.com/turbinelabs/api/objecttype"
+	"github.com/turbinelabs/api/service"
+	"github.com/turbinelabs/codec"
+	"github.com/turbinelabs/tbnctl/fakeapi"
+	"github.com/turbinelabs/test/assert"
+)
+
+func zoneScopedConfig(zone string) *globalConfigT {
+	return &globalConfigT{zone: &zone}
+}
+
+func TestResolveZoneUnset(t *testing.T) {
+	gc := &globalConfigT{}
+	assert.Nil(t, gc.resolveZone())
+	assert.Equal(t, gc.zoneKey, api.ZoneKey(""))
+}
+
+func TestResolveZoneByNameOrKey(t *testing.T) {
+	store := fakeapi.New()
+	z, err := store.All().Zone().Create(api.Zone{Name: "prod"})
+	assert.Nil(t, err)
+
+	for _, name := range []string{"prod", string(z.ZoneKey)} {
+		gc := zoneScopedConfig(name)
+		gc.apiClient = &unifiedSvc{store.All(), store.Admin()}
+		assert.Nil(t, gc.resolveZone())
+		assert.Equal(t, gc.zoneKey, z.ZoneKey)
+	}
+
+	gc := zoneScopedConfig("nope")
+	gc.apiClient = &unifiedSvc{store.All(), store.Admin()}
+	assert.NonNil(t, gc.resolveZone())
+	assert.Equal(t, gc.zoneKey, api.ZoneKey(""))
+}
+
+func TestScopeFilterAttrs(t *testing.T) {
+	gc := &globalConfigT{zoneKey: "zk"}
+
+	attrs := map[string]string{"name": "c"}
+	gc.scopeFilterAttrs(service.ClusterFilter{}, attrs)
+	assert.DeepEqual(t, attrs, map[string]string{"name": "c", "zone_key": "zk"})
+
+	attrs = map[string]string{"zone_key": "other"}
+	gc.scopeFilterAttrs(service.ClusterFilter{}, attrs)
+	assert.DeepEqual(t, attrs, map[string]string{"zone_key": "other"})
+
+	attrs = map[string]string{}
+	gc.scopeFilterAttrs(service.UserFilter{}, attrs)
+	assert.DeepEqual(t, attrs, map[string]string{})
+
+	gc.zoneKey = ""
+	gc.scopeFilterAttrs(service.ClusterFilter{}, attrs)
+	assert.DeepEqual(t, attrs, map[string]string{})
+}
+
+func TestScopeZero(t *testing.T) {
+	gc := &globalConfigT{zoneKey: "zk"}
+
+	assert.DeepEqual(t, gc.scopeZero(api.Cluster{}), api.Cluster{ZoneKey: "zk"})
+	assert.DeepEqual(t, gc.scopeZero(api.Zone{}), api.Zone{})
+	assert.DeepEqual(t, gc.scopeZero(api.User{}), api.User{})
+
+	gc.zoneKey = ""
+	assert.DeepEqual(t, gc.scopeZero(api.Cluster{}), api.Cluster{})
+}
+
+func TestCheckZoneScope(t *testing.T) {
+	gc := zoneScopedConfig("prod")
+	gc.zoneKey = "zk"
+
+	same := api.Cluster{ClusterKey: "c", ZoneKey: "zk"}
+	other := api.Cluster{ClusterKey: "c", ZoneKey: "zk2"}
+
+	assert.Nil(t, gc.checkZoneScope(objecttype.Cluster, "c", same, false))
+	assert.ErrorContains(
+		t,
+		gc.checkZoneScope(objecttype.Cluster, "c", other, false),
+		"cluster c belongs to zone zk2, not prod",
+	)
+	assert.Nil(t, gc.checkZoneScope(objecttype.Cluster, "c", other, true))
+	assert.Nil(t, gc.checkZoneScope(objecttype.User, "u", api.User{}, false))
+
+	gc.zoneKey = ""
+	assert.Nil(t, gc.checkZoneScope(objecttype.Cluster, "c", other, false))
+}
+
+func TestEditApplyChecksZoneScope(t *testing.T) {
+	store := fakeapi.New()
+	svc := store.All()
+
+	prod, err := svc.Zone().Create(api.Zone{Name: "prod"})
+	assert.Nil(t, err)
+	staging, err := svc.Zone().Create(api.Zone{Name: "staging"})
+	assert.Nil(t, err)
+	c, err := svc.Cluster().Create(api.Cluster{ZoneKey: prod.ZoneKey, Name: "c"})
+	assert.Nil(t, err)
+
+	gc := zoneScopedConfig("prod")
+	gc.zoneKey = prod.ZoneKey
+	gc.codec = codec.NewJson()
+	r := &editRunner{&editCfg{globalConfigT: gc}}
+
+	moved := c
+	moved.ZoneKey = staging.ZoneKey
+	txt, err := codec.EncodeToString(gc.codec, moved)
+	assert.Nil(t, err)
+
+	var base interface{} = c
+	_, err = r.apply(clusterAdapter{svc.Cluster()}, txt, &base)
+	assert.ErrorContains(t, err, "belongs to zone "+string(staging.ZoneKey)+", not prod")
+
+	got, err := svc.Cluster().Get(c.ClusterKey)
+	assert.Nil(t, err)
+	assert.Equal(t, got.ZoneKey, prod.ZoneKey)
+
+	r.cfg.force = true
+	_, err = r.apply(clusterAdapter{svc.Cluster()}, txt, &base)
+	assert.Nil(t, err)
+
+	got, err = svc.Cluster().Get(c.ClusterKey)
+	assert.Nil(t, err)
+	assert.Equal(t, got.ZoneKey, staging.ZoneKey)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/turbinelabs/codec"
+	tbnflag "github.com/turbinelabs/nonstdlib/flag"
+)
+
+const (
+	zoneTemplateValuesDesc = `A YAML or JSON file containing values for a Zone document
+written as a Go text/template. If specified, the document is rendered as a
+template before it is decoded.`
+
+	zoneTemplateSetDesc = `A value for a Zone document written as a Go
+text/template, of the form <key>=<value>. Dotted keys (e.g. region.name=us-east)
+produce nested values. Overrides values from --values. If specified, the
+document is rendered as a template before it is decoded. May be repeated.`
+)
+
+// zoneTemplateFlags configures the rendering of Zone documents written as Go
+// text/templates, for commands which accept Zone documents.
+type zoneTemplateFlags struct {
+	valuesFile string
+	sets       tbnflag.Strings
+}
+
+// newZoneTemplateFlags installs the --values and --set flags in the given
+// FlagSet.
+func newZoneTemplateFlags(fs *flag.FlagSet) *zoneTemplateFlags {
+	tf := &zoneTemplateFlags{sets: tbnflag.NewStrings()}
+	fs.StringVar(&tf.valuesFile, "values", "", zoneTemplateValuesDesc)
+	fs.Var(&tf.sets, "set", zoneTemplateSetDesc)
+	return tf
+}
+
+// enabled returns true if template values were specified.
+func (tf *zoneTemplateFlags) enabled() bool {
+	return tf.valuesFile != "" || len(tf.sets.Strings) > 0
+}
+
+// values returns the values from the values file, overridden by any --set
+// flags.
+func (tf *zoneTemplateFlags) values() (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	if tf.valuesFile != "" {
+		b, err := ioutil.ReadFile(tf.valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %s", tf.valuesFile, err)
+		}
+		if err := codec.DecodeFromString(codec.NewYaml(), string(b), &values); err != nil {
+			return nil, fmt.Errorf("could not decode %s: %s", tf.valuesFile, err)
+		}
+	}
+
+	for _, s := range tf.sets.Strings {
+		if err := setTemplateValue(values, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// render renders the Zone document in txt as a template if template values
+// were specified, and returns it unchanged otherwise. The name is used to
+// identify the template in errors.
+func (tf *zoneTemplateFlags) render(name, txt string) (string, error) {
+	if !tf.enabled() {
+		return txt, nil
+	}
+
+	values, err := tf.values()
+	if err != nil {
+		return "", err
+	}
+
+	return renderZoneTemplate(name, txt, values)
+}
+
+// templateName returns the name used to identify a template read from the
+// given file, or from STDIN if the file is empty.
+func templateName(file string) string {
+	if file == "" {
+		return "STDIN"
+	}
+	return file
+}
+
+// setTemplateValue sets a value of the form <key>=<value> in values. Dotted
+// keys are set in nested maps, which are created as needed.
+func setTemplateValue(values map[string]interface{}, s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("malformed value %q: expected <key>=<value>", s)
+	}
+
+	path := strings.Split(parts[0], ".")
+	m := values
+	for i, k := range path[:len(path)-1] {
+		switch next := m[k].(type) {
+		case map[string]interface{}:
+			m = next
+		case nil:
+			child := map[string]interface{}{}
+			m[k] = child
+			m = child
+		default:
+			return fmt.Errorf(
+				"malformed value %q: %s is not a map",
+				s,
+				strings.Join(path[:i+1], "."),
+			)
+		}
+	}
+	m[path[len(path)-1]] = parts[1]
+
+	return nil
+}
+
+// renderZoneTemplate renders txt as a Go text/template with the given values.
+// Referring to a missing value is an error. Errors include the template name
+// and the line number at which they occurred.
+func renderZoneTemplate(name, txt string, values map[string]interface{}) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(txt)
+	if err != nil {
+		return "", fmt.Errorf("could not parse zone template: %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, values); err != nil {
+		return "", fmt.Errorf("could not render zone template: %s", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Turbine Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/turbinelabs/test/assert"
+)
+
+func TestSetTemplateValue(t *testing.T) {
+	values := map[string]interface{}{}
+	assert.Nil(t, setTemplateValue(values, "env=prod"))
+	assert.Nil(t, setTemplateValue(values, "region.name=us-east=1"))
+	assert.Nil(t, setTemplateValue(values, "region.port=443"))
+	assert.DeepEqual(t, values, map[string]interface{}{
+		"env": "prod",
+		"region": map[string]interface{}{
+			"name": "us-east=1",
+			"port": "443",
+		},
+	})
+
+	assert.ErrorContains(t, setTemplateValue(values, "env.name=x"), "env is not a map")
+	assert.NonNil(t, setTemplateValue(values, "novalue"))
+	assert.NonNil(t, setTemplateValue(values, "=x"))
+}
+
+func TestZoneTemplateFlagsRender(t *testing.T) {
+	tf := &zoneTemplateFlags{}
+	txt, err := tf.render("STDIN", `{"zone": {"name": "{{.env}}"}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, txt, `{"zone": {"name": "{{.env}}"}}`)
+
+	tf.sets.Strings = []string{"env=prod"}
+	txt, err = tf.render("STDIN", `{"zone": {"name": "{{.env}}"}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, txt, `{"zone": {"name": "prod"}}`)
+}
+
+func TestRenderZoneTemplateErrors(t *testing.T) {
+	values := map[string]interface{}{"env": "prod"}
+
+	_, err := renderZoneTemplate("zone.tmpl", "{\n  {{.env}\n}", values)
+	assert.ErrorContains(t, err, "zone.tmpl:2")
+
+	_, err = renderZoneTemplate("zone.tmpl", "{\n\n  {{.region}}\n}", values)
+	assert.ErrorContains(t, err, "zone.tmpl:3")
+	assert.ErrorContains(t, err, "region")
+}